@@ -0,0 +1,279 @@
+// Package caldav handles CalDAV (RFC 4791) calendar integration, for calendar servers like Nextcloud, Radicale, iCloud
+// and Fastmail.
+package caldav
+
+import (
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/spinnaker/rotation-scheduler/event"
+	"github.com/spinnaker/rotation-scheduler/ical"
+)
+
+const (
+	// calendarQuery asks for the etag and full iCalendar data of every event in the calendar. Servers don't reliably
+	// support filtering on non-standard properties, so events are filtered by rotation after they're listed.
+	calendarQuery = `<?xml version="1.0" encoding="utf-8"?>
+<c:calendar-query xmlns:d="DAV:" xmlns:c="urn:ietf:params:xml:ns:caldav">
+  <d:prop>
+    <d:getetag/>
+    <c:calendar-data/>
+  </d:prop>
+  <c:filter>
+    <c:comp-filter name="VCALENDAR">
+      <c:comp-filter name="VEVENT"/>
+    </c:comp-filter>
+  </c:filter>
+</c:calendar-query>
+`
+
+	// maxErrorBody limits how much of an error response is included in error messages.
+	maxErrorBody = 512
+)
+
+// CalDAV is an event.Backend for a single CalDAV calendar collection. Every event is stored as its own resource in the
+// collection.
+type CalDAV struct {
+	// CalendarURL is the URL of the calendar collection, like 'https://example.com/dav/calendars/user/oncall/'.
+	CalendarURL *url.URL
+
+	// Rotation tags every event this CalDAV creates, and limits it to only modifying events with the same tag.
+	Rotation string
+
+	cal    *ical.Calendar
+	client *http.Client
+
+	// etags are the entity tags of listed resources, by URL, so updates and deletes fail rather than overwrite changes
+	// made since they were listed.
+	etags map[string]string
+}
+
+var _ event.Backend = &CalDAV{}
+
+// NewCalDAV wraps the calendar collection at calendarURL using the client, which is responsible for authentication.
+// Only events tagged with the rotation name are ever updated or deleted, so multiple rotations and human-created events
+// can share a calendar.
+func NewCalDAV(calendarURL, rotation string, client *http.Client) (*CalDAV, error) {
+	if calendarURL == "" {
+		return nil, fmt.Errorf("calendar URL cannot be empty")
+	}
+
+	u, err := url.Parse(calendarURL)
+	if err != nil {
+		return nil, fmt.Errorf("invalid calendar URL(%v): %v", calendarURL, err)
+	}
+	if !strings.HasSuffix(u.Path, "/") {
+		u.Path += "/"
+	}
+
+	cal, err := ical.NewCalendar(rotation, time.Now())
+	if err != nil {
+		return nil, err
+	}
+
+	if client == nil {
+		client = http.DefaultClient
+	}
+
+	return &CalDAV{
+		CalendarURL: u,
+		Rotation:    rotation,
+		cal:         cal,
+		client:      client,
+		etags:       map[string]string{},
+	}, nil
+}
+
+type multistatus struct {
+	Responses []struct {
+		Href      string `xml:"DAV: href"`
+		Propstats []struct {
+			Status string `xml:"DAV: status"`
+			Prop   struct {
+				ETag         string `xml:"DAV: getetag"`
+				CalendarData string `xml:"urn:ietf:params:xml:ns:caldav calendar-data"`
+			} `xml:"DAV: prop"`
+		} `xml:"DAV: propstat"`
+	} `xml:"DAV: response"`
+}
+
+// List only lists events owned by this rotation. Each event's ID is the URL of the resource it's stored in, and its
+// etag is kept to make later updates and deletes conditional.
+func (c *CalDAV) List() ([]*event.Event, error) {
+	req, err := http.NewRequest("REPORT", c.CalendarURL.String(), strings.NewReader(calendarQuery))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/xml; charset=utf-8")
+	req.Header.Set("Depth", "1")
+
+	body, err := c.do(req, http.StatusMultiStatus)
+	if err != nil {
+		return nil, err
+	}
+
+	ms := &multistatus{}
+	if err := xml.Unmarshal(body, ms); err != nil {
+		return nil, fmt.Errorf("error parsing calendar-query response: %v", err)
+	}
+
+	var events []*event.Event
+	for _, r := range ms.Responses {
+		href, err := c.CalendarURL.Parse(r.Href)
+		if err != nil {
+			return nil, fmt.Errorf("invalid href(%v): %v", r.Href, err)
+		}
+
+		for _, ps := range r.Propstats {
+			if !strings.Contains(ps.Status, " 200 ") || ps.Prop.CalendarData == "" {
+				continue
+			}
+
+			decoded, err := ical.Decode(strings.NewReader(ps.Prop.CalendarData), c.Rotation)
+			if err != nil {
+				return nil, fmt.Errorf("error reading event %v: %v", href, err)
+			}
+			for _, e := range decoded {
+				e.ID = href.String()
+				events = append(events, e)
+			}
+			if len(decoded) != 0 && ps.Prop.ETag != "" {
+				c.etags[href.String()] = ps.Prop.ETag
+			}
+		}
+	}
+	return events, nil
+}
+
+// Insert stores e in a new resource named after its UID. It fails rather than overwriting an existing resource.
+func (c *CalDAV) Insert(e *event.Event) error {
+	u := c.CalendarURL.ResolveReference(&url.URL{Path: url.PathEscape(c.cal.UID(e)) + ".ics"})
+	return c.put(u.String(), e, func(req *http.Request) {
+		req.Header.Set("If-None-Match", "*")
+	})
+}
+
+// Update replaces the resource of the existing event. It fails with a conflict if the resource has changed since it was
+// listed.
+func (c *CalDAV) Update(existing, e *event.Event) error {
+	return c.put(existing.ID, e, func(req *http.Request) {
+		c.ifMatch(req, existing.ID)
+	})
+}
+
+// Delete removes the resource of the event. Like Update, it fails with a conflict if the resource has changed since it
+// was listed.
+func (c *CalDAV) Delete(e *event.Event) error {
+	req, err := http.NewRequest(http.MethodDelete, e.ID, nil)
+	if err != nil {
+		return err
+	}
+	c.ifMatch(req, e.ID)
+
+	if _, err := c.do(req, http.StatusOK, http.StatusNoContent); err != nil {
+		return err
+	}
+	delete(c.etags, e.ID)
+	return nil
+}
+
+// ifMatch makes req conditional on the resource still having the etag it was listed with, if it had one.
+func (c *CalDAV) ifMatch(req *http.Request, resourceURL string) {
+	if etag, ok := c.etags[resourceURL]; ok {
+		req.Header.Set("If-Match", etag)
+	}
+}
+
+func (c *CalDAV) put(resourceURL string, e *event.Event, modify func(req *http.Request)) error {
+	buf := &bytes.Buffer{}
+	if err := c.cal.EncodeEvents(buf, []*event.Event{e}); err != nil {
+		return fmt.Errorf("error encoding event: %v", err)
+	}
+
+	req, err := http.NewRequest(http.MethodPut, resourceURL, buf)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "text/calendar; charset=utf-8")
+	modify(req)
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if _, err := c.check(req, resp, http.StatusOK, http.StatusCreated, http.StatusNoContent); err != nil {
+		return err
+	}
+
+	// Servers only return the new etag if they stored the event unchanged, so it's forgotten otherwise.
+	if etag := resp.Header.Get("ETag"); etag != "" {
+		c.etags[resourceURL] = etag
+	} else {
+		delete(c.etags, resourceURL)
+	}
+	return nil
+}
+
+// do sends req, and returns the response body if the response has one of the wanted status codes.
+func (c *CalDAV) do(req *http.Request, wantStatus ...int) ([]byte, error) {
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	return c.check(req, resp, wantStatus...)
+}
+
+// check returns the body of resp if it has one of the wanted status codes. A failed If-Match is reported as a conflict.
+func (c *CalDAV) check(req *http.Request, resp *http.Response, wantStatus ...int) ([]byte, error) {
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("error reading %v response: %v", req.Method, err)
+	}
+
+	for _, s := range wantStatus {
+		if resp.StatusCode == s {
+			return body, nil
+		}
+	}
+
+	if resp.StatusCode == http.StatusPreconditionFailed && req.Header.Get("If-Match") != "" {
+		return nil, fmt.Errorf("conflict: %v %v failed because the event was changed since it was listed, "+
+			"sync again to plan against the current calendar", req.Method, req.URL)
+	}
+
+	if len(body) > maxErrorBody {
+		body = body[:maxErrorBody]
+	}
+	return nil, fmt.Errorf("%v %v failed with status %v: %s", req.Method, req.URL, resp.Status,
+		bytes.TrimSpace(body))
+}
+
+// BasicAuthTransport adds HTTP basic authentication to every request, which is what most CalDAV servers expect, usually
+// with an app-specific password.
+type BasicAuthTransport struct {
+	Username string
+	Password string
+
+	// Base defaults to http.DefaultTransport.
+	Base http.RoundTripper
+}
+
+func (t *BasicAuthTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	base := t.Base
+	if base == nil {
+		base = http.DefaultTransport
+	}
+
+	// RoundTrippers must not modify the request.
+	authed := req.Clone(req.Context())
+	authed.SetBasicAuth(t.Username, t.Password)
+	return base.RoundTrip(authed)
+}
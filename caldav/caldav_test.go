@@ -0,0 +1,336 @@
+package caldav
+
+import (
+	"fmt"
+	"hash/fnv"
+	"html"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/spinnaker/rotation-scheduler/event"
+	"github.com/spinnaker/rotation-scheduler/render"
+	"github.com/spinnaker/rotation-scheduler/schedule"
+)
+
+const (
+	testRotation = "build-cop"
+	calendarPath = "/dav/calendars/oncall/"
+
+	humanEvent = "BEGIN:VCALENDAR\r\nVERSION:2.0\r\nBEGIN:VEVENT\r\nUID:standup@example.com\r\n" +
+		"DTSTART;VALUE=DATE:20200601\r\nDTEND;VALUE=DATE:20200602\r\nSUMMARY:abc build-cop\r\n" +
+		"END:VEVENT\r\nEND:VCALENDAR\r\n"
+	otherRotationEvent = "BEGIN:VCALENDAR\r\nVERSION:2.0\r\nBEGIN:VEVENT\r\nUID:20200601-release@example.com\r\n" +
+		"DTSTART;VALUE=DATE:20200601\r\nDTEND;VALUE=DATE:20200608\r\nSUMMARY:abc release-manager\r\n" +
+		"X-ROTATION-SCHEDULER-ROTATION:release-manager\r\nX-ROTATION-SCHEDULER-SHIFT-KEY:20200601\r\n" +
+		"END:VEVENT\r\nEND:VCALENDAR\r\n"
+	timedHumanEvent = "BEGIN:VCALENDAR\r\nVERSION:2.0\r\nBEGIN:VTIMEZONE\r\nTZID:America/New_York\r\n" +
+		"BEGIN:STANDARD\r\nDTSTART:19701101T020000\r\nTZOFFSETFROM:-0400\r\nTZOFFSETTO:-0500\r\nEND:STANDARD\r\n" +
+		"END:VTIMEZONE\r\nBEGIN:VEVENT\r\nUID:planning@example.com\r\n" +
+		"DTSTART;TZID=America/New_York:20200602T090000\r\nDTEND;TZID=America/New_York:20200602T100000\r\n" +
+		"SUMMARY:abc build-cop planning\r\nEND:VEVENT\r\nEND:VCALENDAR\r\n"
+)
+
+// fakeServer is an in-memory calendar collection that supports just enough CalDAV for the CalDAV backend. The etag of
+// every resource is a hash of its data.
+type fakeServer struct {
+	mu        sync.Mutex
+	resources map[string]string
+}
+
+func (f *fakeServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if user, pass, ok := r.BasicAuth(); !ok || user != "user" || pass != "pass" {
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+
+	switch r.Method {
+	case "REPORT":
+		if r.URL.Path != calendarPath || r.Header.Get("Depth") != "1" {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+
+		var hrefs []string
+		for href := range f.resources {
+			hrefs = append(hrefs, href)
+		}
+		sort.Strings(hrefs)
+
+		w.WriteHeader(http.StatusMultiStatus)
+		fmt.Fprint(w, `<?xml version="1.0"?><d:multistatus xmlns:d="DAV:" xmlns:c="urn:ietf:params:xml:ns:caldav">`)
+		for _, href := range hrefs {
+			fmt.Fprintf(w, `<d:response><d:href>%v</d:href><d:propstat><d:prop><d:getetag>%v</d:getetag>`+
+				`<c:calendar-data>%v</c:calendar-data></d:prop><d:status>HTTP/1.1 200 OK</d:status></d:propstat>`+
+				`</d:response>`, href, etag(f.resources[href]), html.EscapeString(f.resources[href]))
+		}
+		fmt.Fprint(w, `</d:multistatus>`)
+	case http.MethodPut:
+		current, exists := f.resources[r.URL.Path]
+		if exists && r.Header.Get("If-None-Match") == "*" || !f.matches(r, current, exists) {
+			w.WriteHeader(http.StatusPreconditionFailed)
+			return
+		}
+		b, _ := ioutil.ReadAll(r.Body)
+		f.resources[r.URL.Path] = string(b)
+		w.Header().Set("ETag", etag(string(b)))
+		if exists {
+			w.WriteHeader(http.StatusNoContent)
+		} else {
+			w.WriteHeader(http.StatusCreated)
+		}
+	case http.MethodDelete:
+		current, ok := f.resources[r.URL.Path]
+		if !ok {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		if !f.matches(r, current, ok) {
+			w.WriteHeader(http.StatusPreconditionFailed)
+			return
+		}
+		delete(f.resources, r.URL.Path)
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		w.WriteHeader(http.StatusMethodNotAllowed)
+	}
+}
+
+// matches is false if r has an If-Match header that doesn't match the current resource.
+func (f *fakeServer) matches(r *http.Request, current string, exists bool) bool {
+	ifMatch := r.Header.Get("If-Match")
+	return ifMatch == "" || exists && ifMatch == etag(current)
+}
+
+func etag(data string) string {
+	h := fnv.New32a()
+	h.Write([]byte(data))
+	return fmt.Sprintf(`"%x"`, h.Sum32())
+}
+
+func newTestCalDAV(t *testing.T, f *fakeServer) *CalDAV {
+	srv := httptest.NewServer(f)
+	t.Cleanup(srv.Close)
+
+	client := &http.Client{Transport: &BasicAuthTransport{Username: "user", Password: "pass"}}
+	c, err := NewCalDAV(srv.URL+strings.TrimSuffix(calendarPath, "/"), testRotation, client)
+	if err != nil {
+		t.Fatalf("error creating CalDAV: %v", err)
+	}
+	return c
+}
+
+func testSchedule() *schedule.Schedule {
+	return &schedule.Schedule{
+		Shifts: []*schedule.Shift{
+			{
+				StartDate: time.Date(2020, 6, 1, 0, 0, 0, 0, time.UTC),
+				User:      "abc",
+			},
+			{
+				StartDate:    time.Date(2020, 6, 8, 0, 0, 0, 0, time.UTC),
+				User:         "lmn",
+				UserOverride: "xyz@example.com",
+			},
+			{
+				StartDate: time.Date(2020, 6, 15, 0, 0, 0, 0, time.UTC),
+				StopDate:  time.Date(2020, 6, 21, 0, 0, 0, 0, time.UTC),
+				User:      "abc",
+			},
+		},
+	}
+}
+
+func TestNewCalDAV(t *testing.T) {
+	if _, err := NewCalDAV("", testRotation, nil); err == nil {
+		t.Error("want error on empty calendar URL and didn't get one.")
+	}
+
+	if _, err := NewCalDAV("https://example.com/cal/", "", nil); err == nil {
+		t.Error("want error on empty rotation and didn't get one.")
+	}
+}
+
+func TestSync(t *testing.T) {
+	f := &fakeServer{
+		resources: map[string]string{
+			calendarPath + "standup.ics": humanEvent,
+			calendarPath + "release.ics": otherRotationEvent,
+		},
+	}
+	c := newTestCalDAV(t, f)
+
+	sched := testSchedule()
+	if err := event.Sync(c, sched, testRotation, render.Default()); err != nil {
+		t.Fatalf("error syncing schedule: %v", err)
+	}
+
+	if want, got := 5, len(f.resources); want != got {
+		t.Fatalf("want %v resources, got %v", want, got)
+	}
+	if f.resources[calendarPath+"standup.ics"] != humanEvent {
+		t.Errorf("event not created by the rotation was modified")
+	}
+	if f.resources[calendarPath+"release.ics"] != otherRotationEvent {
+		t.Errorf("event from another rotation was modified")
+	}
+
+	added := f.resources[calendarPath+"20200608-build-cop@rotation-scheduler.spinnaker.io.ics"]
+	for _, want := range []string{
+		"SUMMARY:xyz@example.com build-cop\r\n",
+		"DTSTART;VALUE=DATE:20200608\r\n",
+		"DTEND;VALUE=DATE:20200615\r\n",
+		"ATTENDEE;CN=xyz@example.com:mailto:xyz@example.com\r\n",
+	} {
+		if !strings.Contains(added, want) {
+			t.Errorf("missing line %q in:\n%v", want, added)
+		}
+	}
+
+	p, err := event.NewPlan(c, sched, testRotation, render.Default())
+	if err != nil {
+		t.Fatalf("error planning: %v", err)
+	}
+	if !p.IsEmpty() {
+		t.Errorf("want no changes after syncing, got:\n%v", p)
+	}
+
+	// Drop the last shift, and hand the first to someone else.
+	sched.Shifts = sched.Shifts[:2]
+	sched.Shifts[1].StopDate = time.Date(2020, 6, 14, 0, 0, 0, 0, time.UTC)
+	sched.Shifts[0].UserOverride = "def@example.com"
+
+	p, err = event.NewPlan(c, sched, testRotation, render.Default())
+	if err != nil {
+		t.Fatalf("error planning: %v", err)
+	}
+	if len(p.Inserts) != 0 || len(p.Updates) != 1 || len(p.Deletes) != 1 {
+		t.Fatalf("want 1 update and 1 delete, got:\n%v", p)
+	}
+	if err := p.Apply(c); err != nil {
+		t.Fatalf("error applying plan: %v", err)
+	}
+
+	if want, got := 4, len(f.resources); want != got {
+		t.Errorf("want %v resources, got %v", want, got)
+	}
+	updated := f.resources[calendarPath+"20200601-build-cop@rotation-scheduler.spinnaker.io.ics"]
+	if !strings.Contains(updated, "SUMMARY:def@example.com build-cop\r\n") {
+		t.Errorf("event was not updated:\n%v", updated)
+	}
+}
+
+func TestSyncTimedHumanEvent(t *testing.T) {
+	f := &fakeServer{
+		resources: map[string]string{
+			calendarPath + "planning.ics": timedHumanEvent,
+		},
+	}
+	c := newTestCalDAV(t, f)
+
+	sched := testSchedule()
+	p, err := event.NewPlan(c, sched, testRotation, render.Default())
+	if err != nil {
+		t.Fatalf("error planning: %v", err)
+	}
+	if len(p.Inserts) != 3 || len(p.Updates) != 0 || len(p.Deletes) != 0 {
+		t.Fatalf("want 3 inserts, got:\n%v", p)
+	}
+	if err := p.Apply(c); err != nil {
+		t.Fatalf("error applying plan: %v", err)
+	}
+
+	// The human-created event is listed next to the rotation's own, and still ignored.
+	sched.Shifts[1].UserOverride = ""
+	p, err = event.NewPlan(c, sched, testRotation, render.Default())
+	if err != nil {
+		t.Fatalf("error planning: %v", err)
+	}
+	want := `~ update "xyz@example.com build-cop" from 2020-06-08 to 2020-06-14, inviting xyz@example.com
+      to "lmn build-cop" from 2020-06-08 to 2020-06-14
+0 to insert, 1 to update, 0 to delete.
+`
+	if got := p.String(); want != got {
+		t.Errorf("want plan:\n%v\n\ngot:\n%v", want, got)
+	}
+	if err := p.Apply(c); err != nil {
+		t.Fatalf("error applying plan: %v", err)
+	}
+
+	if f.resources[calendarPath+"planning.ics"] != timedHumanEvent {
+		t.Errorf("event not created by the rotation was modified")
+	}
+}
+
+func TestUpdateConflict(t *testing.T) {
+	f := &fakeServer{resources: map[string]string{}}
+	c := newTestCalDAV(t, f)
+
+	sched := testSchedule()
+	if err := event.Sync(c, sched, testRotation, render.Default()); err != nil {
+		t.Fatalf("error syncing schedule: %v", err)
+	}
+
+	sched.Shifts = sched.Shifts[:2]
+	sched.Shifts[1].StopDate = time.Date(2020, 6, 14, 0, 0, 0, 0, time.UTC)
+	sched.Shifts[0].UserOverride = "def@example.com"
+	p, err := event.NewPlan(c, sched, testRotation, render.Default())
+	if err != nil {
+		t.Fatalf("error planning: %v", err)
+	}
+
+	// Someone edits both events after they're listed.
+	for _, key := range []string{"20200601", "20200615"} {
+		path := calendarPath + key + "-build-cop@rotation-scheduler.spinnaker.io.ics"
+		f.resources[path] = strings.Replace(f.resources[path], "SUMMARY:", "SUMMARY:edited ", 1)
+	}
+
+	if err := c.Update(p.Updates[0].Before, p.Updates[0].After); err == nil || !strings.Contains(err.Error(), "conflict") {
+		t.Errorf("want conflict error updating an event changed since it was listed, got: %v", err)
+	}
+	if err := c.Delete(p.Deletes[0]); err == nil || !strings.Contains(err.Error(), "conflict") {
+		t.Errorf("want conflict error deleting an event changed since it was listed, got: %v", err)
+	}
+	if want, got := 3, len(f.resources); want != got {
+		t.Errorf("want %v resources, got %v", want, got)
+	}
+}
+
+func TestInsertExisting(t *testing.T) {
+	f := &fakeServer{resources: map[string]string{}}
+	c := newTestCalDAV(t, f)
+
+	events, err := event.Events(testSchedule(), testRotation, render.Default())
+	if err != nil {
+		t.Fatalf("error creating events: %v", err)
+	}
+
+	if err := c.Insert(events[0]); err != nil {
+		t.Fatalf("error inserting event: %v", err)
+	}
+	if err := c.Insert(events[0]); err == nil {
+		t.Error("want error when inserting over an existing resource and didn't get one.")
+	}
+}
+
+func TestUnauthorized(t *testing.T) {
+	srv := httptest.NewServer(&fakeServer{resources: map[string]string{}})
+	defer srv.Close()
+
+	c, err := NewCalDAV(srv.URL+calendarPath, testRotation, nil)
+	if err != nil {
+		t.Fatalf("error creating CalDAV: %v", err)
+	}
+
+	if _, err := c.List(); err == nil {
+		t.Error("want error without credentials and didn't get one.")
+	}
+}
@@ -1,16 +1,87 @@
 package cmd
 
 import (
+	"fmt"
+	"io/ioutil"
+
+	"github.com/ghodss/yaml"
 	"github.com/spf13/cobra"
+	"github.com/spinnaker/rotation-scheduler/render"
 )
 
 var calendarCmd = &cobra.Command{
 	Use:   "calendar",
 	Short: "Shared calender manipulation functions",
 	Long: "Many users prefer to have their shifts reflected on their calendar," +
-		"rather than having to check a text file and make their own calendar events.",
+		"rather than having to check a text file and make their own calendar events.\n\n" +
+		"Event summaries, descriptions, locations and attendees are Go text/templates " +
+		"(https://golang.org/pkg/text/template/), given either with flags or in a YAML file " +
+		"with 'summary', 'description', 'location' and 'attendees' keys, where flags take precedence. " +
+		"Templates can use these fields: .Rotation, .User (the user on duty), .ScheduledUser, " +
+		".UserOverride, .Start, .Stop (the first and inclusive last dates of all-day shifts, or the start and end " +
+		"times of timed shifts), .Index (starting from 0), .Type (empty for the default type), and .Roles, " +
+		"each with .Name, .User, .ScheduledUser and .UserOverride. " +
+		"Attendees renders a comma-separated list, and only email addresses are invited.",
 }
 
+var (
+	calendarName string
+
+	templatesPath string
+	templates     = &render.Templates{}
+)
+
 func init() {
+	calendarCmd.PersistentFlags().StringVarP(&calendarName, "name", "n", "Spinnaker OSS Build Cop",
+		"Optional. Name of the rotation. Used in calendar and event names, and to tell this rotation's events apart "+
+			"from any others on a shared calendar. Changing it orphans previously synced events.")
+
+	calendarCmd.PersistentFlags().StringVar(&templatesPath, "templates", "",
+		"Optional. A YAML file with event templates.")
+	_ = calendarCmd.MarkPersistentFlagFilename("templates", "yaml")
+
+	calendarCmd.PersistentFlags().StringVar(&templates.Summary, "summary", "",
+		"Optional. Template for event summaries. Defaults to '"+render.DefaultSummary+"'.")
+	calendarCmd.PersistentFlags().StringVar(&templates.Description, "description", "",
+		"Optional. Template for event descriptions.")
+	calendarCmd.PersistentFlags().StringVar(&templates.Location, "location", "",
+		"Optional. Template for event locations.")
+	calendarCmd.PersistentFlags().StringVar(&templates.Attendees, "attendees", "",
+		"Optional. Template for event attendees. Defaults to '"+render.DefaultAttendees+"'.")
+
 	RootCmd.AddCommand(calendarCmd)
 }
+
+// renderer combines the --templates file with any individual template flags.
+func renderer() (*render.Renderer, error) {
+	merged := &render.Templates{}
+	if templatesPath != "" {
+		b, err := ioutil.ReadFile(templatesPath)
+		if err != nil {
+			return nil, fmt.Errorf("error reading templates file(%v): %v", templatesPath, err)
+		}
+		if err := yaml.Unmarshal(b, merged); err != nil {
+			return nil, fmt.Errorf("error unmarshalling templates: %v", err)
+		}
+	}
+
+	for _, f := range []struct {
+		flag string
+		dest *string
+	}{
+		{templates.Summary, &merged.Summary},
+		{templates.Description, &merged.Description},
+		{templates.Location, &merged.Location},
+		{templates.Attendees, &merged.Attendees},
+	} {
+		if f.flag != "" {
+			*f.dest = f.flag
+		}
+	}
+
+	r, err := render.NewRenderer(merged)
+	if err != nil {
+		return nil, fmt.Errorf("invalid templates: %v", err)
+	}
+	return r, nil
+}
@@ -0,0 +1,99 @@
+package cmd
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+	"github.com/spinnaker/rotation-scheduler/ical"
+	"github.com/spinnaker/rotation-scheduler/schedule"
+)
+
+var (
+	exportCmd = &cobra.Command{
+		Use:   "export scheduleFilePath [outputPath]",
+		Short: "Export a schedule to an iCalendar (.ics) file.",
+		Long: `Exports each shift as an event in an iCalendar (RFC 5545) file, which can be imported
+into, or subscribed to from, most calendar clients. Shifts are all-day events, unless the
+schedule has a handoffTime or follows the sun, in which case they're timed events in the
+schedule's or their region's time zone. Each event's UID is derived from its shift's start, so
+re-importing an updated schedule updates existing events rather than duplicating them.
+
+With '--splitByUser', outputPath is a directory, and one file named '<user>.ics' is written
+for each user with at least one shift.`,
+		Args: cobra.RangeArgs(1, 2),
+		RunE: executeExport,
+	}
+
+	splitByUser bool
+)
+
+func init() {
+	exportCmd.Flags().BoolVar(&splitByUser, "splitByUser", false,
+		"Optional. Write a separate calendar for each user into the outputPath directory.")
+
+	calendarCmd.AddCommand(exportCmd)
+}
+
+func executeExport(_ *cobra.Command, args []string) error {
+	sched, err := readSchedule(args[0])
+	if err != nil {
+		return fmt.Errorf("error reading schedule: %v", err)
+	}
+
+	cal, err := ical.NewCalendar(calendarName, time.Now())
+	if err != nil {
+		return fmt.Errorf("error initializing calendar: %v", err)
+	}
+
+	if cal.Renderer, err = renderer(); err != nil {
+		return err
+	}
+
+	if splitByUser {
+		if len(args) != 2 {
+			return fmt.Errorf("an outputPath directory is required with --splitByUser")
+		}
+		return exportByUser(cal, sched, args[1])
+	}
+
+	buf := &bytes.Buffer{}
+	if err := cal.Encode(buf, sched); err != nil {
+		return fmt.Errorf("error encoding calendar: %v", err)
+	}
+
+	destFilepath := os.Stdout.Name()
+	if len(args) == 2 {
+		destFilepath = args[1]
+	}
+
+	if err := ioutil.WriteFile(destFilepath, buf.Bytes(), 0666); err != nil {
+		return fmt.Errorf("error writing calendar: %v", err)
+	}
+	return nil
+}
+
+func exportByUser(cal *ical.Calendar, sched *schedule.Schedule, destDir string) error {
+	cals, err := cal.EncodeByUser(sched)
+	if err != nil {
+		return fmt.Errorf("error encoding calendars: %v", err)
+	}
+
+	if err := os.MkdirAll(destDir, 0777); err != nil {
+		return fmt.Errorf("error creating output directory: %v", err)
+	}
+
+	for user, b := range cals {
+		// Usernames and emails are generally safe filenames, but make sure they can't escape destDir.
+		filename := strings.ReplaceAll(user, string(filepath.Separator), "_") + ".ics"
+		if err := ioutil.WriteFile(filepath.Join(destDir, filename), b, 0666); err != nil {
+			return fmt.Errorf("error writing calendar for %v: %v", user, err)
+		}
+	}
+	return nil
+}
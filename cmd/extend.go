@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"io/ioutil"
 	"os"
+	"time"
 
 	"github.com/ghodss/yaml"
 	"github.com/spf13/cobra"
@@ -20,7 +21,21 @@ needs to be extended further into the future. During that time, users sometimes
 added or removed from the rotation. By default, the previous scheduled shifts won't be modified.
 if '--prune' is true, however, all shifts are reviewed to ensure a current member of the rotation
 owns that shift. If a shift is found from a now-unknown user, shifts from that point forward are
-rescheduled (regenerated) with the current rotation membership.`,
+rescheduled (regenerated) with the current rotation membership. Overrides of rescheduled shifts
+are kept where both users are still in the rotation and a shift still has the same dates and user; any
+that can't be kept are reported on stderr. With '--reassign', only the
+shifts of users no longer in the rotation are reassigned, each to the best replacement, and
+every shift that changed hands is reported. Shifts starting within '--freezeDays' of today, and
+shifts locked with 'lock', are never changed; any held by users no longer in the rotation are
+reported, so they can be overridden by hand.
+
+The rotation continues exactly where the schedule's 'rotation' key says it left off. Users
+added since join at the end of the current cycle, and the saved shift duration or pattern is
+used unless '--shiftDurationDays' or '--shiftPattern' is given.
+
+Pruning keeps the shift on duty today, in the schedule's time zone (or '--timezone'), and
+counting from its handoff time. Use '--asOf' to prune as of a fixed date instead, which
+makes the result reproducible.`,
 		Args: cobra.MaximumNArgs(1),
 		RunE: executeExtend,
 	}
@@ -28,6 +43,12 @@ rescheduled (regenerated) with the current rotation membership.`,
 	previousSchedulePath string
 
 	prune bool
+
+	reassign bool
+
+	freezeDays int
+
+	asOfStr string
 )
 
 func init() {
@@ -37,25 +58,80 @@ func init() {
 
 	extendCmd.Flags().BoolVarP(&prune, "prune", "p", false, "Prune removes all shifts before the current shift and reschedules all shifts if shift owners are no longer in rotation.")
 
+	extendCmd.Flags().BoolVar(&reassign, "reassign", false, "Optional. With --prune, only reassign the shifts of users no longer in the rotation, instead of rescheduling every shift from the first of them. Shifts that changed hands are reported on stderr.")
+
+	extendCmd.Flags().IntVar(&freezeDays, "freezeDays", 0, "Optional. With --prune, never reschedule or reassign shifts starting within this many days of today (or --asOf). Frozen shifts held by users no longer in the rotation are reported on stderr, to be overridden by hand.")
+
+	extendCmd.Flags().StringVar(&asOfStr, "asOf", "", "Optional. Prune as if it were this date, instead of today in --timezone (or the schedule's time zone). Must be in the format yyyy-mm-dd.")
+
+	extendCmd.Flags().StringVar(&stopStr, "stop", "", "Required. Generate schedule stopping on this date (inclusive). Must be in the format "+startStopFormat)
+	_ = extendCmd.MarkFlagRequired("stop")
+
 	scheduleCmd.AddCommand(extendCmd)
 }
 
-func executeExtend(_ *cobra.Command, args []string) error {
+func executeExtend(cmd *cobra.Command, args []string) error {
 	if err := parseTimeFlags(); err != nil {
 		return err
 	}
 
-	sched, err := previousSchedule(previousSchedulePath)
+	sched, err := readSchedule(previousSchedulePath)
 	if err != nil {
 		return fmt.Errorf("error parsing previous schedule: %v", err)
 	}
 
-	userSrc, err := userSrc()
+	roster, err := readRoster()
+	if err != nil {
+		return err
+	}
+
+	userSrc, err := userSrc(roster, sched.Roster)
+	if err != nil {
+		return err
+	}
+
+	opts, err := schedulerOptions(roster)
 	if err != nil {
 		return err
 	}
+	opts = append(opts, scheduler.WithReport(func(r scheduler.Report) {
+		fmt.Fprintln(os.Stderr, r)
+	}))
+	if reassign {
+		if !prune {
+			return fmt.Errorf("--reassign requires --prune")
+		}
+		opts = append(opts, scheduler.WithReassign())
+	}
+	if freezeDays != 0 {
+		if !prune {
+			return fmt.Errorf("--freezeDays requires --prune")
+		}
+		opts = append(opts, scheduler.WithFreezeDays(freezeDays))
+	}
+	if asOfStr != "" {
+		asOf, err := time.Parse(startStopFormat, asOfStr)
+		if err != nil {
+			return fmt.Errorf("error parsing --asOf: %v", err)
+		}
+		opts = append(opts, scheduler.WithAsOf(asOf))
+	}
+
+	durationDays := shiftDurationDays
+	if !cmd.Flags().Changed("shiftDurationDays") && !cmd.Flags().Changed("shiftPattern") && sched.Rotation != nil {
+		if sched.Rotation.ShiftDurationDays != 0 {
+			durationDays = sched.Rotation.ShiftDurationDays
+		}
+		if sched.Rotation.ShiftPattern != "" {
+			pattern, err := scheduler.ParsePattern(sched.Rotation.ShiftPattern)
+			if err != nil {
+				return fmt.Errorf("invalid shift pattern in schedule: %v", err)
+			}
+			opts = append(opts, scheduler.WithPattern(pattern))
+		}
+	}
 
-	schdlr, err := scheduler.NewScheduler(userSrc, shiftDurationDays)
+	schdlr, err := scheduler.NewScheduler(userSrc, durationDays, opts...)
 	if err != nil {
 		return fmt.Errorf("error creating new scheduler: %v", err)
 	}
@@ -73,8 +149,8 @@ func executeExtend(_ *cobra.Command, args []string) error {
 	return marshalSchedule(sched, destFilepath)
 }
 
-func previousSchedule(previousSchedulePath string) (*schedule.Schedule, error) {
-	prevBytes, err := ioutil.ReadFile(previousSchedulePath)
+func readSchedule(schedulePath string) (*schedule.Schedule, error) {
+	prevBytes, err := ioutil.ReadFile(schedulePath)
 	if err != nil {
 		return nil, err
 	}
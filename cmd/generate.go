@@ -27,13 +27,16 @@ $ rotation schedule generate \
 	}
 
 	startStr  string
-	startTime = time.Now().AddDate(0, 0, 1).Truncate(24 * time.Hour) // default to tomorrow, local time.
+	startTime time.Time
 )
 
 func init() {
 	generateCmd.Flags().StringVar(&startStr, "start", "",
 		"Optional. Generate schedule starting on this date (inclusive). "+
-			"Defaults to tomorrow's date. Must be in the format yyyy-mm-dd.")
+			"Defaults to tomorrow's date in --timezone. Must be in the format yyyy-mm-dd.")
+
+	generateCmd.Flags().StringVar(&stopStr, "stop", "", "Required. Generate schedule stopping on this date (inclusive). Must be in the format "+startStopFormat)
+	_ = generateCmd.MarkFlagRequired("stop")
 
 	scheduleCmd.AddCommand(generateCmd)
 }
@@ -44,6 +47,16 @@ func parseTimeFlags() error {
 		if startTime, err = time.Parse(startStopFormat, startStr); err != nil {
 			return fmt.Errorf("error parsing --start: %v", err)
 		}
+	} else {
+		// Default to tomorrow.
+		loc := time.UTC
+		if timeZone != "" {
+			if loc, err = time.LoadLocation(timeZone); err != nil {
+				return fmt.Errorf("error parsing --timezone: %v", err)
+			}
+		}
+		now := time.Now().In(loc)
+		startTime = time.Date(now.Year(), now.Month(), now.Day()+1, 0, 0, 0, 0, time.UTC)
 	}
 	if stopStr != "" {
 		if stopTime, err = time.Parse(startStopFormat, stopStr); err != nil {
@@ -59,12 +72,22 @@ func executeGenerate(_ *cobra.Command, args []string) error {
 		return err
 	}
 
-	userSrc, err := userSrc()
+	roster, err := readRoster()
+	if err != nil {
+		return err
+	}
+
+	userSrc, err := userSrc(roster)
+	if err != nil {
+		return err
+	}
+
+	opts, err := schedulerOptions(roster)
 	if err != nil {
 		return err
 	}
 
-	schdlr, err := scheduler.NewScheduler(userSrc, shiftDurationDays)
+	schdlr, err := scheduler.NewScheduler(userSrc, shiftDurationDays, opts...)
 	if err != nil {
 		return fmt.Errorf("error creating new scheduler: %v", err)
 	}
@@ -0,0 +1,72 @@
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/spf13/cobra"
+	"github.com/spinnaker/rotation-scheduler/schedule"
+)
+
+var lintCmd = &cobra.Command{
+	Use:   "lint scheduleFilePath",
+	Short: "Reports shifts that need attention.",
+	Long: `Validates a schedule, then lists any shifts held by a user who is unavailable during
+the shift, according to the schedule's roster or '--roster', on duty again within the
+schedule's cooldown or '--cooldownDays' and '--cooldownShifts', or breaking any of the
+schedule's rules or '--rules'. Holidays for 'maxHolidayShifts' rules are read from
+'--holidays'. Exits with an error if any are found.`,
+	Args: cobra.ExactArgs(1),
+	RunE: executeLint,
+}
+
+func init() {
+	scheduleCmd.AddCommand(lintCmd)
+}
+
+func executeLint(_ *cobra.Command, args []string) error {
+	sched, err := readSchedule(args[0])
+	if err != nil {
+		return fmt.Errorf("error reading schedule: %v", err)
+	}
+
+	if err := sched.Validate(); err != nil {
+		return fmt.Errorf("invalid schedule: %v", err)
+	}
+
+	roster, err := readRoster()
+	if err != nil {
+		return err
+	}
+
+	c := cooldown()
+	if err := c.Validate(); err != nil {
+		return fmt.Errorf("invalid cooldown: %v", err)
+	}
+
+	var rules []*schedule.Rule
+	if rulesPath != "" {
+		if rules, err = readRules(); err != nil {
+			return err
+		}
+		for _, r := range rules {
+			if err := r.Validate(); err != nil {
+				return fmt.Errorf("invalid rule: %v", err)
+			}
+		}
+	}
+
+	holidays, err := readHolidays()
+	if err != nil {
+		return err
+	}
+
+	problems := sched.Lint(roster, c, rules, holidays)
+	for _, p := range problems {
+		fmt.Println(p)
+	}
+
+	if len(problems) != 0 {
+		return fmt.Errorf("found %v problem(s)", len(problems))
+	}
+	return nil
+}
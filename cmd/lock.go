@@ -0,0 +1,70 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/spf13/cobra"
+)
+
+var (
+	lockCmd = &cobra.Command{
+		Use:   "lock scheduleFilePath",
+		Short: "Locks shifts so they're never rescheduled.",
+		Long: `Locks every shift of a schedule that overlaps '--start' to '--stop', like a week promised
+to someone around a launch. Locked shifts are never changed when extending the schedule, even
+with '--prune' or '--reassign', or if their users leave the rotation: shifts rescheduled around
+them stop short of them, and the rotation continues after them. Use '--unlock' to unlock them
+again. The schedule is updated in place.`,
+		Args: cobra.ExactArgs(1),
+		RunE: executeLock,
+	}
+
+	unlock bool
+)
+
+func init() {
+	lockCmd.Flags().StringVar(&startStr, "start", "", "Required. Lock shifts overlapping this date (inclusive) onwards. Must be in the format "+startStopFormat)
+	_ = lockCmd.MarkFlagRequired("start")
+
+	lockCmd.Flags().StringVar(&stopStr, "stop", "", "Optional. Lock shifts up to this date (inclusive). Defaults to --start. Must be in the format "+startStopFormat)
+
+	lockCmd.Flags().BoolVar(&unlock, "unlock", false, "Optional. Unlock the shifts instead.")
+
+	scheduleCmd.AddCommand(lockCmd)
+}
+
+func executeLock(_ *cobra.Command, args []string) error {
+	start, err := time.Parse(startStopFormat, startStr)
+	if err != nil {
+		return fmt.Errorf("error parsing --start: %v", err)
+	}
+
+	stop := start
+	if stopStr != "" {
+		if stop, err = time.Parse(startStopFormat, stopStr); err != nil {
+			return fmt.Errorf("error parsing --stop: %v", err)
+		}
+	}
+	if stop.Before(start) {
+		return fmt.Errorf("--stop cannot be before --start")
+	}
+
+	sched, err := readSchedule(args[0])
+	if err != nil {
+		return fmt.Errorf("error reading schedule: %v", err)
+	}
+
+	if err := sched.Validate(); err != nil {
+		return fmt.Errorf("invalid schedule: %v", err)
+	}
+
+	verb := "locked"
+	if unlock {
+		verb = "unlocked"
+	}
+	fmt.Fprintf(os.Stderr, "%v %v shift(s)\n", verb, sched.Lock(start, stop, !unlock))
+
+	return marshalSchedule(sched, args[0])
+}
@@ -6,11 +6,17 @@ import (
 	"io"
 	"io/ioutil"
 	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
 	"time"
 
 	"github.com/ghodss/yaml"
 	"github.com/spf13/cobra"
+	"github.com/spinnaker/rotation-scheduler/ical"
 	"github.com/spinnaker/rotation-scheduler/schedule"
+	"github.com/spinnaker/rotation-scheduler/schedule/scheduler"
 	"github.com/spinnaker/rotation-scheduler/users"
 	"github.com/spinnaker/rotation-scheduler/users/ghteams"
 	"golang.org/x/oauth2"
@@ -23,30 +29,75 @@ var (
 		Short: "Schedule creation and extension functions.",
 		Long: "These options control the shift output, like providing the user list " +
 			"(or how to get a user list) and how long to make each shift. For GitHub team integration, " +
-			"users can be invited to their shift by making their email public.",
+			"users can be invited to their shift by making their email public.\n\n" +
+			"Handoff times, shift patterns and types, regions, roles, rosters, cooldowns, rules, holidays and " +
+			"orders are described with their flags below, and with examples in the README.",
 	}
 
 	stopStr  string
 	stopTime time.Time
 
 	shiftDurationDays int
+	shiftPattern      string
+
+	handoffTime string
+	timeZone    string
 
 	userList    []string
 	githubFlags []string
+	roleFlags   []string
+	typeFlags   []string
+	regionFlags []string
+
+	rosterPath string
+
+	cooldownDays   int
+	cooldownShifts int
+
+	rulesPath string
+
+	holidayPaths []string
 
 	emailDomains []string
+
+	orderStr string
+	seed     int64
 )
 
 func init() {
-	scheduleCmd.PersistentFlags().StringVar(&stopStr, "stop", "", "Required. Generate schedule stopping on this date (inclusive). Must be in the format "+startStopFormat)
-	_ = scheduleCmd.MarkPersistentFlagRequired("stop")
+	scheduleCmd.PersistentFlags().IntVarP(&shiftDurationDays, "shiftDurationDays", "d", 7, "Optional. Duration in days for each shift. Defaults to 7, or the schedule's existing duration when extending. Must be a positive integer.")
+
+	scheduleCmd.PersistentFlags().StringVar(&shiftPattern, "shiftPattern", "", "Optional. When shifts change hands, instead of every --shiftDurationDays. One of 'days:N', 'weeks:N:WEEKDAY' like 'weeks:2:monday', 'months:N' for calendar months, or 'workweek' for Monday to Friday then the weekend. Defaults to the schedule's existing pattern when extending.")
 
-	scheduleCmd.PersistentFlags().IntVarP(&shiftDurationDays, "shiftDurationDays", "d", 7, "Optional. Duration in days for each shift. Defaults to 7, must be a positive integer.")
+	scheduleCmd.PersistentFlags().StringVar(&handoffTime, "handoffTime", "", "Optional. Time of day shifts change hands, like '10:00'. Must be in the format "+schedule.HandoffTimeFormat+". Defaults to whole-day shifts, or the schedule's existing handoff time when extending.")
 
-	scheduleCmd.PersistentFlags().StringSliceVarP(&userList, "users", "u", []string{}, "Set of users for the rotation. Required if --github* options are not specified.")
+	scheduleCmd.PersistentFlags().StringVar(&timeZone, "timezone", "", "Optional. IANA time zone of --handoffTime, and of today's date when pruning, like 'America/Los_Angeles'. Defaults to UTC, or the schedule's existing time zone when extending.")
+
+	scheduleCmd.PersistentFlags().StringSliceVarP(&userList, "users", "u", []string{}, "Set of users for the rotation. Defaults to the members of --roster and the schedule's roster if --github* options are not specified either. Part-time members can be given a weight between 0 and 1, like 'abc:0.5', to take that fraction of their turns.")
 
 	scheduleCmd.PersistentFlags().StringSliceVarP(&githubFlags, "github", "g", []string{}, "Fetch the user list from GitHub. Order of args must be 'organization,team,accessToken'. Must specify an access token with read:org permissions.")
 
+	scheduleCmd.PersistentFlags().StringArrayVar(&roleFlags, "role", []string{}, "Optional. An additional role for every shift, and its users, like 'secondary=abc,lmn:0.5,xyz'. Can be repeated. Roles are filled in the order given.")
+
+	scheduleCmd.PersistentFlags().StringArrayVar(&typeFlags, "shiftType", []string{}, "Optional. Another type of shift, the weekdays it starts on, and its users, like 'weekend:sat,sun=abc,lmn'. Shifts starting on those weekdays are filled from its users instead of --users. Can be repeated.")
+
+	scheduleCmd.PersistentFlags().StringArrayVar(&regionFlags, "region", []string{}, "Optional. A region of a follow-the-sun rotation, the time of day it takes over in its IANA time zone, and its users, like 'apac:09:00:Asia/Singapore=abc,lmn'. Repeat it for each region. Shifts are timed, and filled from the users of the region on duty instead of --users. Must be given again when extending.")
+
+	scheduleCmd.PersistentFlags().StringVar(&rosterPath, "roster", "", "Optional. A YAML file listing members' weights, when they join and leave the rotation, and when they're unavailable, in addition to any roster in the schedule.")
+	_ = scheduleCmd.MarkPersistentFlagFilename("roster", "yaml")
+
+	scheduleCmd.PersistentFlags().IntVar(&cooldownDays, "cooldownDays", 0, "Optional. Minimum number of days between the end of a user's shift and the start of their next one, in any role, in addition to any cooldown in the schedule.")
+
+	scheduleCmd.PersistentFlags().IntVar(&cooldownShifts, "cooldownShifts", 0, "Optional. Minimum number of other shifts between two of a user's shifts, in any role, in addition to any cooldown in the schedule.")
+
+	scheduleCmd.PersistentFlags().StringVar(&rulesPath, "rules", "", "Optional. A YAML list of rules every shift must follow, in addition to any rules in the schedule, like 'notConsecutive', 'atLeastOne', 'unavailable', or 'maxHolidayShifts' for a list of 'users'.")
+
+	scheduleCmd.PersistentFlags().StringArrayVar(&holidayPaths, "holidays", []string{}, "Optional. An iCalendar (.ics) file of holidays, or a YAML list of holidays with 'date' (yyyy-mm-dd) and 'name' keys. Can be repeated, like once per region.")
+
+	scheduleCmd.PersistentFlags().StringVar(&orderStr, "order", string(users.Alphabetical), fmt.Sprintf("Optional. The order users take turns in. One of %v.", users.Orders))
+
+	scheduleCmd.PersistentFlags().Int64Var(&seed, "seed", 0, "Optional. Seed of the random order of '--order shuffle'. The same seed always gives the same order.")
+
 	scheduleCmd.PersistentFlags().StringSliceVar(&emailDomains, "domains", []string{"*"}, "Only include email addresses from --github that match these domains. A single value of '*' will allow any domain. Use '--domains []' to only use GitHub usernames.")
 
 	RootCmd.AddCommand(scheduleCmd)
@@ -67,18 +118,25 @@ func parseGithubDetails() (*githubDetails, error) {
 	}, nil
 }
 
-func userSrc() (users.Source, error) {
-	var userSrc users.Source
+// userSrc creates the user source from --users or --github, or otherwise the members of the rosters. Users are weighted
+// by any weights given in --users, then in each roster, in order of precedence. Rosters may be nil.
+func userSrc(rosters ...*schedule.Roster) (users.Source, error) {
+	var names []string
+	weights := map[string]float64{}
 
 	if len(userList) != 0 {
-		userSrc = users.NewStaticSource(userList...)
+		var err error
+		names, weights, err = parseWeightedUsers(userList)
+		if err != nil {
+			return nil, fmt.Errorf("invalid --users value: %v", err)
+		}
 	} else if len(githubFlags) != 0 {
 		github, err := parseGithubDetails()
 		if err != nil {
 			return nil, err
 		}
 		client, closer, err := ghHttpClient(github)
-		userSrc, err = ghteams.NewGitHubTeamsUserSource(client, github.org, github.team, emailDomains...)
+		src, err := ghteams.NewGitHubTeamsUserSource(client, github.org, github.team, emailDomains...)
 		if err != nil {
 			return nil, fmt.Errorf("error creating GitHub users source: %v", err)
 		}
@@ -87,9 +145,306 @@ func userSrc() (users.Source, error) {
 				_ = closer.Close()
 			}
 		}()
+		names = rosterOrder(src.Users(), rosters...)
+	} else {
+		names = rosterOrder(nil, rosters...)
+	}
+
+	if names == nil && len(regionFlags) != 0 {
+		// Every shift is filled from the users of its region, so these are never on duty.
+		names = regionUsers()
+	}
+
+	if names == nil {
+		return nil, nil
+	}
+
+	for _, r := range rosters {
+		for user, w := range r.Weights() {
+			if _, ok := weights[user]; !ok {
+				weights[user] = w
+			}
+		}
+	}
+
+	return newSource(names, weights, seed)
+}
+
+// newSource creates a source of names in the --order order, weighted by weights.
+func newSource(names []string, weights map[string]float64, seed int64) (users.Source, error) {
+	src, err := users.NewSource(users.Order(orderStr), seed, names...)
+	if err != nil {
+		return nil, fmt.Errorf("invalid --order value: %v", err)
+	}
+
+	if len(weights) == 0 {
+		return src, nil
+	}
+	return users.NewWeightedSource(src, weights)
+}
+
+// rosterOrder sorts names in the order they're listed in rosters, with names not in any roster last, in their existing
+// order. If names is nil, it returns every member of the rosters instead.
+func rosterOrder(names []string, rosters ...*schedule.Roster) []string {
+	var ordered []string
+	seen := map[string]bool{}
+	for _, r := range rosters {
+		if r == nil {
+			continue
+		}
+		for _, m := range r.Members {
+			if names == nil && !seen[strings.ToLower(m.User)] {
+				ordered = append(ordered, m.User)
+				seen[strings.ToLower(m.User)] = true
+			}
+			for _, n := range names {
+				if strings.EqualFold(n, m.User) && !seen[strings.ToLower(n)] {
+					ordered = append(ordered, n)
+					seen[strings.ToLower(n)] = true
+				}
+			}
+		}
+	}
+
+	for _, n := range names {
+		if !seen[strings.ToLower(n)] {
+			ordered = append(ordered, n)
+		}
+	}
+	return ordered
+}
+
+// parseShiftType parses a --shiftType value, like 'weekend:sat,sun=abc,lmn:0.5', into a scheduler option. Its users
+// are ordered by seed.
+func parseShiftType(value string, seed int64) (scheduler.Option, error) {
+	parts := strings.SplitN(value, "=", 2)
+	if len(parts) != 2 || parts[1] == "" {
+		return nil, fmt.Errorf("must be 'type:weekday1,weekday2=user1,user2'")
+	}
+	nameDays := strings.SplitN(parts[0], ":", 2)
+	if len(nameDays) != 2 || nameDays[0] == "" || nameDays[1] == "" {
+		return nil, fmt.Errorf("must be 'type:weekday1,weekday2=user1,user2'")
+	}
+
+	var weekdays []time.Weekday
+	for _, d := range strings.Split(nameDays[1], ",") {
+		weekday, err := scheduler.ParseWeekday(d)
+		if err != nil {
+			return nil, err
+		}
+		weekdays = append(weekdays, weekday)
+	}
+
+	names, weights, err := parseWeightedUsers(strings.Split(parts[1], ","))
+	if err != nil {
+		return nil, err
+	}
+	src, err := newSource(names, weights, seed)
+	if err != nil {
+		return nil, err
+	}
+	return scheduler.WithShiftType(nameDays[0], src, weekdays...), nil
+}
+
+// parseRegion reads a --region value, like 'apac:09:00:Asia/Singapore=abc,lmn', into an option adding the region.
+func parseRegion(value string, seed int64) (scheduler.Option, error) {
+	parts := strings.SplitN(value, "=", 2)
+	if len(parts) != 2 || parts[1] == "" {
+		return nil, fmt.Errorf("must be 'region:hh:mm:timeZone=user1,user2'")
+	}
+	spec := strings.SplitN(parts[0], ":", 4)
+	if len(spec) != 4 || spec[0] == "" || spec[3] == "" {
+		return nil, fmt.Errorf("must be 'region:hh:mm:timeZone=user1,user2'")
+	}
+
+	names, weights, err := parseWeightedUsers(strings.Split(parts[1], ","))
+	if err != nil {
+		return nil, err
+	}
+	src, err := newSource(names, weights, seed)
+	if err != nil {
+		return nil, err
+	}
+	return scheduler.WithRegion(spec[0], spec[1]+":"+spec[2], spec[3], src), nil
+}
+
+// regionUsers returns the users of every --region, ignoring invalid values, which are reported by parseRegion.
+func regionUsers() []string {
+	var names []string
+	for _, r := range regionFlags {
+		if parts := strings.SplitN(r, "=", 2); len(parts) == 2 {
+			if users, _, err := parseWeightedUsers(strings.Split(parts[1], ",")); err == nil {
+				names = append(names, users...)
+			}
+		}
+	}
+	return names
+}
+
+// parseWeightedUsers splits values like 'abc:0.5' into the user and their weight. Users without a weight aren't in the
+// returned map.
+func parseWeightedUsers(values []string) ([]string, map[string]float64, error) {
+	names := make([]string, 0, len(values))
+	weights := map[string]float64{}
+	for _, v := range values {
+		i := strings.LastIndex(v, ":")
+		if i == -1 {
+			names = append(names, v)
+			continue
+		}
+
+		w, err := strconv.ParseFloat(v[i+1:], 64)
+		if err != nil {
+			return nil, nil, fmt.Errorf("invalid weight for %v: %v", v[:i], err)
+		}
+		names = append(names, v[:i])
+		weights[strings.ToLower(v[:i])] = w
+	}
+	return names, weights, nil
+}
+
+// schedulerOptions returns the scheduler options common to all schedule commands. roster is the --roster file, and
+// may be nil.
+func schedulerOptions(roster *schedule.Roster) ([]scheduler.Option, error) {
+	opts := []scheduler.Option{scheduler.WithHandoff(handoffTime, timeZone)}
+	if shiftPattern != "" {
+		pattern, err := scheduler.ParsePattern(shiftPattern)
+		if err != nil {
+			return nil, fmt.Errorf("invalid --shiftPattern value: %v", err)
+		}
+		opts = append(opts, scheduler.WithPattern(pattern))
+	}
+
+	for i, r := range roleFlags {
+		parts := strings.SplitN(r, "=", 2)
+		if len(parts) != 2 || parts[0] == "" || parts[1] == "" {
+			return nil, fmt.Errorf("invalid --role value %q. Must be 'role=user1,user2'", r)
+		}
+		names, weights, err := parseWeightedUsers(strings.Split(parts[1], ","))
+		if err != nil {
+			return nil, fmt.Errorf("invalid --role value %q: %v", r, err)
+		}
+
+		// Each role gets its own seed, so roles with the same users aren't shuffled in lockstep.
+		src, err := newSource(names, weights, seed+int64(i)+1)
+		if err != nil {
+			return nil, fmt.Errorf("invalid --role value %q: %v", r, err)
+		}
+		opts = append(opts, scheduler.WithRole(parts[0], src))
+	}
+
+	for i, t := range typeFlags {
+		opt, err := parseShiftType(t, seed+int64(len(roleFlags)+i)+1)
+		if err != nil {
+			return nil, fmt.Errorf("invalid --shiftType value %q: %v", t, err)
+		}
+		opts = append(opts, opt)
+	}
+
+	for i, r := range regionFlags {
+		opt, err := parseRegion(r, seed+int64(len(roleFlags)+len(typeFlags)+i)+1)
+		if err != nil {
+			return nil, fmt.Errorf("invalid --region value %q: %v", r, err)
+		}
+		opts = append(opts, opt)
+	}
+
+	if roster != nil {
+		opts = append(opts, scheduler.WithRoster(roster))
+	}
+
+	if c := cooldown(); c != nil {
+		opts = append(opts, scheduler.WithCooldown(c))
 	}
 
-	return userSrc, nil
+	if rulesPath != "" {
+		rules, err := readRules()
+		if err != nil {
+			return nil, err
+		}
+		opts = append(opts, scheduler.WithRules(rules))
+	}
+
+	if len(holidayPaths) != 0 {
+		holidays, err := readHolidays()
+		if err != nil {
+			return nil, err
+		}
+		opts = append(opts, scheduler.WithHolidays(holidays))
+	}
+	return opts, nil
+}
+
+// cooldown returns the cooldown given by --cooldownDays and --cooldownShifts, or nil if there isn't one.
+func cooldown() *schedule.Cooldown {
+	if cooldownDays == 0 && cooldownShifts == 0 {
+		return nil
+	}
+	return &schedule.Cooldown{Days: cooldownDays, Shifts: cooldownShifts}
+}
+
+// readHolidays reads every --holidays file.
+func readHolidays() ([]*schedule.Holiday, error) {
+	var holidays []*schedule.Holiday
+	for _, path := range holidayPaths {
+		var h []*schedule.Holiday
+		if strings.EqualFold(filepath.Ext(path), ".ics") {
+			f, err := os.Open(path)
+			if err != nil {
+				return nil, fmt.Errorf("error reading holidays file(%v): %v", path, err)
+			}
+			h, err = ical.DecodeHolidays(f)
+			_ = f.Close()
+			if err != nil {
+				return nil, fmt.Errorf("error decoding holidays file(%v): %v", path, err)
+			}
+		} else {
+			b, err := ioutil.ReadFile(path)
+			if err != nil {
+				return nil, fmt.Errorf("error reading holidays file(%v): %v", path, err)
+			}
+			if err := yaml.Unmarshal(b, &h); err != nil {
+				return nil, fmt.Errorf("error unmarshalling holidays file(%v): %v", path, err)
+			}
+		}
+		holidays = append(holidays, h...)
+	}
+	return holidays, nil
+}
+
+// readRoster reads the --roster file, if there is one.
+func readRoster() (*schedule.Roster, error) {
+	if rosterPath == "" {
+		return nil, nil
+	}
+
+	b, err := ioutil.ReadFile(rosterPath)
+	if err != nil {
+		return nil, fmt.Errorf("error reading roster file(%v): %v", rosterPath, err)
+	}
+
+	roster := &schedule.Roster{}
+	if err := yaml.Unmarshal(b, roster); err != nil {
+		return nil, fmt.Errorf("error unmarshalling roster: %v", err)
+	}
+	if err := roster.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid roster: %v", err)
+	}
+	return roster, nil
+}
+
+// readRules reads the --rules file.
+func readRules() ([]*schedule.Rule, error) {
+	b, err := ioutil.ReadFile(rulesPath)
+	if err != nil {
+		return nil, fmt.Errorf("error reading rules file(%v): %v", rulesPath, err)
+	}
+
+	var rules []*schedule.Rule
+	if err := yaml.Unmarshal(b, &rules); err != nil {
+		return nil, fmt.Errorf("error unmarshalling rules: %v", err)
+	}
+	return rules, nil
 }
 
 func ghHttpClient(github *githubDetails) (*http.Client, io.Closer, error) {
@@ -3,16 +3,22 @@ package cmd
 import (
 	"context"
 	"encoding/base64"
+	"encoding/json"
 	"fmt"
 	"io"
 	"io/ioutil"
 	"net/http"
+	"os"
 
 	"cloud.google.com/go/httpreplay"
 	"github.com/ghodss/yaml"
 	"github.com/spf13/cobra"
+	"github.com/spinnaker/rotation-scheduler/caldav"
+	"github.com/spinnaker/rotation-scheduler/event"
 	"github.com/spinnaker/rotation-scheduler/gcal"
+	"github.com/spinnaker/rotation-scheduler/outlook"
 	"github.com/spinnaker/rotation-scheduler/schedule"
+	"golang.org/x/oauth2/clientcredentials"
 	"golang.org/x/oauth2/google"
 	"google.golang.org/api/calendar/v3"
 	"google.golang.org/api/option"
@@ -22,32 +28,102 @@ var (
 	syncCmd = &cobra.Command{
 		Use:   "sync scheduleFilePath",
 		Short: "Sync a schedule to a shared calendar.",
-		Long: `A dedicated user to own these events is **strongly** recommended. 
-When a schedule of shifts is sync'ed, all events on that user's primary calendar 
-are cleared and each shift is added. The service account must be authorized to do 
-this to another users's calendar by granting it G Suite's "Domain-wide Delegation."`,
+		Long: `Syncs a schedule of shifts to a calendar. Events for new or changed shifts are added or
+updated, and events for shifts no longer in the schedule are deleted. Only events previously
+created for the rotation named by '--name' are ever updated or deleted; any other events on the
+calendar are left alone. Events for unchanged shifts aren't touched either, so attendees are not
+re-invited on every sync.
+
+The '--backend' flag picks the calendar service:
+
+  google: Any Google calendar the '--subject' user can edit, including secondary and shared
+          calendars. The service account must be authorized to act as the subject by granting
+          it G Suite's "Domain-wide Delegation."
+  caldav: A CalDAV calendar collection, like those served by Nextcloud, Radicale, iCloud or
+          Fastmail, given by '--caldavURL'. Credentials are sent with HTTP basic auth.
+  outlook: A Microsoft 365 / Outlook calendar of a user, shared mailbox or group, given by
+          '--outlookCalendar', using the Microsoft Graph API. The '--outlookClientID' app must
+          be granted the Calendars.ReadWrite application permission in the '--outlookTenantID'
+          tenant.`,
 		Args: cobra.ExactValidArgs(1),
 		RunE: executeSync,
 	}
 
+	backend string
+
 	jsonKeyBase64 string
 	calendarID    string
+	subject       string
+
+	caldavURL      string
+	caldavUsername string
+	caldavPassword string
+
+	outlookCalendar     string
+	outlookTenantID     string
+	outlookClientID     string
+	outlookClientSecret string
+
+	dryRun       bool
+	outputFormat string
 )
 
 func init() {
+	syncCmd.Flags().StringVarP(&backend, "backend", "b", "google",
+		"Optional. The calendar service to sync to. One of 'google', 'caldav' or 'outlook'.")
+
 	syncCmd.Flags().StringVarP(&jsonKeyBase64, "jsonKey", "j", "",
-		"Required. A base64-encoded service account key with access to the Calendar API. "+
+		"Required for the google backend. A base64-encoded service account key with access to the Calendar API. "+
 			"Service account must have domain-wide delegation. Create this value with something like "+
 			"'cat key.json | base64 -w 0'")
-	_ = syncCmd.MarkFlagRequired("jsonKey")
 
 	syncCmd.Flags().StringVarP(&calendarID, "calendarID", "c", "spinbot@spinnaker.io",
-		"Optional. The calendar ID to update. Must be a 'primary' user calendar.")
+		"Optional. The calendar ID to update. Can be a user's primary calendar or a secondary or shared calendar "+
+			"(like 'abc123@group.calendar.google.com') that --subject can edit.")
+
+	syncCmd.Flags().StringVar(&subject, "subject", "",
+		"Optional. The G Suite user the service account acts as. Defaults to --calendarID, which only works "+
+			"when it's a user's primary calendar.")
+
+	syncCmd.Flags().StringVar(&caldavURL, "caldavURL", "",
+		"Required for the caldav backend. The URL of the calendar collection, "+
+			"like 'https://cloud.example.com/remote.php/dav/calendars/user/oncall/'.")
+
+	syncCmd.Flags().StringVar(&caldavUsername, "caldavUsername", "",
+		"Optional. The username for the caldav backend.")
+
+	syncCmd.Flags().StringVar(&caldavPassword, "caldavPassword", "",
+		"Optional. The password for the caldav backend. Defaults to the CALDAV_PASSWORD environment variable. "+
+			"Prefer an app-specific password where the server supports them.")
+
+	syncCmd.Flags().StringVar(&outlookCalendar, "outlookCalendar", "",
+		"Required for the outlook backend. The Graph path of the calendar, like "+
+			"'users/oncall@example.com/calendar' or 'groups/{group-id}/calendar'.")
+
+	syncCmd.Flags().StringVar(&outlookTenantID, "outlookTenantID", "",
+		"Required for the outlook backend. The Azure AD tenant (directory) ID.")
+
+	syncCmd.Flags().StringVar(&outlookClientID, "outlookClientID", "",
+		"Required for the outlook backend. The application (client) ID of the app registration.")
+
+	syncCmd.Flags().StringVar(&outlookClientSecret, "outlookClientSecret", "",
+		"Optional. The client secret of the app registration. Defaults to the OUTLOOK_CLIENT_SECRET environment "+
+			"variable.")
+
+	syncCmd.Flags().BoolVar(&dryRun, "dryRun", false,
+		"Optional. Print the events that would be inserted, updated and deleted, without changing the calendar.")
+
+	syncCmd.Flags().StringVarP(&outputFormat, "output", "o", "text",
+		"Optional. Format of the --dryRun output. One of 'text' or 'json'.")
 
 	calendarCmd.AddCommand(syncCmd)
 }
 
 func executeSync(_ *cobra.Command, args []string) error {
+	if outputFormat != "text" && outputFormat != "json" {
+		return fmt.Errorf("invalid --output value %q. Must be 'text' or 'json'", outputFormat)
+	}
+
 	schedPath := args[0]
 	schedBytes, err := ioutil.ReadFile(schedPath)
 	if err != nil {
@@ -59,9 +135,9 @@ func executeSync(_ *cobra.Command, args []string) error {
 		return fmt.Errorf("error unmarshalling schedule: %v", err)
 	}
 
-	client, closer, err := gcalHttpClient()
+	b, closer, err := calendarBackend()
 	if err != nil {
-		return fmt.Errorf("error initializing HTTP client: %v", err)
+		return err
 	}
 	defer func() {
 		if closer != nil {
@@ -69,18 +145,106 @@ func executeSync(_ *cobra.Command, args []string) error {
 		}
 	}()
 
-	cal, err := gcal.NewGCal(calendarID, client)
+	r, err := renderer()
 	if err != nil {
-		return fmt.Errorf("error initializing Calendar service: %v", err)
+		return err
 	}
 
-	if err := cal.Schedule(sched); err != nil {
+	plan, err := event.NewPlan(b, sched, calendarName, r)
+	if err != nil {
+		return fmt.Errorf("error planning sync: %v", err)
+	}
+
+	if dryRun {
+		return printPlan(plan)
+	}
+
+	if err := plan.Apply(b); err != nil {
 		return fmt.Errorf("error syncing schedule: %v", err)
 	}
 
 	return nil
 }
 
+func printPlan(plan *event.Plan) error {
+	if outputFormat == "json" {
+		b, err := json.MarshalIndent(plan, "", "  ")
+		if err != nil {
+			return fmt.Errorf("error marshalling plan to json: %v", err)
+		}
+		fmt.Println(string(b))
+		return nil
+	}
+
+	fmt.Print(plan)
+	return nil
+}
+
+// calendarBackend creates the backend named by --backend. The closer, if not nil, must be closed when done.
+func calendarBackend() (event.Backend, io.Closer, error) {
+	switch backend {
+	case "google":
+		if jsonKeyBase64 == "" {
+			return nil, nil, fmt.Errorf("--jsonKey is required for the google backend")
+		}
+
+		client, closer, err := gcalHttpClient()
+		if err != nil {
+			return nil, nil, fmt.Errorf("error initializing HTTP client: %v", err)
+		}
+
+		cal, err := gcal.NewGCal(calendarID, calendarName, client)
+		if err != nil {
+			if closer != nil {
+				_ = closer.Close()
+			}
+			return nil, nil, fmt.Errorf("error initializing Calendar service: %v", err)
+		}
+		return cal, closer, nil
+	case "caldav":
+		if caldavURL == "" {
+			return nil, nil, fmt.Errorf("--caldavURL is required for the caldav backend")
+		}
+
+		password := caldavPassword
+		if password == "" {
+			password = os.Getenv("CALDAV_PASSWORD")
+		}
+
+		client := &http.Client{}
+		if caldavUsername != "" || password != "" {
+			client.Transport = &caldav.BasicAuthTransport{Username: caldavUsername, Password: password}
+		}
+
+		cal, err := caldav.NewCalDAV(caldavURL, calendarName, client)
+		if err != nil {
+			return nil, nil, fmt.Errorf("error initializing CalDAV calendar: %v", err)
+		}
+		return cal, nil, nil
+	case "outlook":
+		if outlookCalendar == "" || outlookTenantID == "" || outlookClientID == "" {
+			return nil, nil, fmt.Errorf(
+				"--outlookCalendar, --outlookTenantID and --outlookClientID are required for the outlook backend")
+		}
+
+		client, closer, err := outlookHttpClient()
+		if err != nil {
+			return nil, nil, fmt.Errorf("error initializing HTTP client: %v", err)
+		}
+
+		cal, err := outlook.NewOutlook(outlookCalendar, calendarName, client)
+		if err != nil {
+			if closer != nil {
+				_ = closer.Close()
+			}
+			return nil, nil, fmt.Errorf("error initializing Outlook calendar: %v", err)
+		}
+		return cal, closer, nil
+	default:
+		return nil, nil, fmt.Errorf("invalid --backend value %q. Must be 'google', 'caldav' or 'outlook'", backend)
+	}
+}
+
 func gcalHttpClient() (*http.Client, io.Closer, error) {
 	keyBytes, err := base64.StdEncoding.DecodeString(jsonKeyBase64)
 	if err != nil {
@@ -93,7 +257,10 @@ func gcalHttpClient() (*http.Client, io.Closer, error) {
 	// Since apparently service accounts don't have any associated quotas in GSuite,
 	// we must supply a user to charge quota against, and I think they need to have
 	// admin permission on the G Suite account to work.
-	jwtConfig.Subject = calendarID
+	jwtConfig.Subject = subject
+	if jwtConfig.Subject == "" {
+		jwtConfig.Subject = calendarID
+	}
 	ctx := context.Background()
 
 	if recordFilepath == "" {
@@ -112,3 +279,36 @@ func gcalHttpClient() (*http.Client, io.Closer, error) {
 	}
 	return client, r, nil
 }
+
+func outlookHttpClient() (*http.Client, io.Closer, error) {
+	secret := outlookClientSecret
+	if secret == "" {
+		secret = os.Getenv("OUTLOOK_CLIENT_SECRET")
+	}
+	if secret == "" {
+		return nil, nil, fmt.Errorf("--outlookClientSecret or OUTLOOK_CLIENT_SECRET is required")
+	}
+
+	config := &clientcredentials.Config{
+		ClientID:     outlookClientID,
+		ClientSecret: secret,
+		TokenURL:     "https://login.microsoftonline.com/" + outlookTenantID + "/oauth2/v2.0/token",
+		Scopes:       []string{"https://graph.microsoft.com/.default"},
+	}
+	ctx := context.Background()
+
+	if recordFilepath == "" {
+		return config.Client(ctx), nil, nil
+	}
+
+	r, err := httpreplay.NewRecorder(recordFilepath, []byte{})
+	if err != nil {
+		return nil, nil, fmt.Errorf("error intializing recorder: %v", err)
+	}
+
+	client, err := r.Client(ctx, option.WithTokenSource(config.TokenSource(ctx)))
+	if err != nil {
+		return nil, nil, fmt.Errorf("error creating recorder client: %v", err)
+	}
+	return client, r, nil
+}
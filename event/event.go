@@ -0,0 +1,189 @@
+// Package event models a schedule's shifts as calendar events, independent of any calendar service, and keeps calendar
+// Backends in sync with a schedule.
+package event
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/spinnaker/rotation-scheduler/render"
+	"github.com/spinnaker/rotation-scheduler/schedule"
+)
+
+const (
+	DateFormat     = "2006-01-02"
+	DateTimeFormat = "2006-01-02 15:04 MST"
+)
+
+// Backend is a calendar that holds a rotation's events. Implementations are responsible for tagging the events they
+// insert as belonging to the rotation, and must never list, update or delete events without that tag.
+type Backend interface {
+	// List returns all of the rotation's events.
+	List() ([]*Event, error)
+
+	// Insert creates a new event.
+	Insert(e *Event) error
+
+	// Update replaces the contents of existing, which was returned by List, with e.
+	Update(existing, e *Event) error
+
+	// Delete removes e, which was returned by List.
+	Delete(e *Event) error
+}
+
+// Event is a calendar event for a single shift. Shifts of schedules without a handoff time are all-day events, unless
+// they're timed shifts, like those of a schedule that follows the sun.
+type Event struct {
+	// ID is assigned by the Backend, and is empty for events that haven't been inserted yet.
+	ID string
+
+	// Key is the schedule.Shift.Key() the event is for. Listed events without a key can't be matched to a shift.
+	Key string
+
+	Summary     string
+	Description string
+	Location    string
+
+	// Start is inclusive, and End is exclusive, as most calendar APIs expect. Only the dates of all-day events are
+	// relevant.
+	Start time.Time
+	End   time.Time
+
+	// TimeZone is the IANA time zone of a timed event, and is empty for all-day events.
+	TimeZone string
+
+	// Attendees are the email addresses invited to the event.
+	Attendees []string
+}
+
+// Events creates an Event for every shift in sched, in the same order as the shifts.
+func Events(sched *schedule.Schedule, rotation string, renderer *render.Renderer) ([]*Event, error) {
+	handoff, err := sched.Handoff()
+	if err != nil {
+		return nil, err
+	}
+
+	events := make([]*Event, len(sched.Shifts))
+	for i, shift := range sched.Shifts {
+		rendered, err := renderer.Render(render.ShiftData(sched, i, rotation))
+		if err != nil {
+			return nil, fmt.Errorf("error rendering event for shift at index %v: %v", i, err)
+		}
+
+		_, stopDateExcl := sched.ShiftStopDates(i)
+		events[i] = &Event{
+			Key:         shift.Key(),
+			Summary:     rendered.Summary,
+			Description: rendered.Description,
+			Location:    rendered.Location,
+			Start:       shift.StartDate,
+			End:         stopDateExcl,
+			Attendees:   rendered.Attendees,
+		}
+		switch {
+		case shift.Timed():
+			events[i].TimeZone = shift.TimeZone
+		case handoff.Timed:
+			events[i].Start = handoff.At(shift.StartDate)
+			events[i].End = handoff.At(stopDateExcl)
+			events[i].TimeZone = handoff.Location.String()
+		}
+	}
+	return events, nil
+}
+
+// IsAllDay is true for events without a time zone.
+func (e *Event) IsAllDay() bool {
+	return e.TimeZone == ""
+}
+
+// StopDate returns the inclusive date the event stops.
+func (e *Event) StopDate() time.Time {
+	return e.End.AddDate(0, 0, -1)
+}
+
+// TimeLocation loads the event's TimeZone. All-day events are in UTC.
+func (e *Event) TimeLocation() (*time.Location, error) {
+	if e.IsAllDay() {
+		return time.UTC, nil
+	}
+	return time.LoadLocation(e.TimeZone)
+}
+
+// Matches compares everything except the ID, which is assigned by the Backend.
+func (e *Event) Matches(other *Event) bool {
+	if e.Key != other.Key || e.Summary != other.Summary || e.Description != other.Description ||
+		e.Location != other.Location || e.TimeZone != other.TimeZone {
+		return false
+	}
+
+	if !e.Start.Equal(other.Start) || !e.End.Equal(other.End) {
+		return false
+	}
+
+	if len(e.Attendees) != len(other.Attendees) {
+		return false
+	}
+	emails := make(map[string]bool, len(e.Attendees))
+	for _, a := range e.Attendees {
+		emails[strings.ToLower(a)] = true
+	}
+	for _, a := range other.Attendees {
+		if !emails[strings.ToLower(a)] {
+			return false
+		}
+	}
+	return true
+}
+
+// MarshalJSON writes the start and inclusive stop dates of all-day events in the `DateFormat` format. Timed events have
+// RFC 3339 start and (exclusive) stop times in their time zone instead.
+func (e *Event) MarshalJSON() ([]byte, error) {
+	start, stop := e.formatTimes(DateFormat, time.RFC3339)
+	return json.Marshal(&struct {
+		ID          string   `json:"id,omitempty"`
+		Key         string   `json:"shiftKey,omitempty"`
+		Summary     string   `json:"summary"`
+		Description string   `json:"description,omitempty"`
+		Location    string   `json:"location,omitempty"`
+		Start       string   `json:"start"`
+		Stop        string   `json:"stop"`
+		TimeZone    string   `json:"timeZone,omitempty"`
+		Attendees   []string `json:"attendees,omitempty"`
+	}{
+		ID:          e.ID,
+		Key:         e.Key,
+		Summary:     e.Summary,
+		Description: e.Description,
+		Location:    e.Location,
+		Start:       start,
+		Stop:        stop,
+		TimeZone:    e.TimeZone,
+		Attendees:   e.Attendees,
+	})
+}
+
+// formatTimes formats the start and inclusive stop dates of all-day events with dateFormat, and the start and end of
+// timed events, in their own time zone, with timeFormat.
+func (e *Event) formatTimes(dateFormat, timeFormat string) (start, stop string) {
+	if e.IsAllDay() {
+		return e.Start.Format(dateFormat), e.StopDate().Format(dateFormat)
+	}
+
+	start, stop = e.Start.Format(timeFormat), e.End.Format(timeFormat)
+	if loc, err := e.TimeLocation(); err == nil {
+		start, stop = e.Start.In(loc).Format(timeFormat), e.End.In(loc).Format(timeFormat)
+	}
+	return start, stop
+}
+
+func (e *Event) String() string {
+	start, stop := e.formatTimes(DateFormat, DateTimeFormat)
+	s := fmt.Sprintf("%q from %v to %v", e.Summary, start, stop)
+	if len(e.Attendees) > 0 {
+		s += fmt.Sprintf(", inviting %v", strings.Join(e.Attendees, ", "))
+	}
+	return s
+}
@@ -0,0 +1,264 @@
+package event
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/spinnaker/rotation-scheduler/render"
+	"github.com/spinnaker/rotation-scheduler/schedule"
+)
+
+func TestEvents(t *testing.T) {
+	for _, tc := range []struct {
+		desc     string
+		schedule *schedule.Schedule
+		want     []*Event
+	}{
+		{
+			desc: "single day separation",
+			schedule: &schedule.Schedule{
+				Shifts: []*schedule.Shift{
+					{
+						StartDate: time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC),
+						User:      "first",
+					},
+					{
+						StartDate:    time.Date(2020, 1, 2, 0, 0, 0, 0, time.UTC),
+						StopDate:     time.Date(2020, 1, 2, 0, 0, 0, 0, time.UTC),
+						User:         "second",
+						UserOverride: "third@example.com",
+					},
+				},
+			},
+			want: []*Event{
+				{
+					Key:     "20200101",
+					Summary: "first build-cop",
+					Start:   time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC),
+					End:     time.Date(2020, 1, 2, 0, 0, 0, 0, time.UTC),
+				},
+				{
+					Key:       "20200102",
+					Summary:   "third@example.com build-cop",
+					Start:     time.Date(2020, 1, 2, 0, 0, 0, 0, time.UTC),
+					End:       time.Date(2020, 1, 3, 0, 0, 0, 0, time.UTC),
+					Attendees: []string{"third@example.com"},
+				},
+			},
+		},
+		{
+			desc: "multi day separation",
+			schedule: &schedule.Schedule{
+				Shifts: []*schedule.Shift{
+					{
+						StartDate: time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC),
+						User:      "first",
+					},
+					{
+						StartDate: time.Date(2020, 1, 10, 0, 0, 0, 0, time.UTC),
+						StopDate:  time.Date(2020, 1, 19, 0, 0, 0, 0, time.UTC),
+						User:      "second",
+					},
+				},
+			},
+			want: []*Event{
+				{
+					Key:     "20200101",
+					Summary: "first build-cop",
+					Start:   time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC),
+					End:     time.Date(2020, 1, 10, 0, 0, 0, 0, time.UTC),
+				},
+				{
+					Key:     "20200110",
+					Summary: "second build-cop",
+					Start:   time.Date(2020, 1, 10, 0, 0, 0, 0, time.UTC),
+					End:     time.Date(2020, 1, 20, 0, 0, 0, 0, time.UTC),
+				},
+			},
+		},
+	} {
+		t.Run(tc.desc, func(t *testing.T) {
+			got, err := Events(tc.schedule, "build-cop", render.Default())
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			if !reflect.DeepEqual(tc.want, got) {
+				t.Errorf("events do not match: want\n%v\n\ngot\n%v", tc.want, got)
+			}
+		})
+	}
+}
+
+func TestEventsTimed(t *testing.T) {
+	sched := &schedule.Schedule{
+		HandoffTime: "10:00",
+		TimeZone:    "America/Los_Angeles",
+		Shifts: []*schedule.Shift{
+			{
+				StartDate: time.Date(2020, 10, 26, 0, 0, 0, 0, time.UTC),
+				StopDate:  time.Date(2020, 11, 1, 0, 0, 0, 0, time.UTC),
+				User:      "first",
+			},
+		},
+	}
+
+	got, err := Events(sched, "build-cop", render.Default())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	// Daylight saving time ends during the shift.
+	e := got[0]
+	if want := time.Date(2020, 10, 26, 17, 0, 0, 0, time.UTC); !want.Equal(e.Start) {
+		t.Errorf("start: want %v, got %v", want, e.Start)
+	}
+	if want := time.Date(2020, 11, 2, 18, 0, 0, 0, time.UTC); !want.Equal(e.End) {
+		t.Errorf("end: want %v, got %v", want, e.End)
+	}
+	if e.IsAllDay() || e.TimeZone != "America/Los_Angeles" {
+		t.Errorf("want timed event in America/Los_Angeles, got %q", e.TimeZone)
+	}
+
+	want := `"first build-cop" from 2020-10-26 10:00 PDT to 2020-11-02 10:00 PST`
+	if got := e.String(); want != got {
+		t.Errorf("want %v, got %v", want, got)
+	}
+}
+
+func TestEventsFollowTheSun(t *testing.T) {
+	sched := &schedule.Schedule{
+		Regions: []*schedule.Region{
+			{Name: "apac", HandoffTime: "09:00", TimeZone: "Asia/Singapore"},
+			{Name: "emea", HandoffTime: "09:00", TimeZone: "Europe/London"},
+		},
+		Shifts: []*schedule.Shift{
+			{
+				StartDate: time.Date(2020, 6, 1, 1, 0, 0, 0, time.UTC),
+				User:      "first",
+				Type:      "apac",
+				TimeZone:  "Asia/Singapore",
+			},
+			{
+				StartDate: time.Date(2020, 6, 1, 8, 0, 0, 0, time.UTC),
+				StopDate:  time.Date(2020, 6, 2, 1, 0, 0, 0, time.UTC),
+				User:      "second",
+				Type:      "emea",
+				TimeZone:  "Europe/London",
+			},
+		},
+	}
+
+	got, err := Events(sched, "build-cop", render.Default())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	want := []string{
+		`"first build-cop apac" from 2020-06-01 09:00 +08 to 2020-06-01 16:00 +08`,
+		`"second build-cop emea" from 2020-06-01 09:00 BST to 2020-06-02 02:00 BST`,
+	}
+	for i, e := range got {
+		if e.IsAllDay() || e.TimeZone != sched.Shifts[i].TimeZone {
+			t.Errorf("want timed event in %v, got %q", sched.Shifts[i].TimeZone, e.TimeZone)
+		}
+		if e.String() != want[i] {
+			t.Errorf("want %v, got %v", want[i], e.String())
+		}
+	}
+}
+
+func TestMatches(t *testing.T) {
+	base := func() *Event {
+		return &Event{
+			Key:       "20200101",
+			Summary:   "first build-cop",
+			Start:     time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC),
+			End:       time.Date(2020, 1, 8, 0, 0, 0, 0, time.UTC),
+			Attendees: []string{"first@example.com"},
+		}
+	}
+
+	for _, tc := range []struct {
+		desc   string
+		modify func(e *Event)
+		want   bool
+	}{
+		{
+			desc:   "identical",
+			modify: func(e *Event) {},
+			want:   true,
+		},
+		{
+			desc: "ID and attendee case are ignored",
+			modify: func(e *Event) {
+				e.ID = "foo"
+				e.Attendees[0] = "First@Example.com"
+			},
+			want: true,
+		},
+		{
+			desc:   "different summary",
+			modify: func(e *Event) { e.Summary = "foo" },
+		},
+		{
+			desc:   "different description",
+			modify: func(e *Event) { e.Description = "foo" },
+		},
+		{
+			desc:   "different location",
+			modify: func(e *Event) { e.Location = "foo" },
+		},
+		{
+			desc:   "different start",
+			modify: func(e *Event) { e.Start = e.Start.AddDate(0, 0, 1) },
+		},
+		{
+			desc:   "different end",
+			modify: func(e *Event) { e.End = e.End.AddDate(0, 0, 1) },
+		},
+		{
+			desc:   "different time zone",
+			modify: func(e *Event) { e.TimeZone = "UTC" },
+		},
+		{
+			desc:   "attendee removed",
+			modify: func(e *Event) { e.Attendees = nil },
+		},
+		{
+			desc:   "attendee changed",
+			modify: func(e *Event) { e.Attendees[0] = "second@example.com" },
+		},
+	} {
+		t.Run(tc.desc, func(t *testing.T) {
+			e := base()
+			tc.modify(e)
+			if got := base().Matches(e); tc.want != got {
+				t.Errorf("want %v, got %v", tc.want, got)
+			}
+		})
+	}
+}
+
+func TestMarshalEvent(t *testing.T) {
+	e := &Event{
+		Key:       "20200101",
+		Summary:   "abc build-cop",
+		Start:     time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC),
+		End:       time.Date(2020, 1, 8, 0, 0, 0, 0, time.UTC),
+		Attendees: []string{"abc@example.com"},
+	}
+
+	got, err := json.Marshal(e)
+	if err != nil {
+		t.Fatalf("error marshalling event: %v", err)
+	}
+
+	want := `{"shiftKey":"20200101","summary":"abc build-cop","start":"2020-01-01","stop":"2020-01-07",` +
+		`"attendees":["abc@example.com"]}`
+	if want != string(got) {
+		t.Errorf("want:\n%v\n\ngot:\n%v", want, string(got))
+	}
+}
@@ -0,0 +1,148 @@
+package event
+
+import (
+	"fmt"
+	"log"
+	"strings"
+
+	"github.com/spinnaker/rotation-scheduler/render"
+	"github.com/spinnaker/rotation-scheduler/schedule"
+)
+
+// Plan is the set of changes needed to bring a Backend in line with a schedule.
+type Plan struct {
+	Inserts []*Event  `json:"inserts"`
+	Updates []*Update `json:"updates"`
+	Deletes []*Event  `json:"deletes"`
+}
+
+// Update replaces an existing event, Before, with After.
+type Update struct {
+	Before *Event `json:"before"`
+	After  *Event `json:"after"`
+}
+
+// NewPlan lists the rotation's events on b, and compares them to the events for each shift in sched. Events are only
+// inserted, updated or deleted when their shift has changed, so attendees aren't re-invited to shifts they already
+// know about.
+func NewPlan(b Backend, sched *schedule.Schedule, rotation string, renderer *render.Renderer) (*Plan, error) {
+	if err := sched.Validate(); err != nil {
+		return nil, fmt.Errorf("schedule is invalid: %v", err)
+	}
+
+	existing, err := b.List()
+	if err != nil {
+		return nil, fmt.Errorf("error listing existing events: %v", err)
+	}
+
+	desired, err := Events(sched, rotation, renderer)
+	if err != nil {
+		return nil, err
+	}
+
+	return reconcile(existing, desired), nil
+}
+
+// Sync plans and applies the changes needed to bring b in line with sched.
+func Sync(b Backend, sched *schedule.Schedule, rotation string, renderer *render.Renderer) error {
+	p, err := NewPlan(b, sched, rotation, renderer)
+	if err != nil {
+		return err
+	}
+	return p.Apply(b)
+}
+
+// reconcile matches existing events to desired events by their shift key. Existing events without a matching shift,
+// including any duplicates, are deleted.
+func reconcile(existing, desired []*Event) *Plan {
+	p := &Plan{
+		Inserts: []*Event{},
+		Updates: []*Update{},
+		Deletes: []*Event{},
+	}
+
+	byKey := make(map[string]*Event, len(existing))
+	for _, e := range existing {
+		if _, dup := byKey[e.Key]; e.Key == "" || dup {
+			p.Deletes = append(p.Deletes, e)
+			continue
+		}
+		byKey[e.Key] = e
+	}
+
+	for _, d := range desired {
+		e, ok := byKey[d.Key]
+		if !ok {
+			p.Inserts = append(p.Inserts, d)
+			continue
+		}
+		delete(byKey, d.Key)
+
+		if !e.Matches(d) {
+			after := *d
+			after.ID = e.ID
+			p.Updates = append(p.Updates, &Update{Before: e, After: &after})
+		}
+	}
+
+	// Preserve the order of the existing events, rather than ranging over the map.
+	for _, e := range existing {
+		if byKey[e.Key] == e {
+			p.Deletes = append(p.Deletes, e)
+		}
+	}
+
+	return p
+}
+
+// Apply makes the changes in the plan. Deletes are made first, then updates, then inserts.
+func (p *Plan) Apply(b Backend) error {
+	for _, e := range p.Deletes {
+		if err := b.Delete(e); err != nil {
+			return fmt.Errorf("delete error with event %v: %v", e.ID, err)
+		}
+		log.Printf("Removed event %v", e)
+	}
+
+	for _, u := range p.Updates {
+		if err := b.Update(u.Before, u.After); err != nil {
+			return fmt.Errorf("update error with event %v: %v\nEvent value:\n%+v", u.Before.ID, err, u.After)
+		}
+		log.Printf("Updated event %v", u.After)
+	}
+
+	for i, e := range p.Inserts {
+		if err := b.Insert(e); err != nil {
+			return fmt.Errorf("insert error with event at index %v: %v\nEvent value:\n%+v", i, err, e)
+		}
+		log.Printf("Added event %v", e)
+	}
+
+	return nil
+}
+
+// IsEmpty is true when the Backend is already in sync.
+func (p *Plan) IsEmpty() bool {
+	return len(p.Inserts) == 0 && len(p.Updates) == 0 && len(p.Deletes) == 0
+}
+
+// String describes the plan for humans, one change per line.
+func (p *Plan) String() string {
+	if p.IsEmpty() {
+		return "No changes. The calendar is in sync with the schedule.\n"
+	}
+
+	sb := &strings.Builder{}
+	for _, e := range p.Deletes {
+		fmt.Fprintf(sb, "- delete %v\n", e)
+	}
+	for _, u := range p.Updates {
+		fmt.Fprintf(sb, "~ update %v\n", u.Before)
+		fmt.Fprintf(sb, "      to %v\n", u.After)
+	}
+	for _, e := range p.Inserts {
+		fmt.Fprintf(sb, "+ insert %v\n", e)
+	}
+	fmt.Fprintf(sb, "%v to insert, %v to update, %v to delete.\n", len(p.Inserts), len(p.Updates), len(p.Deletes))
+	return sb.String()
+}
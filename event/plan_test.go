@@ -0,0 +1,168 @@
+package event
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/spinnaker/rotation-scheduler/render"
+	"github.com/spinnaker/rotation-scheduler/schedule"
+)
+
+// fakeBackend keeps events in memory, in insertion order.
+type fakeBackend struct {
+	events []*Event
+	nextID int
+}
+
+func (f *fakeBackend) List() ([]*Event, error) {
+	return f.events, nil
+}
+
+func (f *fakeBackend) Insert(e *Event) error {
+	f.nextID++
+	inserted := *e
+	inserted.ID = fmt.Sprintf("id%v", f.nextID)
+	f.events = append(f.events, &inserted)
+	return nil
+}
+
+func (f *fakeBackend) Update(existing, e *Event) error {
+	for i, fe := range f.events {
+		if fe.ID == existing.ID {
+			updated := *e
+			updated.ID = existing.ID
+			f.events[i] = &updated
+			return nil
+		}
+	}
+	return fmt.Errorf("event %v not found", existing.ID)
+}
+
+func (f *fakeBackend) Delete(e *Event) error {
+	for i, fe := range f.events {
+		if fe.ID == e.ID {
+			f.events = append(f.events[:i], f.events[i+1:]...)
+			return nil
+		}
+	}
+	return fmt.Errorf("event %v not found", e.ID)
+}
+
+func testSchedule() *schedule.Schedule {
+	return &schedule.Schedule{
+		Shifts: []*schedule.Shift{
+			{
+				StartDate: time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC),
+				User:      "first",
+			},
+			{
+				StartDate:    time.Date(2020, 1, 8, 0, 0, 0, 0, time.UTC),
+				User:         "second",
+				UserOverride: "third@example.com",
+			},
+			{
+				StartDate: time.Date(2020, 1, 15, 0, 0, 0, 0, time.UTC),
+				StopDate:  time.Date(2020, 1, 21, 0, 0, 0, 0, time.UTC),
+				User:      "first",
+			},
+		},
+	}
+}
+
+func TestReconcile(t *testing.T) {
+	desired, err := Events(testSchedule(), "build-cop", render.Default())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	unchanged := &Event{
+		ID:      "unchanged",
+		Key:     "20200101",
+		Summary: "first build-cop",
+		Start:   time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC),
+		End:     time.Date(2020, 1, 8, 0, 0, 0, 0, time.UTC),
+	}
+	changedUser := &Event{
+		ID:      "changedUser",
+		Key:     "20200108",
+		Summary: "second build-cop",
+		Start:   time.Date(2020, 1, 8, 0, 0, 0, 0, time.UTC),
+		End:     time.Date(2020, 1, 15, 0, 0, 0, 0, time.UTC),
+	}
+	duplicate := &Event{
+		ID:      "duplicate",
+		Key:     "20200101",
+		Summary: "first build-cop",
+		Start:   time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC),
+		End:     time.Date(2020, 1, 8, 0, 0, 0, 0, time.UTC),
+	}
+	stale := &Event{
+		ID:      "stale",
+		Key:     "20191225",
+		Summary: "first build-cop",
+		Start:   time.Date(2019, 12, 25, 0, 0, 0, 0, time.UTC),
+		End:     time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC),
+	}
+	missingKey := &Event{
+		ID:      "missingKey",
+		Summary: "first build-cop",
+		Start:   time.Date(2020, 1, 2, 0, 0, 0, 0, time.UTC),
+		End:     time.Date(2020, 1, 3, 0, 0, 0, 0, time.UTC),
+	}
+
+	got := reconcile([]*Event{stale, unchanged, changedUser, duplicate, missingKey}, desired)
+
+	updated := *desired[1]
+	updated.ID = "changedUser"
+	want := &Plan{
+		Inserts: []*Event{desired[2]},
+		Updates: []*Update{{Before: changedUser, After: &updated}},
+		Deletes: []*Event{duplicate, missingKey, stale},
+	}
+	if !reflect.DeepEqual(want, got) {
+		t.Errorf("plans do not match.\nwant:\n%v\ngot:\n%v", want, got)
+	}
+
+	wantString := `- delete "first build-cop" from 2020-01-01 to 2020-01-07
+- delete "first build-cop" from 2020-01-02 to 2020-01-02
+- delete "first build-cop" from 2019-12-25 to 2019-12-31
+~ update "second build-cop" from 2020-01-08 to 2020-01-14
+      to "third@example.com build-cop" from 2020-01-08 to 2020-01-14, inviting third@example.com
++ insert "first build-cop" from 2020-01-15 to 2020-01-21
+1 to insert, 1 to update, 3 to delete.
+`
+	if gotString := got.String(); wantString != gotString {
+		t.Errorf("want:\n%v\n\ngot:\n%v", wantString, gotString)
+	}
+}
+
+func TestSync(t *testing.T) {
+	b := &fakeBackend{}
+	if err := Sync(b, testSchedule(), "build-cop", render.Default()); err != nil {
+		t.Fatalf("error syncing: %v", err)
+	}
+
+	if len(b.events) != 3 {
+		t.Errorf("want 3 events, got %v", len(b.events))
+	}
+
+	// Syncing the same schedule again should be a no-op.
+	p, err := NewPlan(b, testSchedule(), "build-cop", render.Default())
+	if err != nil {
+		t.Fatalf("error planning: %v", err)
+	}
+	if !p.IsEmpty() {
+		t.Errorf("want empty plan, got:\n%v", p)
+	}
+	if want, got := "No changes. The calendar is in sync with the schedule.\n", p.String(); want != got {
+		t.Errorf("want %q, got %q", want, got)
+	}
+}
+
+func TestNewPlanInvalidSchedule(t *testing.T) {
+	if _, err := NewPlan(&fakeBackend{}, &schedule.Schedule{}, "build-cop", render.Default()); err == nil {
+		t.Error("want error on invalid schedule and didn't get one.")
+	}
+}
@@ -4,12 +4,10 @@ package gcal
 import (
 	"context"
 	"fmt"
-	"log"
 	"net/http"
-	"strings"
 	"time"
 
-	"github.com/spinnaker/rotation-scheduler/schedule"
+	"github.com/spinnaker/rotation-scheduler/event"
 	"google.golang.org/api/calendar/v3"
 	"google.golang.org/api/option"
 )
@@ -19,21 +17,39 @@ const (
 	DateFormat = "2006-01-02"
 
 	defaultEndpoint = "https://www.googleapis.com/calendar/v3/"
+
+	// shiftKeyProperty is the private extended property that ties an event to its schedule.Shift.
+	shiftKeyProperty = "shiftKey"
+	// rotationProperty is the private extended property that marks an event as owned by a rotation. Events without it
+	// were not created by this package, and are never modified.
+	rotationProperty = "rotation"
+	maxListResults   = 2500
 )
 
-// GCal wraps the Google Calendar service.
+// GCal wraps the Google Calendar service, and is an event.Backend.
 type GCal struct {
 	CalendarID string
-	svc        *calendar.Service
+
+	// Rotation tags every event this GCal creates, and limits it to only modifying events with the same tag.
+	Rotation string
+
+	svc *calendar.Service
 }
 
-// NewGCal wraps the calendar specified using the client. CalendarID should be a user's primary calendar (not a shared,
-// calendar), because the Clear() method only works on primary calendars.
-func NewGCal(calendarID string, client *http.Client) (*GCal, error) {
+var _ event.Backend = &GCal{}
+
+// NewGCal wraps the calendar specified using the client. The calendar can be any calendar the client can edit,
+// including secondary and shared calendars. Only events tagged with the rotation name are ever updated or deleted, so
+// multiple rotations and human-created events can share a calendar.
+func NewGCal(calendarID, rotation string, client *http.Client) (*GCal, error) {
 	if calendarID == "" {
 		return nil, fmt.Errorf("calendar ID cannot be empty")
 	}
 
+	if rotation == "" {
+		return nil, fmt.Errorf("rotation cannot be empty")
+	}
+
 	svc, err := calendar.NewService(context.Background(),
 		option.WithHTTPClient(client),
 		option.WithUserAgent(UserAgent),
@@ -44,82 +60,118 @@ func NewGCal(calendarID string, client *http.Client) (*GCal, error) {
 
 	return &GCal{
 		CalendarID: calendarID,
+		Rotation:   rotation,
 		svc:        svc,
 	}, nil
 }
 
-// Clears all events on the calendar and replaces them with events from Schedule sched.
-func (g *GCal) Schedule(sched *schedule.Schedule) error {
-	if err := sched.Validate(); err != nil {
-		return fmt.Errorf("schedule is invalid: %v", err)
-	}
+// List only lists events owned by this rotation.
+func (g *GCal) List() ([]*event.Event, error) {
+	var events []*event.Event
+	err := g.svc.Events.List(g.CalendarID).
+		PrivateExtendedProperty(rotationProperty+"="+g.Rotation).
+		ShowDeleted(false).
+		MaxResults(maxListResults).
+		Pages(context.Background(), func(page *calendar.Events) error {
+			for _, ge := range page.Items {
+				e, err := fromGcalEvent(ge)
+				if err != nil {
+					return fmt.Errorf("error reading event %v: %v", ge.Id, err)
+				}
+				events = append(events, e)
+			}
+			return nil
+		})
+	return events, err
+}
 
-	internalEvents := internalEvents(sched)
+func (g *GCal) Insert(e *event.Event) error {
+	_, err := g.svc.Events.Insert(g.CalendarID, g.toGcalEvent(e)).SendUpdates("externalOnly").Do()
+	return err
+}
 
-	// Clear all events from the calendar
-	if err := g.svc.Calendars.Clear(g.CalendarID).Do(); err != nil {
-		return fmt.Errorf("error clearing calendar: %v", err)
+func (g *GCal) Update(existing, e *event.Event) error {
+	_, err := g.svc.Events.Update(g.CalendarID, existing.ID, g.toGcalEvent(e)).SendUpdates("externalOnly").Do()
+	return err
+}
+
+func (g *GCal) Delete(e *event.Event) error {
+	return g.svc.Events.Delete(g.CalendarID, e.ID).SendUpdates("externalOnly").Do()
+}
+
+func (g *GCal) toGcalEvent(e *event.Event) *calendar.Event {
+	ge := &calendar.Event{
+		Summary:     e.Summary,
+		Description: e.Description,
+		Location:    e.Location,
+		Start:       toEventDateTime(e, e.Start), // Start is inclusive.
+		End:         toEventDateTime(e, e.End),   // End is exclusive.
+		ExtendedProperties: &calendar.EventExtendedProperties{
+			Private: map[string]string{
+				shiftKeyProperty: e.Key,
+				rotationProperty: g.Rotation,
+			},
+		},
+	}
+	for _, a := range e.Attendees {
+		ge.Attendees = append(ge.Attendees, &calendar.EventAttendee{
+			Email: a,
+		})
 	}
+	return ge
+}
 
-	for i, ie := range internalEvents {
-		_, err := g.svc.Events.Insert(g.CalendarID, ie.GcalEvent).SendUpdates("externalOnly").Do()
-		if err != nil {
-			return fmt.Errorf("insert error with event at index %v: %v\nEvent value:\n%+v", i, err, ie.GcalEvent)
-		} else {
-			log.Printf("Added shift for %v from %v to %v", ie.User, ie.GcalEvent.Start.Date, ie.StopDateIncl.Format(DateFormat))
+func toEventDateTime(e *event.Event, t time.Time) *calendar.EventDateTime {
+	if e.IsAllDay() {
+		return &calendar.EventDateTime{
+			Date: t.Format(DateFormat),
 		}
 	}
 
-	return nil
+	if loc, err := e.TimeLocation(); err == nil {
+		t = t.In(loc)
+	}
+	return &calendar.EventDateTime{
+		DateTime: t.Format(time.RFC3339),
+		TimeZone: e.TimeZone,
+	}
 }
 
-type internalEvent struct {
-	GcalEvent    *calendar.Event
-	User         string
-	StopDateIncl time.Time
-}
+func fromGcalEvent(ge *calendar.Event) (*event.Event, error) {
+	e := &event.Event{
+		ID:          ge.Id,
+		Summary:     ge.Summary,
+		Description: ge.Description,
+		Location:    ge.Location,
+	}
 
-func internalEvents(sched *schedule.Schedule) []*internalEvent {
-	intEvents := make([]*internalEvent, len(sched.Shifts))
-	for i, shift := range sched.Shifts {
-		var stopDateIncl, stopDateExcl time.Time
-		if shift == sched.LastShift() {
-			stopDateIncl = shift.StopDate
-			stopDateExcl = shift.StopDateExclusive()
-		} else {
-			nextShift := sched.Shifts[i+1]
-			stopDateIncl = nextShift.StartDateExclusive()
-			stopDateExcl = nextShift.StartDate
-		}
+	if ge.ExtendedProperties != nil {
+		e.Key = ge.ExtendedProperties.Private[shiftKeyProperty]
+	}
 
-		u := shift.User
-		if shift.UserOverride != "" {
-			u = shift.UserOverride
-		}
-		event := &calendar.Event{
-			Summary: eventSummary(u),
-			Start: &calendar.EventDateTime{
-				Date: shift.StartDate.Format(DateFormat), // Start.Date is inclusive.
-			},
-			End: &calendar.EventDateTime{
-				Date: stopDateExcl.Format(DateFormat), // End.Date is exclusive
-			},
-		}
-		if strings.Contains(u, "@") {
-			event.Attendees = append(event.Attendees, &calendar.EventAttendee{
-				Email: u,
-			})
-		}
-		intEvents[i] = &internalEvent{
-			GcalEvent:    event,
-			User:         u,
-			StopDateIncl: stopDateIncl,
-		}
+	var err error
+	if e.Start, err = parseEventDateTime(ge.Start); err != nil {
+		return nil, fmt.Errorf("invalid start: %v", err)
+	}
+	if e.End, err = parseEventDateTime(ge.End); err != nil {
+		return nil, fmt.Errorf("invalid end: %v", err)
+	}
+	if ge.Start.Date == "" {
+		e.TimeZone = ge.Start.TimeZone
 	}
 
-	return intEvents
+	for _, a := range ge.Attendees {
+		e.Attendees = append(e.Attendees, a.Email)
+	}
+	return e, nil
 }
 
-func eventSummary(user string) string {
-	return fmt.Sprintf("%v Spinnaker OSS Build Cop", user)
+func parseEventDateTime(edt *calendar.EventDateTime) (time.Time, error) {
+	if edt == nil {
+		return time.Time{}, fmt.Errorf("missing date")
+	}
+	if edt.Date != "" {
+		return time.Parse(DateFormat, edt.Date)
+	}
+	return time.Parse(time.RFC3339, edt.DateTime)
 }
@@ -2,163 +2,344 @@ package gcal
 
 import (
 	"context"
+	"encoding/json"
+	"fmt"
 	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
 	"reflect"
+	"sort"
+	"strings"
+	"sync"
 	"testing"
 	"time"
 
 	"cloud.google.com/go/httpreplay"
 	"github.com/ghodss/yaml"
+	"github.com/spinnaker/rotation-scheduler/event"
+	"github.com/spinnaker/rotation-scheduler/render"
 	"github.com/spinnaker/rotation-scheduler/schedule"
 	"google.golang.org/api/calendar/v3"
+	"google.golang.org/api/option"
 )
 
 const (
 	testCalendarID = "spinbot@spinnaker.io"
+	testRotation   = "build-cop"
+	replayFile     = "testing/schedule.replay"
 )
 
-// schedule.replay file generated with:
+// fakeServer is an in-memory calendar that supports just enough of the Google Calendar API for GCal.
+type fakeServer struct {
+	mu     sync.Mutex
+	events map[string]*calendar.Event
+	nextID int
+}
+
+func (f *fakeServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	eventsPath := "/calendar/v3/calendars/" + testCalendarID + "/events"
+	id := strings.TrimPrefix(strings.TrimPrefix(r.URL.Path, eventsPath), "/")
+	if !strings.HasPrefix(r.URL.Path, eventsPath) {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+
+	switch {
+	case r.Method == http.MethodGet && id == "":
+		prop := strings.SplitN(r.URL.Query().Get("privateExtendedProperty"), "=", 2)
+		var ids []string
+		for id := range f.events {
+			ids = append(ids, id)
+		}
+		sort.Strings(ids)
+
+		page := &calendar.Events{}
+		for _, id := range ids {
+			e := f.events[id]
+			if len(prop) == 2 && (e.ExtendedProperties == nil || e.ExtendedProperties.Private[prop[0]] != prop[1]) {
+				continue
+			}
+			page.Items = append(page.Items, e)
+		}
+		json.NewEncoder(w).Encode(page)
+	case r.Method == http.MethodPost && id == "":
+		e := &calendar.Event{}
+		if err := json.NewDecoder(r.Body).Decode(e); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		f.nextID++
+		e.Id = fmt.Sprintf("inserted%v", f.nextID)
+		f.events[e.Id] = e
+		json.NewEncoder(w).Encode(e)
+	case r.Method == http.MethodPut && id != "":
+		if _, ok := f.events[id]; !ok {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		e := &calendar.Event{}
+		if err := json.NewDecoder(r.Body).Decode(e); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		e.Id = id
+		f.events[id] = e
+		json.NewEncoder(w).Encode(e)
+	case r.Method == http.MethodDelete && id != "":
+		if _, ok := f.events[id]; !ok {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		delete(f.events, id)
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		w.WriteHeader(http.StatusMethodNotAllowed)
+	}
+}
+
+func newTestGCal(t *testing.T, f *fakeServer) *GCal {
+	srv := httptest.NewServer(f)
+	t.Cleanup(srv.Close)
+
+	g, err := NewGCal(testCalendarID, testRotation, srv.Client())
+	if err != nil {
+		t.Fatalf("cannot create new gcal: %v", err)
+	}
+
+	g.svc, err = calendar.NewService(context.Background(),
+		option.WithHTTPClient(srv.Client()),
+		option.WithEndpoint(srv.URL+"/calendar/v3/"))
+	if err != nil {
+		t.Fatalf("cannot create calendar service: %v", err)
+	}
+	return g
+}
+
+func readTestSchedule(t *testing.T) *schedule.Schedule {
+	testSchedBytes, err := ioutil.ReadFile("testing/test_schedule.yaml")
+	if err != nil {
+		t.Fatalf("cannot read test schedule: %v", err)
+	}
+
+	testSched := &schedule.Schedule{}
+	if err := yaml.Unmarshal(testSchedBytes, testSched); err != nil {
+		t.Fatalf("cannot read schedule from yaml file: %v", err)
+	}
+	return testSched
+}
+
+// schedule.replay is recorded against a real calendar, whenever the requests GCal makes change, with:
 // JSON_KEY=$(cat rotation-scheduler.json | base64 -w 0)
-// go run rotation.go calendar sync --record ./gcal/testing/schedule.replay --jsonKey $JSON_KEY ./gcal/testing/test_schedule.yaml
+// go run rotation.go calendar sync --record ./gcal/testing/schedule.replay --jsonKey $JSON_KEY --name build-cop \
+// ./gcal/testing/test_schedule.yaml
 func TestSchedule(t *testing.T) {
-	replayer, err := httpreplay.NewReplayer("testing/schedule.replay")
+	if _, err := os.Stat(replayFile); os.IsNotExist(err) {
+		t.Skipf("%v hasn't been recorded", replayFile)
+	}
+
+	replayer, err := httpreplay.NewReplayer(replayFile)
 	if err != nil {
-		t.Fatalf("cannot initializer HTTP replayer: %v", err)
+		t.Fatalf("cannot initialize HTTP replayer: %v", err)
 	}
+	defer replayer.Close()
 
 	client, err := replayer.Client(context.Background())
 	if err != nil {
 		t.Fatalf("cannot initialize client from replayer: %v", err)
 	}
 
-	gcalUnderTest, err := NewGCal(testCalendarID, client)
+	gcalUnderTest, err := NewGCal(testCalendarID, testRotation, client)
 	if err != nil {
 		t.Fatalf("cannot create new gcal: %v", err)
 	}
 
-	testSchedBytes, err := ioutil.ReadFile("testing/test_schedule.yaml")
+	if err := event.Sync(gcalUnderTest, readTestSchedule(t), testRotation, render.Default()); err != nil {
+		t.Errorf("error during scheduling: %v", err)
+	}
+}
+
+// TestScheduleFakeServer starts with a calendar holding:
+// * an untagged event from Mon 25 May 2020, and a release-manager event for Mon 01 Jun 2020, which are not listed and
+// left alone.
+// * a build-cop event from Mon 25 May 2020, which is deleted.
+// * tagged events for the shifts starting Mon 01 Jun 2020 and Mon 22 Jun 2020, which are unchanged.
+// * a tagged event for the shift starting Mon 08 Jun 2020 with the wrong user, which is updated.
+// The shift starting Mon 15 Jun 2020 has no event, so one is inserted.
+func TestScheduleFakeServer(t *testing.T) {
+	testSched := readTestSchedule(t)
+
+	events, err := event.Events(testSched, testRotation, render.Default())
 	if err != nil {
-		t.Fatalf("cannot read test schedule: %v", err)
+		t.Fatalf("cannot create events: %v", err)
 	}
 
-	testSched := &schedule.Schedule{}
-	err = yaml.Unmarshal(testSchedBytes, testSched)
+	g := &GCal{Rotation: testRotation}
+	allDay := func(summary, key string, start time.Time) *calendar.Event {
+		return g.toGcalEvent(&event.Event{Summary: summary, Key: key, Start: start, End: start.AddDate(0, 0, 7)})
+	}
+
+	human := allDay("abc build-cop", "", time.Date(2020, 5, 25, 0, 0, 0, 0, time.UTC))
+	human.ExtendedProperties = nil
+	release := allDay("abc release-manager", "20200601", time.Date(2020, 6, 1, 0, 0, 0, 0, time.UTC))
+	release.ExtendedProperties.Private[rotationProperty] = "release-manager"
+	wrongUser := g.toGcalEvent(events[1])
+	wrongUser.Summary = "xyz build-cop"
+
+	f := &fakeServer{events: map[string]*calendar.Event{
+		"human":     human,
+		"release":   release,
+		"old0525":   allDay("abc build-cop", "20200525", time.Date(2020, 5, 25, 0, 0, 0, 0, time.UTC)),
+		"shift0601": g.toGcalEvent(events[0]),
+		"shift0608": wrongUser,
+		"shift0622": g.toGcalEvent(events[3]),
+	}}
+	for id, e := range f.events {
+		e.Id = id
+	}
+	gcalUnderTest := newTestGCal(t, f)
+
+	p, err := event.NewPlan(gcalUnderTest, testSched, testRotation, render.Default())
 	if err != nil {
-		t.Fatalf("cannot read schedule from yaml file: %v", err)
+		t.Fatalf("error planning: %v", err)
 	}
 
-	err = gcalUnderTest.Schedule(testSched)
+	wantPlan := `- delete "abc build-cop" from 2020-05-25 to 2020-05-31
+~ update "xyz build-cop" from 2020-06-08 to 2020-06-14
+      to "lmn build-cop" from 2020-06-08 to 2020-06-14
++ insert "abc build-cop" from 2020-06-15 to 2020-06-21
+1 to insert, 1 to update, 1 to delete.
+`
+	if got := p.String(); wantPlan != got {
+		t.Errorf("want plan:\n%v\n\ngot:\n%v", wantPlan, got)
+	}
+
+	err = p.Apply(gcalUnderTest)
 	if err != nil {
 		t.Errorf("error during scheduling: %v", err)
 	}
+
+	if f.events["human"] != human || f.events["release"] != release {
+		t.Errorf("want events of other rotations left alone, got %v", f.events)
+	}
+
+	p, err = event.NewPlan(gcalUnderTest, testSched, testRotation, render.Default())
+	if err != nil {
+		t.Fatalf("error planning again: %v", err)
+	}
+	if !p.IsEmpty() {
+		t.Errorf("want calendar in sync with the schedule, got plan:\n%v", p)
+	}
 }
 
-func TestInternalEvent(t *testing.T) {
+func TestGcalEvent(t *testing.T) {
+	g := &GCal{Rotation: testRotation}
+
 	for _, tc := range []struct {
-		desc     string
-		schedule *schedule.Schedule
-		want     []*internalEvent
+		desc  string
+		event *event.Event
+		want  *calendar.Event
 	}{
 		{
-			desc: "single day separation",
-			schedule: &schedule.Schedule{
-				Shifts: []*schedule.Shift{
-					{
-						StartDate: time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC),
-						User:      "first",
-					},
-					{
-						StartDate:    time.Date(2020, 1, 2, 0, 0, 0, 0, time.UTC),
-						StopDate:     time.Date(2020, 1, 2, 0, 0, 0, 0, time.UTC),
-						User:         "second",
-						UserOverride: "third",
-					},
-				},
+			desc: "minimal",
+			event: &event.Event{
+				Key:     "20200101",
+				Summary: "first build-cop",
+				Start:   time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC),
+				End:     time.Date(2020, 1, 2, 0, 0, 0, 0, time.UTC),
 			},
-			want: []*internalEvent{
-				{
-					GcalEvent: &calendar.Event{
-						Summary: eventSummary("first"),
-						Start: &calendar.EventDateTime{
-							Date: "2020-01-01",
-						},
-						End: &calendar.EventDateTime{
-							Date: "2020-01-02",
-						},
-					},
-					User:         "first",
-					StopDateIncl: time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC),
+			want: &calendar.Event{
+				Summary: "first build-cop",
+				Start: &calendar.EventDateTime{
+					Date: "2020-01-01",
 				},
-				{
-					GcalEvent: &calendar.Event{
-						Summary: eventSummary("third"),
-						Start: &calendar.EventDateTime{
-							Date: "2020-01-02",
-						},
-						End: &calendar.EventDateTime{
-							Date: "2020-01-03",
-						},
-					},
-					User:         "third",
-					StopDateIncl: time.Date(2020, 1, 2, 0, 0, 0, 0, time.UTC),
+				End: &calendar.EventDateTime{
+					Date: "2020-01-02",
 				},
+				ExtendedProperties: privateProperties("20200101"),
 			},
 		},
 		{
-			desc: "multi day separation",
-			schedule: &schedule.Schedule{
-				Shifts: []*schedule.Shift{
-					{
-						StartDate: time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC),
-						User:      "first",
-					},
-					{
-						StartDate: time.Date(2020, 1, 10, 0, 0, 0, 0, time.UTC),
-						StopDate:  time.Date(2020, 1, 19, 0, 0, 0, 0, time.UTC),
-						User:      "second",
-					},
+			desc: "all fields",
+			event: &event.Event{
+				Key:         "20200110",
+				Summary:     "second build-cop",
+				Description: "description",
+				Location:    "location",
+				Start:       time.Date(2020, 1, 10, 0, 0, 0, 0, time.UTC),
+				End:         time.Date(2020, 1, 20, 0, 0, 0, 0, time.UTC),
+				Attendees:   []string{"second@example.com"},
+			},
+			want: &calendar.Event{
+				Summary:     "second build-cop",
+				Description: "description",
+				Location:    "location",
+				Start: &calendar.EventDateTime{
+					Date: "2020-01-10",
+				},
+				End: &calendar.EventDateTime{
+					Date: "2020-01-20",
 				},
+				Attendees: []*calendar.EventAttendee{
+					{Email: "second@example.com"},
+				},
+				ExtendedProperties: privateProperties("20200110"),
 			},
-			want: []*internalEvent{
-				{
-					GcalEvent: &calendar.Event{
-						Summary: eventSummary("first"),
-						Start: &calendar.EventDateTime{
-							Date: "2020-01-01",
-						},
-						End: &calendar.EventDateTime{
-							Date: "2020-01-10",
-						},
-					},
-					User:         "first",
-					StopDateIncl: time.Date(2020, 1, 9, 0, 0, 0, 0, time.UTC),
+		},
+		{
+			desc: "timed",
+			event: &event.Event{
+				Key:      "20200601",
+				Summary:  "first build-cop",
+				Start:    time.Date(2020, 6, 1, 17, 0, 0, 0, time.UTC),
+				End:      time.Date(2020, 6, 8, 17, 0, 0, 0, time.UTC),
+				TimeZone: "America/Los_Angeles",
+			},
+			want: &calendar.Event{
+				Summary: "first build-cop",
+				Start: &calendar.EventDateTime{
+					DateTime: "2020-06-01T10:00:00-07:00",
+					TimeZone: "America/Los_Angeles",
 				},
-				{
-					GcalEvent: &calendar.Event{
-						Summary: eventSummary("second"),
-						Start: &calendar.EventDateTime{
-							Date: "2020-01-10",
-						},
-						End: &calendar.EventDateTime{
-							Date: "2020-01-20",
-						},
-					},
-					User:         "second",
-					StopDateIncl: time.Date(2020, 1, 19, 0, 0, 0, 0, time.UTC),
+				End: &calendar.EventDateTime{
+					DateTime: "2020-06-08T10:00:00-07:00",
+					TimeZone: "America/Los_Angeles",
 				},
+				ExtendedProperties: privateProperties("20200601"),
 			},
 		},
 	} {
 		t.Run(tc.desc, func(t *testing.T) {
-			got := internalEvents(tc.schedule)
-
-			if !reflect.DeepEqual(got, tc.want) {
-				toStr := func(intEvents []*internalEvent) string {
-					b, _ := yaml.Marshal(intEvents)
-					return string(b)
-				}
-				t.Errorf("internal events do not match: want\n%v\n\ngot\n%v", toStr(tc.want), toStr(got))
+			got := g.toGcalEvent(tc.event)
+			if !reflect.DeepEqual(tc.want, got) {
+				t.Errorf("gcal events do not match: want\n%+v\n\ngot\n%+v", tc.want, got)
+			}
+
+			roundTrip, err := fromGcalEvent(got)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if !tc.event.Matches(roundTrip) {
+				t.Errorf("round trip events do not match: want\n%+v\n\ngot\n%+v", tc.event, roundTrip)
 			}
 		})
 	}
 }
+
+func TestFromGcalEventInvalid(t *testing.T) {
+	if _, err := fromGcalEvent(&calendar.Event{}); err == nil {
+		t.Error("want error on missing dates and didn't get one.")
+	}
+}
+
+func privateProperties(key string) *calendar.EventExtendedProperties {
+	return &calendar.EventExtendedProperties{
+		Private: map[string]string{
+			shiftKeyProperty: key,
+			rotationProperty: testRotation,
+		},
+	}
+}
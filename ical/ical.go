@@ -0,0 +1,459 @@
+// Package ical exports schedules as RFC 5545 iCalendar (.ics) files.
+package ical
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/spinnaker/rotation-scheduler/event"
+	"github.com/spinnaker/rotation-scheduler/render"
+	"github.com/spinnaker/rotation-scheduler/schedule"
+)
+
+const (
+	ProdID          = "-//spinnaker//rotation-scheduler//EN"
+	DateFormat      = "20060102"
+	TimestampFormat = "20060102T150405Z"
+
+	// localTimeFormat is a DATE-TIME with a TZID, or a floating one.
+	localTimeFormat = "20060102T150405"
+
+	uidDomain = "rotation-scheduler.spinnaker.io"
+
+	// Non-standard properties that tag events with the rotation and shift they belong to, so they can be read back.
+	rotationProperty = "X-ROTATION-SCHEDULER-ROTATION"
+	shiftKeyProperty = "X-ROTATION-SCHEDULER-SHIFT-KEY"
+	// timeZoneProperty keeps the time zone of timed events, which are written in UTC.
+	timeZoneProperty = "X-ROTATION-SCHEDULER-TIME-ZONE"
+
+	// RFC 5545 section 3.1: lines should not be longer than 75 octets, excluding the line break.
+	maxLineOctets = 75
+)
+
+// Calendar converts schedules into iCalendar files.
+type Calendar struct {
+	// Name is the name of the rotation. It's used as the calendar's name and is available to the event templates.
+	Name string
+
+	// Stamp is used as the DTSTAMP of every event, and is usually the time the file is generated.
+	Stamp time.Time
+
+	// Renderer renders the text of each event. Defaults to render.Default().
+	Renderer *render.Renderer
+}
+
+// NewCalendar creates a Calendar. All args are required.
+func NewCalendar(name string, stamp time.Time) (*Calendar, error) {
+	if name == "" {
+		return nil, fmt.Errorf("calendar name cannot be empty")
+	}
+
+	if stamp.IsZero() {
+		return nil, fmt.Errorf("stamp cannot be zero value")
+	}
+
+	return &Calendar{
+		Name:     name,
+		Stamp:    stamp,
+		Renderer: render.Default(),
+	}, nil
+}
+
+// Encode writes every shift in sched to w as an event. Shifts are all-day events unless sched has a handoff time.
+func (c *Calendar) Encode(w io.Writer, sched *schedule.Schedule) error {
+	return c.encode(w, sched, func(string) bool { return true })
+}
+
+// EncodeByUser creates a separate calendar for each user with at least one shift in sched, in any role. The returned
+// map is keyed by user.
+func (c *Calendar) EncodeByUser(sched *schedule.Schedule) (map[string][]byte, error) {
+	if err := sched.Validate(); err != nil {
+		return nil, fmt.Errorf("schedule is invalid: %v", err)
+	}
+
+	cals := map[string][]byte{}
+	for _, shift := range sched.Shifts {
+		for _, user := range shift.Users() {
+			if _, ok := cals[user]; ok {
+				continue
+			}
+
+			buf := &bytes.Buffer{}
+			if err := c.encode(buf, sched, func(u string) bool { return u == user }); err != nil {
+				return nil, fmt.Errorf("error encoding calendar for %v: %v", user, err)
+			}
+			cals[user] = buf.Bytes()
+		}
+	}
+	return cals, nil
+}
+
+func (c *Calendar) encode(w io.Writer, sched *schedule.Schedule, include func(user string) bool) error {
+	if err := sched.Validate(); err != nil {
+		return fmt.Errorf("schedule is invalid: %v", err)
+	}
+
+	events, err := event.Events(sched, c.Name, c.Renderer)
+	if err != nil {
+		return err
+	}
+
+	var included []*event.Event
+	for i, shift := range sched.Shifts {
+		for _, user := range shift.Users() {
+			if include(user) {
+				included = append(included, events[i])
+				break
+			}
+		}
+	}
+	return c.EncodeEvents(w, included)
+}
+
+// EncodeEvents writes a calendar containing events to w.
+func (c *Calendar) EncodeEvents(w io.Writer, events []*event.Event) error {
+	lw := &lineWriter{w: w}
+	lw.writeLine("BEGIN:VCALENDAR")
+	lw.writeLine("VERSION:2.0")
+	lw.writeLine("PRODID:" + ProdID)
+	lw.writeLine("CALSCALE:GREGORIAN")
+	lw.writeLine("METHOD:PUBLISH")
+	lw.writeLine("X-WR-CALNAME:" + escapeText(c.Name))
+
+	for _, e := range events {
+		lw.writeLine("BEGIN:VEVENT")
+		lw.writeLine("UID:" + c.UID(e))
+		lw.writeLine("DTSTAMP:" + c.Stamp.UTC().Format(TimestampFormat))
+		if e.IsAllDay() {
+			lw.writeLine("DTSTART;VALUE=DATE:" + e.Start.Format(DateFormat)) // DTSTART is inclusive.
+			lw.writeLine("DTEND;VALUE=DATE:" + e.End.Format(DateFormat))     // DTEND is exclusive.
+		} else {
+			// UTC times don't need a VTIMEZONE component.
+			lw.writeLine("DTSTART:" + e.Start.UTC().Format(TimestampFormat))
+			lw.writeLine("DTEND:" + e.End.UTC().Format(TimestampFormat))
+		}
+		lw.writeLine("SUMMARY:" + escapeText(e.Summary))
+		if e.Description != "" {
+			lw.writeLine("DESCRIPTION:" + escapeText(e.Description))
+		}
+		if e.Location != "" {
+			lw.writeLine("LOCATION:" + escapeText(e.Location))
+		}
+		lw.writeLine("TRANSP:TRANSPARENT")
+		for _, a := range e.Attendees {
+			lw.writeLine("ATTENDEE;CN=" + quoteParam(a) + ":mailto:" + a)
+		}
+		lw.writeLine(rotationProperty + ":" + escapeText(c.Name))
+		lw.writeLine(shiftKeyProperty + ":" + escapeText(e.Key))
+		if !e.IsAllDay() {
+			lw.writeLine(timeZoneProperty + ":" + escapeText(e.TimeZone))
+		}
+		lw.writeLine("END:VEVENT")
+	}
+
+	lw.writeLine("END:VCALENDAR")
+	return lw.err
+}
+
+// UID is derived from the event's shift key rather than its user, so calendar clients update an existing event when a
+// shift changes hands instead of adding a duplicate.
+func (c *Calendar) UID(e *event.Event) string {
+	return fmt.Sprintf("%v-%v@%v", e.Key, slug(c.Name), uidDomain)
+}
+
+// DecodeHolidays reads every day of every event in an iCalendar file of holidays, like a public holiday calendar, that
+// isn't tagged with a rotation. Events spanning several days are a holiday on each day. Timed events, like a half day,
+// are a holiday on every date they overlap in their own time zone.
+func DecodeHolidays(r io.Reader) ([]*schedule.Holiday, error) {
+	events, err := Decode(r, "")
+	if err != nil {
+		return nil, err
+	}
+
+	var holidays []*schedule.Holiday
+	for _, e := range events {
+		if e.Start.IsZero() {
+			return nil, fmt.Errorf("holiday %q has no start date", e.Summary)
+		}
+
+		day := date(e.Start)
+		stop := e.End
+		if !e.IsAllDay() && !e.End.IsZero() {
+			// The date the event ends on is part of it, unless it ends at midnight.
+			stop = date(e.End)
+			if !e.End.Equal(time.Date(stop.Year(), stop.Month(), stop.Day(), 0, 0, 0, 0, e.End.Location())) {
+				stop = stop.AddDate(0, 0, 1)
+			}
+		}
+		for {
+			holidays = append(holidays, &schedule.Holiday{Date: day, Name: e.Summary})
+			day = day.AddDate(0, 0, 1)
+			if !day.Before(stop) {
+				break
+			}
+		}
+	}
+	return holidays, nil
+}
+
+// date returns the date of t, in its own location, at midnight UTC.
+func date(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+}
+
+// property is a content line of an event.
+type property struct {
+	line   int
+	name   string
+	params map[string]string
+	value  string
+}
+
+// Decode reads the events in an iCalendar file that are tagged with the rotation, as written by EncodeEvents. Other
+// events are ignored without reading their dates. Only the properties that EncodeEvents writes are read. Times with a
+// TZID are read in that time zone, and floating times, or times in a time zone that isn't known, are read in the
+// calendar's X-WR-TIMEZONE, or UTC.
+func Decode(r io.Reader, rotation string) ([]*event.Event, error) {
+	lines, err := unfold(r)
+	if err != nil {
+		return nil, err
+	}
+
+	var events []*event.Event
+	var current []*property
+	inEvent := false
+	calendarZone := time.UTC
+	for i, line := range lines {
+		name, params, value, err := parseLine(line)
+		if err != nil {
+			return nil, fmt.Errorf("error parsing line %v: %v", i+1, err)
+		}
+
+		switch {
+		case name == "BEGIN" && value == "VEVENT":
+			current, inEvent = nil, true
+		case name == "END" && value == "VEVENT":
+			if inEvent && tagged(current, rotation) {
+				e, err := decodeEvent(current, calendarZone)
+				if err != nil {
+					return nil, err
+				}
+				events = append(events, e)
+			}
+			inEvent = false
+		case inEvent:
+			current = append(current, &property{line: i + 1, name: name, params: params, value: value})
+		case name == "X-WR-TIMEZONE":
+			if loc, err := time.LoadLocation(value); err == nil {
+				calendarZone = loc
+			}
+		}
+	}
+	return events, nil
+}
+
+// tagged is true if the event with props is tagged with the rotation, or isn't tagged at all if rotation is empty.
+func tagged(props []*property, rotation string) bool {
+	for _, p := range props {
+		if p.name == rotationProperty {
+			return unescapeText(p.value) == rotation
+		}
+	}
+	return rotation == ""
+}
+
+// decodeEvent reads an event from its props. Floating times are in calendarZone.
+func decodeEvent(props []*property, calendarZone *time.Location) (*event.Event, error) {
+	e := &event.Event{}
+	timeZone := ""
+	for _, p := range props {
+		var err error
+		var loc *time.Location
+		switch p.name {
+		case "DTSTART":
+			e.Start, loc, err = parseDate(p.params, p.value, calendarZone)
+			if loc != nil {
+				timeZone = loc.String()
+			}
+		case "DTEND":
+			e.End, _, err = parseDate(p.params, p.value, calendarZone)
+		case "SUMMARY":
+			e.Summary = unescapeText(p.value)
+		case "DESCRIPTION":
+			e.Description = unescapeText(p.value)
+		case "LOCATION":
+			e.Location = unescapeText(p.value)
+		case "ATTENDEE":
+			if len(p.value) > len("mailto:") && strings.EqualFold(p.value[:len("mailto:")], "mailto:") {
+				e.Attendees = append(e.Attendees, p.value[len("mailto:"):])
+			}
+		case shiftKeyProperty:
+			e.Key = unescapeText(p.value)
+		case timeZoneProperty:
+			e.TimeZone = unescapeText(p.value)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("error parsing line %v: %v", p.line, err)
+		}
+	}
+	if e.TimeZone == "" {
+		e.TimeZone = timeZone
+	}
+	return e, nil
+}
+
+// unfold joins folded content lines back together.
+func unfold(r io.Reader) ([]string, error) {
+	var lines []string
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := strings.TrimSuffix(scanner.Text(), "\r")
+		if len(line) > 0 && (line[0] == ' ' || line[0] == '\t') && len(lines) > 0 {
+			lines[len(lines)-1] += line[1:]
+			continue
+		}
+		if line != "" {
+			lines = append(lines, line)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("error reading calendar: %v", err)
+	}
+	return lines, nil
+}
+
+// parseLine splits a content line into its name, parameters and value. Quoted parameter values may contain colons.
+func parseLine(line string) (name string, params map[string]string, value string, err error) {
+	inQuotes := false
+	colon := -1
+	for i, r := range line {
+		if r == '"' {
+			inQuotes = !inQuotes
+		} else if r == ':' && !inQuotes {
+			colon = i
+			break
+		}
+	}
+	if colon < 0 {
+		return "", nil, "", fmt.Errorf("missing ':' in %q", line)
+	}
+
+	parts := strings.Split(line[:colon], ";")
+	params = map[string]string{}
+	for _, p := range parts[1:] {
+		if kv := strings.SplitN(p, "=", 2); len(kv) == 2 {
+			params[strings.ToUpper(kv[0])] = strings.Trim(kv[1], `"`)
+		}
+	}
+	return strings.ToUpper(parts[0]), params, line[colon+1:], nil
+}
+
+// parseDate reads a DATE, or a DATE-TIME in UTC, in the time zone of its TZID, or floating in calendarZone. It also
+// returns the time zone of DATE-TIME values.
+func parseDate(params map[string]string, value string, calendarZone *time.Location) (time.Time, *time.Location, error) {
+	if params["VALUE"] == "DATE" || len(value) == len(DateFormat) {
+		t, err := time.Parse(DateFormat, value)
+		return t, nil, err
+	}
+
+	if strings.HasSuffix(value, "Z") {
+		t, err := time.Parse(TimestampFormat, value)
+		return t, time.UTC, err
+	}
+
+	loc := calendarZone
+	if tzid, ok := params["TZID"]; ok {
+		if l, err := time.LoadLocation(tzid); err == nil {
+			loc = l
+		}
+	}
+	t, err := time.ParseInLocation(localTimeFormat, value, loc)
+	return t, loc, err
+}
+
+func slug(s string) string {
+	return strings.Trim(strings.Map(func(r rune) rune {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			return unicode.ToLower(r)
+		}
+		return '-'
+	}, s), "-")
+}
+
+// escapeText escapes a TEXT value per RFC 5545 section 3.3.11.
+func escapeText(s string) string {
+	return strings.NewReplacer(
+		`\`, `\\`,
+		";", `\;`,
+		",", `\,`,
+		"\r\n", `\n`,
+		"\n", `\n`,
+	).Replace(s)
+}
+
+// unescapeText reverses escapeText.
+func unescapeText(s string) string {
+	var sb strings.Builder
+	escaped := false
+	for _, r := range s {
+		switch {
+		case escaped && (r == 'n' || r == 'N'):
+			sb.WriteRune('\n')
+		case escaped:
+			sb.WriteRune(r)
+		case r == '\\':
+			escaped = true
+			continue
+		default:
+			sb.WriteRune(r)
+		}
+		escaped = false
+	}
+	return sb.String()
+}
+
+// quoteParam quotes a parameter value if it contains characters that aren't allowed unquoted.
+func quoteParam(s string) string {
+	s = strings.ReplaceAll(s, `"`, "'")
+	if strings.ContainsAny(s, ":;,") {
+		return `"` + s + `"`
+	}
+	return s
+}
+
+// lineWriter writes CRLF terminated content lines, folding any longer than maxLineOctets. Errors are sticky, so only
+// the last one needs to be checked.
+type lineWriter struct {
+	w   io.Writer
+	err error
+}
+
+func (lw *lineWriter) writeLine(line string) {
+	if lw.err != nil {
+		return
+	}
+
+	var folded strings.Builder
+	limit := maxLineOctets
+	for len(line) > limit {
+		// Don't split a multi-byte character across lines.
+		cut := limit
+		for cut > 0 && !utf8.RuneStart(line[cut]) {
+			cut--
+		}
+		folded.WriteString(line[:cut])
+		folded.WriteString("\r\n ")
+		line = line[cut:]
+		limit = maxLineOctets - 1 // Account for the leading space of the continuation line.
+	}
+	folded.WriteString(line)
+	folded.WriteString("\r\n")
+
+	_, lw.err = io.WriteString(lw.w, folded.String())
+}
@@ -0,0 +1,489 @@
+package ical
+
+import (
+	"bytes"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/spinnaker/rotation-scheduler/event"
+	"github.com/spinnaker/rotation-scheduler/render"
+	"github.com/spinnaker/rotation-scheduler/schedule"
+)
+
+var (
+	testStamp = time.Date(2020, 5, 1, 12, 30, 0, 0, time.UTC)
+)
+
+func testSchedule() *schedule.Schedule {
+	return &schedule.Schedule{
+		Shifts: []*schedule.Shift{
+			{
+				StartDate: time.Date(2020, 6, 1, 0, 0, 0, 0, time.UTC),
+				User:      "abc",
+			},
+			{
+				StartDate:    time.Date(2020, 6, 8, 0, 0, 0, 0, time.UTC),
+				User:         "lmn",
+				UserOverride: "xyz@example.com",
+			},
+			{
+				StartDate: time.Date(2020, 6, 15, 0, 0, 0, 0, time.UTC),
+				StopDate:  time.Date(2020, 6, 21, 0, 0, 0, 0, time.UTC),
+				User:      "abc",
+			},
+		},
+	}
+}
+
+// crlf allows wants to be written with regular line breaks.
+func crlf(s string) string {
+	return strings.ReplaceAll(s, "\n", "\r\n")
+}
+
+func TestNewCalendar(t *testing.T) {
+	if _, err := NewCalendar("", testStamp); err == nil {
+		t.Error("want error on empty name and didn't get one.")
+	}
+
+	if _, err := NewCalendar("Build Cop", time.Time{}); err == nil {
+		t.Error("want error on zero stamp and didn't get one.")
+	}
+}
+
+func TestEncode(t *testing.T) {
+	cal, err := NewCalendar("Build Cop", testStamp)
+	if err != nil {
+		t.Fatalf("error creating calendar: %v", err)
+	}
+
+	buf := &bytes.Buffer{}
+	if err := cal.Encode(buf, testSchedule()); err != nil {
+		t.Fatalf("error encoding schedule: %v", err)
+	}
+
+	want := crlf(`BEGIN:VCALENDAR
+VERSION:2.0
+PRODID:-//spinnaker//rotation-scheduler//EN
+CALSCALE:GREGORIAN
+METHOD:PUBLISH
+X-WR-CALNAME:Build Cop
+BEGIN:VEVENT
+UID:20200601-build-cop@rotation-scheduler.spinnaker.io
+DTSTAMP:20200501T123000Z
+DTSTART;VALUE=DATE:20200601
+DTEND;VALUE=DATE:20200608
+SUMMARY:abc Build Cop
+TRANSP:TRANSPARENT
+X-ROTATION-SCHEDULER-ROTATION:Build Cop
+X-ROTATION-SCHEDULER-SHIFT-KEY:20200601
+END:VEVENT
+BEGIN:VEVENT
+UID:20200608-build-cop@rotation-scheduler.spinnaker.io
+DTSTAMP:20200501T123000Z
+DTSTART;VALUE=DATE:20200608
+DTEND;VALUE=DATE:20200615
+SUMMARY:xyz@example.com Build Cop
+TRANSP:TRANSPARENT
+ATTENDEE;CN=xyz@example.com:mailto:xyz@example.com
+X-ROTATION-SCHEDULER-ROTATION:Build Cop
+X-ROTATION-SCHEDULER-SHIFT-KEY:20200608
+END:VEVENT
+BEGIN:VEVENT
+UID:20200615-build-cop@rotation-scheduler.spinnaker.io
+DTSTAMP:20200501T123000Z
+DTSTART;VALUE=DATE:20200615
+DTEND;VALUE=DATE:20200622
+SUMMARY:abc Build Cop
+TRANSP:TRANSPARENT
+X-ROTATION-SCHEDULER-ROTATION:Build Cop
+X-ROTATION-SCHEDULER-SHIFT-KEY:20200615
+END:VEVENT
+END:VCALENDAR
+`)
+
+	if got := buf.String(); want != got {
+		t.Errorf("want:\n%v\n\ngot:\n%v", want, got)
+	}
+}
+
+func TestEncodeInvalidSchedule(t *testing.T) {
+	cal, err := NewCalendar("Build Cop", testStamp)
+	if err != nil {
+		t.Fatalf("error creating calendar: %v", err)
+	}
+
+	if err := cal.Encode(&bytes.Buffer{}, &schedule.Schedule{}); err == nil {
+		t.Error("want error on invalid schedule and didn't get one.")
+	}
+}
+
+func TestEncodeByUser(t *testing.T) {
+	cal, err := NewCalendar("Build Cop", testStamp)
+	if err != nil {
+		t.Fatalf("error creating calendar: %v", err)
+	}
+
+	got, err := cal.EncodeByUser(testSchedule())
+	if err != nil {
+		t.Fatalf("error encoding schedule: %v", err)
+	}
+
+	for user, wantEvents := range map[string]int{
+		"abc":             2,
+		"xyz@example.com": 1,
+	} {
+		ics, ok := got[user]
+		if !ok {
+			t.Errorf("missing calendar for %v", user)
+			continue
+		}
+		if gotEvents := strings.Count(string(ics), "BEGIN:VEVENT"); wantEvents != gotEvents {
+			t.Errorf("%v: want %v events, got %v", user, wantEvents, gotEvents)
+		}
+	}
+
+	if _, ok := got["lmn"]; ok {
+		t.Errorf("lmn's only shift was overridden and should not have a calendar")
+	}
+}
+
+func TestEncodeByUserRoles(t *testing.T) {
+	cal, err := NewCalendar("Build Cop", testStamp)
+	if err != nil {
+		t.Fatalf("error creating calendar: %v", err)
+	}
+
+	sched := testSchedule()
+	sched.Shifts[0].Roles = map[string]*schedule.Assignment{"secondary": {User: "xyz@example.com"}}
+
+	got, err := cal.EncodeByUser(sched)
+	if err != nil {
+		t.Fatalf("error encoding schedule: %v", err)
+	}
+
+	if gotEvents := strings.Count(string(got["xyz@example.com"]), "BEGIN:VEVENT"); gotEvents != 2 {
+		t.Errorf("want 2 events for xyz@example.com, one as secondary, got %v", gotEvents)
+	}
+}
+
+func TestWriteLine(t *testing.T) {
+	for _, tc := range []struct {
+		desc string
+		line string
+		want string
+	}{
+		{
+			desc: "short line",
+			line: "SUMMARY:abc",
+			want: "SUMMARY:abc\r\n",
+		},
+		{
+			desc: "exactly 75 octets",
+			line: strings.Repeat("a", 75),
+			want: strings.Repeat("a", 75) + "\r\n",
+		},
+		{
+			desc: "folded line",
+			line: strings.Repeat("a", 80),
+			want: strings.Repeat("a", 75) + "\r\n " + strings.Repeat("a", 5) + "\r\n",
+		},
+		{
+			desc: "multi-byte character not split",
+			line: strings.Repeat("a", 74) + "é",
+			want: strings.Repeat("a", 74) + "\r\n é\r\n",
+		},
+	} {
+		t.Run(tc.desc, func(t *testing.T) {
+			buf := &bytes.Buffer{}
+			lw := &lineWriter{w: buf}
+			lw.writeLine(tc.line)
+			if lw.err != nil {
+				t.Fatalf("unexpected error: %v", lw.err)
+			}
+
+			if got := buf.String(); tc.want != got {
+				t.Errorf("want %q, got %q", tc.want, got)
+			}
+		})
+	}
+}
+
+func TestEscapeText(t *testing.T) {
+	want := `a\, b\; c\\d\ne`
+	if got := escapeText("a, b; c\\d\ne"); want != got {
+		t.Errorf("want %v, got %v", want, got)
+	}
+}
+
+func TestEncodeTemplates(t *testing.T) {
+	cal, err := NewCalendar("Build Cop", testStamp)
+	if err != nil {
+		t.Fatalf("error creating calendar: %v", err)
+	}
+	cal.Renderer, err = render.NewRenderer(&render.Templates{
+		Summary:     "{{.Rotation}}: {{.User}}",
+		Description: "Scheduled: {{.ScheduledUser}}",
+		Location:    "#build-cop",
+		Attendees:   "oncall@example.com",
+	})
+	if err != nil {
+		t.Fatalf("error creating renderer: %v", err)
+	}
+
+	buf := &bytes.Buffer{}
+	if err := cal.Encode(buf, testSchedule()); err != nil {
+		t.Fatalf("error encoding schedule: %v", err)
+	}
+
+	for _, want := range []string{
+		"SUMMARY:Build Cop: xyz@example.com\r\n",
+		"DESCRIPTION:Scheduled: lmn\r\n",
+		"LOCATION:#build-cop\r\n",
+		"ATTENDEE;CN=oncall@example.com:mailto:oncall@example.com\r\n",
+	} {
+		if !strings.Contains(buf.String(), want) {
+			t.Errorf("missing line %q in:\n%v", want, buf.String())
+		}
+	}
+}
+
+func TestDecode(t *testing.T) {
+	cal, err := NewCalendar("Build Cop", testStamp)
+	if err != nil {
+		t.Fatalf("error creating calendar: %v", err)
+	}
+	cal.Renderer, err = render.NewRenderer(&render.Templates{
+		Summary:     "{{.User}}, on call",
+		Description: "Line one\nLine two; with a \\ backslash",
+		Location:    strings.Repeat("long location ", 10),
+	})
+	if err != nil {
+		t.Fatalf("error creating renderer: %v", err)
+	}
+
+	want, err := event.Events(testSchedule(), "Build Cop", cal.Renderer)
+	if err != nil {
+		t.Fatalf("error creating events: %v", err)
+	}
+
+	buf := &bytes.Buffer{}
+	if err := cal.EncodeEvents(buf, want); err != nil {
+		t.Fatalf("error encoding events: %v", err)
+	}
+
+	got, err := Decode(bytes.NewReader(buf.Bytes()), "Build Cop")
+	if err != nil {
+		t.Fatalf("error decoding calendar: %v", err)
+	}
+	if !reflect.DeepEqual(want, got) {
+		t.Errorf("events do not match: want\n%v\n\ngot\n%v", want, got)
+	}
+
+	got, err = Decode(bytes.NewReader(buf.Bytes()), "Release Manager")
+	if err != nil {
+		t.Fatalf("error decoding calendar: %v", err)
+	}
+	if len(got) != 0 {
+		t.Errorf("want no events for another rotation, got %v", got)
+	}
+}
+
+func TestDecodeHolidays(t *testing.T) {
+	ics := crlf(`BEGIN:VCALENDAR
+VERSION:2.0
+BEGIN:VEVENT
+UID:christmas@example.com
+DTSTART;VALUE=DATE:20201225
+DTEND;VALUE=DATE:20201226
+SUMMARY:Christmas Day
+END:VEVENT
+BEGIN:VEVENT
+UID:new-year@example.com
+DTSTART;VALUE=DATE:20201231
+DTEND;VALUE=DATE:20210102
+SUMMARY:New Year
+END:VEVENT
+BEGIN:VEVENT
+UID:no-end@example.com
+DTSTART;VALUE=DATE:20210118
+SUMMARY:Martin Luther King Jr. Day
+END:VEVENT
+BEGIN:VEVENT
+UID:christmas-eve@example.com
+DTSTART;TZID=America/New_York:20201224T120000
+DTEND;TZID=America/New_York:20201224T170000
+SUMMARY:Christmas Eve afternoon
+END:VEVENT
+BEGIN:VEVENT
+UID:overnight@example.com
+DTSTART:20210201T220000
+DTEND:20210202T020000
+SUMMARY:Overnight
+END:VEVENT
+BEGIN:VEVENT
+UID:20201228@build-cop.rotation-scheduler
+DTSTART;VALUE=DATE:20201228
+SUMMARY:abc Build Cop
+X-ROTATION-SCHEDULER-ROTATION:Build Cop
+END:VEVENT
+END:VCALENDAR
+`)
+
+	got, err := DecodeHolidays(strings.NewReader(ics))
+	if err != nil {
+		t.Fatalf("error decoding holidays: %v", err)
+	}
+
+	want := []*schedule.Holiday{
+		{Date: time.Date(2020, 12, 25, 0, 0, 0, 0, time.UTC), Name: "Christmas Day"},
+		{Date: time.Date(2020, 12, 31, 0, 0, 0, 0, time.UTC), Name: "New Year"},
+		{Date: time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC), Name: "New Year"},
+		{Date: time.Date(2021, 1, 18, 0, 0, 0, 0, time.UTC), Name: "Martin Luther King Jr. Day"},
+		{Date: time.Date(2020, 12, 24, 0, 0, 0, 0, time.UTC), Name: "Christmas Eve afternoon"},
+		{Date: time.Date(2021, 2, 1, 0, 0, 0, 0, time.UTC), Name: "Overnight"},
+		{Date: time.Date(2021, 2, 2, 0, 0, 0, 0, time.UTC), Name: "Overnight"},
+	}
+	if !reflect.DeepEqual(want, got) {
+		t.Errorf("want %+v, got %+v", want, got)
+	}
+}
+
+func TestDecodeForeignEvent(t *testing.T) {
+	ics := crlf(`BEGIN:VCALENDAR
+VERSION:2.0
+BEGIN:VEVENT
+UID:standup@example.com
+DTSTART:20200601T090000Z
+DTEND:20200601T091500Z
+SUMMARY:Standup
+END:VEVENT
+BEGIN:VEVENT
+UID:planning@example.com
+DTSTART;TZID=Not/A_Zone:not a time
+SUMMARY:Planning
+END:VEVENT
+END:VCALENDAR
+`)
+
+	got, err := Decode(strings.NewReader(ics), "Build Cop")
+	if err != nil {
+		t.Fatalf("error decoding calendar: %v", err)
+	}
+	if len(got) != 0 {
+		t.Errorf("want untagged events to be ignored, got %v", got)
+	}
+
+	if _, err := Decode(strings.NewReader("BEGIN:VCALENDAR\r\nnot a content line\r\n"), "Build Cop"); err == nil {
+		t.Error("want error on invalid content line and didn't get one.")
+	}
+}
+
+func TestEncodeTimed(t *testing.T) {
+	cal, err := NewCalendar("Build Cop", testStamp)
+	if err != nil {
+		t.Fatalf("error creating calendar: %v", err)
+	}
+
+	sched := testSchedule()
+	sched.HandoffTime = "10:00"
+	sched.TimeZone = "America/Los_Angeles"
+
+	buf := &bytes.Buffer{}
+	if err := cal.Encode(buf, sched); err != nil {
+		t.Fatalf("error encoding schedule: %v", err)
+	}
+
+	for _, want := range []string{
+		"DTSTART:20200601T170000Z\r\n",
+		"DTEND:20200608T170000Z\r\n",
+		"X-ROTATION-SCHEDULER-TIME-ZONE:America/Los_Angeles\r\n",
+	} {
+		if !strings.Contains(buf.String(), want) {
+			t.Errorf("missing line %q in:\n%v", want, buf.String())
+		}
+	}
+
+	want, err := event.Events(sched, "Build Cop", cal.Renderer)
+	if err != nil {
+		t.Fatalf("error creating events: %v", err)
+	}
+	got, err := Decode(bytes.NewReader(buf.Bytes()), "Build Cop")
+	if err != nil {
+		t.Fatalf("error decoding calendar: %v", err)
+	}
+	if len(want) != len(got) {
+		t.Fatalf("want %v events, got %v", len(want), len(got))
+	}
+	for i := range want {
+		if !want[i].Matches(got[i]) {
+			t.Errorf("event %v does not match: want\n%v\n\ngot\n%v", i, want[i], got[i])
+		}
+	}
+}
+
+func TestParseDate(t *testing.T) {
+	ny, err := time.LoadLocation("America/New_York")
+	if err != nil {
+		t.Fatalf("error loading time zone: %v", err)
+	}
+
+	for _, tc := range []struct {
+		desc     string
+		params   map[string]string
+		value    string
+		want     time.Time
+		wantZone string
+	}{
+		{
+			desc:   "date",
+			params: map[string]string{"VALUE": "DATE"},
+			value:  "20200302",
+			want:   time.Date(2020, 3, 2, 0, 0, 0, 0, time.UTC),
+		},
+		{
+			desc:     "UTC",
+			value:    "20200302T140000Z",
+			want:     time.Date(2020, 3, 2, 14, 0, 0, 0, time.UTC),
+			wantZone: "UTC",
+		},
+		{
+			desc:     "TZID",
+			params:   map[string]string{"TZID": "America/New_York"},
+			value:    "20200302T090000",
+			want:     time.Date(2020, 3, 2, 14, 0, 0, 0, time.UTC),
+			wantZone: "America/New_York",
+		},
+		{
+			desc:     "unknown TZID",
+			params:   map[string]string{"TZID": "Eastern Standard Time"},
+			value:    "20200302T090000",
+			want:     time.Date(2020, 3, 2, 14, 0, 0, 0, time.UTC),
+			wantZone: "America/New_York",
+		},
+		{
+			desc:     "floating",
+			value:    "20200302T090000",
+			want:     time.Date(2020, 3, 2, 14, 0, 0, 0, time.UTC),
+			wantZone: "America/New_York",
+		},
+	} {
+		t.Run(tc.desc, func(t *testing.T) {
+			got, loc, err := parseDate(tc.params, tc.value, ny)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if !tc.want.Equal(got) {
+				t.Errorf("want %v, got %v", tc.want, got)
+			}
+			zone := ""
+			if loc != nil {
+				zone = loc.String()
+			}
+			if tc.wantZone != zone {
+				t.Errorf("want time zone %q, got %q", tc.wantZone, zone)
+			}
+		})
+	}
+}
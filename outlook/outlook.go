@@ -0,0 +1,364 @@
+// Package outlook handles Microsoft 365 / Outlook calendar integration through the Microsoft Graph API.
+package outlook
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/spinnaker/rotation-scheduler/event"
+)
+
+const (
+	UserAgent = "github.com/spinnaker/rotation-scheduler"
+
+	// DateTimeFormat is how Graph represents the date and time of an event, in the event's time zone. All-day events
+	// must start and end at midnight.
+	DateTimeFormat = "2006-01-02T15:04:05"
+
+	defaultEndpoint = "https://graph.microsoft.com/v1.0/"
+
+	// propertySetID namespaces the single-value extended properties this package creates.
+	propertySetID = "{a4b2c6f0-3d1e-4c8b-9f57-6e0d2b81c3a9}"
+	// shiftKeyProperty is the extended property that ties an event to its schedule.Shift.
+	shiftKeyProperty = "String " + propertySetID + " Name shiftKey"
+	// rotationProperty is the extended property that marks an event as owned by a rotation. Events without it were
+	// not created by this package, and are never modified.
+	rotationProperty = "String " + propertySetID + " Name rotation"
+	// timeZoneProperty keeps the IANA time zone of timed events, since Graph may return the time zone an event was
+	// created in as a Windows name, like 'Pacific Standard Time'.
+	timeZoneProperty = "String " + propertySetID + " Name timeZone"
+	maxListResults   = 100
+)
+
+// Outlook wraps a single Outlook calendar, and is an event.Backend.
+type Outlook struct {
+	// Calendar is the Graph path of the calendar, relative to the API version, like 'users/oncall@example.com/calendar'
+	// or 'groups/{group-id}/calendar'.
+	Calendar string
+
+	// Rotation tags every event this Outlook creates, and limits it to only modifying events with the same tag.
+	Rotation string
+
+	endpoint string
+	client   *http.Client
+}
+
+var _ event.Backend = &Outlook{}
+
+// NewOutlook wraps the calendar specified using the client, which is responsible for authentication. The calendar can
+// be the calendar of any user, shared mailbox or group the client can edit. Only events tagged with the rotation name
+// are ever updated or deleted, so multiple rotations and human-created events can share a calendar.
+func NewOutlook(calendar, rotation string, client *http.Client) (*Outlook, error) {
+	calendar = strings.Trim(calendar, "/")
+	if calendar == "" {
+		return nil, fmt.Errorf("calendar cannot be empty")
+	}
+
+	if !strings.HasPrefix(calendar, "users/") && !strings.HasPrefix(calendar, "groups/") {
+		return nil, fmt.Errorf("calendar(%v) must start with 'users/' or 'groups/'", calendar)
+	}
+
+	if rotation == "" {
+		return nil, fmt.Errorf("rotation cannot be empty")
+	}
+
+	if client == nil {
+		client = http.DefaultClient
+	}
+
+	return &Outlook{
+		Calendar: calendar,
+		Rotation: rotation,
+		endpoint: defaultEndpoint,
+		client:   client,
+	}, nil
+}
+
+type graphEvent struct {
+	ID                            string              `json:"id,omitempty"`
+	Subject                       string              `json:"subject"`
+	Body                          *itemBody           `json:"body"`
+	Location                      *location           `json:"location"`
+	Start                         *dateTimeTimeZone   `json:"start"`
+	End                           *dateTimeTimeZone   `json:"end"`
+	IsAllDay                      bool                `json:"isAllDay"`
+	OriginalStartTimeZone         string              `json:"originalStartTimeZone,omitempty"`
+	ShowAs                        string              `json:"showAs,omitempty"`
+	Attendees                     []*attendee         `json:"attendees"`
+	SingleValueExtendedProperties []*extendedProperty `json:"singleValueExtendedProperties,omitempty"`
+}
+
+type itemBody struct {
+	ContentType string `json:"contentType"`
+	Content     string `json:"content"`
+}
+
+type location struct {
+	DisplayName string `json:"displayName"`
+}
+
+type dateTimeTimeZone struct {
+	DateTime string `json:"dateTime"`
+	TimeZone string `json:"timeZone"`
+}
+
+type attendee struct {
+	EmailAddress *emailAddress `json:"emailAddress"`
+	Type         string        `json:"type"`
+}
+
+type emailAddress struct {
+	Address string `json:"address"`
+}
+
+type extendedProperty struct {
+	ID    string `json:"id"`
+	Value string `json:"value"`
+}
+
+type eventList struct {
+	Value    []*graphEvent `json:"value"`
+	NextLink string        `json:"@odata.nextLink"`
+}
+
+type graphError struct {
+	Error struct {
+		Code    string `json:"code"`
+		Message string `json:"message"`
+	} `json:"error"`
+}
+
+// List only lists events owned by this rotation.
+func (o *Outlook) List() ([]*event.Event, error) {
+	params := url.Values{}
+	params.Set("$filter", fmt.Sprintf("singleValueExtendedProperties/Any(ep: ep/id eq '%v' and ep/value eq '%v')",
+		rotationProperty, strings.ReplaceAll(o.Rotation, "'", "''")))
+	params.Set("$expand", fmt.Sprintf("singleValueExtendedProperties($filter=id eq '%v' or id eq '%v')",
+		shiftKeyProperty, timeZoneProperty))
+	params.Set("$top", fmt.Sprint(maxListResults))
+
+	var events []*event.Event
+	next := o.eventsURL() + "?" + params.Encode()
+	for next != "" {
+		page := &eventList{}
+		if err := o.do(http.MethodGet, next, nil, page); err != nil {
+			return nil, err
+		}
+
+		for _, ge := range page.Value {
+			e, err := fromGraphEvent(ge)
+			if err != nil {
+				return nil, fmt.Errorf("error reading event %v: %v", ge.ID, err)
+			}
+			events = append(events, e)
+		}
+		next = page.NextLink
+	}
+	return events, nil
+}
+
+func (o *Outlook) Insert(e *event.Event) error {
+	return o.do(http.MethodPost, o.eventsURL(), o.toGraphEvent(e), nil)
+}
+
+func (o *Outlook) Update(existing, e *event.Event) error {
+	return o.do(http.MethodPatch, o.eventURL(existing.ID), o.toGraphEvent(e), nil)
+}
+
+func (o *Outlook) Delete(e *event.Event) error {
+	return o.do(http.MethodDelete, o.eventURL(e.ID), nil, nil)
+}
+
+func (o *Outlook) eventsURL() string {
+	return o.endpoint + o.Calendar + "/events"
+}
+
+func (o *Outlook) eventURL(id string) string {
+	return o.eventsURL() + "/" + url.PathEscape(id)
+}
+
+// do sends a request with in, if not nil, as its JSON body, and decodes the JSON response into out, if not nil.
+func (o *Outlook) do(method, u string, in, out interface{}) error {
+	var body []byte
+	if in != nil {
+		var err error
+		if body, err = json.Marshal(in); err != nil {
+			return fmt.Errorf("error marshalling request: %v", err)
+		}
+	}
+
+	req, err := http.NewRequest(method, u, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("User-Agent", UserAgent)
+	// Descriptions are plain text, so make sure they're returned that way rather than as HTML, and times are returned
+	// in UTC rather than the calendar owner's Windows time zone.
+	req.Header.Set("Prefer", `outlook.body-content-type="text", outlook.timezone="UTC"`)
+	if in != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	resp, err := o.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	respBody, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return fmt.Errorf("error reading %v response: %v", method, err)
+	}
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		ge := &graphError{}
+		if err := json.Unmarshal(respBody, ge); err != nil || ge.Error.Code == "" {
+			return fmt.Errorf("%v %v failed with status %v", method, req.URL.Path, resp.Status)
+		}
+		return fmt.Errorf("%v %v failed with status %v: %v: %v", method, req.URL.Path, resp.Status, ge.Error.Code,
+			ge.Error.Message)
+	}
+
+	if out == nil {
+		return nil
+	}
+	if err := json.Unmarshal(respBody, out); err != nil {
+		return fmt.Errorf("error unmarshalling %v response: %v", method, err)
+	}
+	return nil
+}
+
+func (o *Outlook) toGraphEvent(e *event.Event) *graphEvent {
+	ge := &graphEvent{
+		Subject: e.Summary,
+		Body: &itemBody{
+			ContentType: "text",
+			Content:     e.Description,
+		},
+		Location: &location{
+			DisplayName: e.Location,
+		},
+		Start:    toDateTimeTimeZone(e, e.Start), // Start is inclusive.
+		End:      toDateTimeTimeZone(e, e.End),   // End is exclusive.
+		IsAllDay: e.IsAllDay(),
+		// Don't block out attendees' whole calendars for the shift.
+		ShowAs:    "free",
+		Attendees: []*attendee{},
+		SingleValueExtendedProperties: []*extendedProperty{
+			{ID: shiftKeyProperty, Value: e.Key},
+			{ID: rotationProperty, Value: o.Rotation},
+		},
+	}
+	if !e.IsAllDay() {
+		ge.SingleValueExtendedProperties = append(ge.SingleValueExtendedProperties,
+			&extendedProperty{ID: timeZoneProperty, Value: e.TimeZone})
+	}
+	for _, a := range e.Attendees {
+		ge.Attendees = append(ge.Attendees, &attendee{
+			EmailAddress: &emailAddress{Address: a},
+			Type:         "required",
+		})
+	}
+	return ge
+}
+
+func toDateTimeTimeZone(e *event.Event, t time.Time) *dateTimeTimeZone {
+	if e.IsAllDay() {
+		return &dateTimeTimeZone{
+			DateTime: t.Format(DateTimeFormat),
+			TimeZone: "UTC",
+		}
+	}
+
+	if loc, err := e.TimeLocation(); err == nil {
+		t = t.In(loc)
+	}
+	return &dateTimeTimeZone{
+		DateTime: t.Format(DateTimeFormat),
+		TimeZone: e.TimeZone,
+	}
+}
+
+func fromGraphEvent(ge *graphEvent) (*event.Event, error) {
+	e := &event.Event{
+		ID:      ge.ID,
+		Summary: ge.Subject,
+	}
+
+	if ge.Body != nil {
+		// Graph may append a line break when converting the body to text.
+		e.Description = strings.TrimRight(ge.Body.Content, "\r\n")
+	}
+	if ge.Location != nil {
+		e.Location = ge.Location.DisplayName
+	}
+
+	var timeZone string
+	for _, p := range ge.SingleValueExtendedProperties {
+		// Graph doesn't preserve the case of the property ID.
+		switch {
+		case strings.EqualFold(p.ID, shiftKeyProperty):
+			e.Key = p.Value
+		case strings.EqualFold(p.ID, timeZoneProperty):
+			timeZone = p.Value
+		}
+	}
+
+	var err error
+	if e.Start, err = parseDateTimeTimeZone(ge.Start, ge.IsAllDay); err != nil {
+		return nil, fmt.Errorf("invalid start: %v", err)
+	}
+	if e.End, err = parseDateTimeTimeZone(ge.End, ge.IsAllDay); err != nil {
+		return nil, fmt.Errorf("invalid end: %v", err)
+	}
+	if !ge.IsAllDay {
+		// Graph returns times in UTC, but remembers the time zone the event was created in, though maybe by its Windows
+		// name, so the tagged IANA time zone is preferred.
+		for _, tz := range []string{timeZone, ge.OriginalStartTimeZone, ge.Start.TimeZone} {
+			if _, err := time.LoadLocation(tz); tz != "" && err == nil {
+				e.TimeZone = tz
+				break
+			}
+		}
+	}
+
+	for _, a := range ge.Attendees {
+		if a.EmailAddress != nil {
+			e.Attendees = append(e.Attendees, a.EmailAddress.Address)
+		}
+	}
+	return e, nil
+}
+
+// parseDateTimeTimeZone reads the date of an all-day event, or the time of a timed event. Graph returns fractional
+// seconds, like '2020-06-01T00:00:00.0000000', which are ignored.
+func parseDateTimeTimeZone(dt *dateTimeTimeZone, allDay bool) (time.Time, error) {
+	if dt == nil {
+		return time.Time{}, fmt.Errorf("missing date")
+	}
+
+	s := dt.DateTime
+	if i := strings.Index(s, "."); i >= 0 {
+		s = s[:i]
+	}
+
+	if allDay {
+		t, err := time.Parse(DateTimeFormat, s)
+		if err != nil {
+			return time.Time{}, err
+		}
+		return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC), nil
+	}
+
+	loc, err := time.LoadLocation(dt.TimeZone)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("unsupported time zone(%v): %v", dt.TimeZone, err)
+	}
+	return time.ParseInLocation(DateTimeFormat, s, loc)
+}
@@ -0,0 +1,423 @@
+package outlook
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"reflect"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"cloud.google.com/go/httpreplay"
+	"github.com/ghodss/yaml"
+	"github.com/spinnaker/rotation-scheduler/event"
+	"github.com/spinnaker/rotation-scheduler/render"
+	"github.com/spinnaker/rotation-scheduler/schedule"
+)
+
+const (
+	testCalendar = "users/spinbot@spinnaker.io/calendar"
+	testRotation = "build-cop"
+	replayFile   = "testing/schedule.replay"
+)
+
+// fakeServer is an in-memory calendar that supports just enough of the Microsoft Graph API for Outlook. It lists
+// events pageSize at a time.
+type fakeServer struct {
+	mu       sync.Mutex
+	events   map[string]*graphEvent
+	nextID   int
+	pageSize int
+}
+
+var rotationFilter = regexp.MustCompile(`ep/value eq '(.*)'\)$`)
+
+func (f *fakeServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	eventsPath := "/v1.0/" + testCalendar + "/events"
+	id := strings.TrimPrefix(strings.TrimPrefix(r.URL.Path, eventsPath), "/")
+	if !strings.HasPrefix(r.URL.Path, eventsPath) {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+
+	switch {
+	case r.Method == http.MethodGet && id == "":
+		rotation := rotationFilter.FindStringSubmatch(r.URL.Query().Get("$filter"))
+		if rotation == nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+
+		var ids []string
+		for id, e := range f.events {
+			if propertyValue(e, rotationProperty) == rotation[1] {
+				ids = append(ids, id)
+			}
+		}
+		sort.Strings(ids)
+
+		skip, _ := strconv.Atoi(r.URL.Query().Get("$skip"))
+		page := &eventList{}
+		for i := skip; i < len(ids) && i < skip+f.pageSize; i++ {
+			// Only the expanded shift key and time zone are returned.
+			e := *f.events[ids[i]]
+			e.SingleValueExtendedProperties = nil
+			for _, id := range []string{shiftKeyProperty, timeZoneProperty} {
+				if v := propertyValue(f.events[ids[i]], id); v != "" {
+					e.SingleValueExtendedProperties = append(e.SingleValueExtendedProperties,
+						&extendedProperty{ID: id, Value: v})
+				}
+			}
+			page.Value = append(page.Value, &e)
+		}
+		if skip+f.pageSize < len(ids) {
+			q := r.URL.Query()
+			q.Set("$skip", fmt.Sprint(skip+f.pageSize))
+			page.NextLink = "http://" + r.Host + r.URL.Path + "?" + q.Encode()
+		}
+		json.NewEncoder(w).Encode(page)
+	case r.Method == http.MethodPost && id == "":
+		e := &graphEvent{}
+		if err := json.NewDecoder(r.Body).Decode(e); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		f.nextID++
+		e.ID = fmt.Sprintf("inserted%v", f.nextID)
+		f.events[e.ID] = e
+		w.WriteHeader(http.StatusCreated)
+		json.NewEncoder(w).Encode(e)
+	case r.Method == http.MethodPatch && id != "":
+		if _, ok := f.events[id]; !ok {
+			w.WriteHeader(http.StatusNotFound)
+			fmt.Fprint(w, `{"error":{"code":"ErrorItemNotFound","message":"The specified object was not found in the store."}}`)
+			return
+		}
+		e := &graphEvent{}
+		if err := json.NewDecoder(r.Body).Decode(e); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		e.ID = id
+		f.events[id] = e
+		json.NewEncoder(w).Encode(e)
+	case r.Method == http.MethodDelete && id != "":
+		if _, ok := f.events[id]; !ok {
+			w.WriteHeader(http.StatusNotFound)
+			fmt.Fprint(w, `{"error":{"code":"ErrorItemNotFound","message":"The specified object was not found in the store."}}`)
+			return
+		}
+		delete(f.events, id)
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		w.WriteHeader(http.StatusMethodNotAllowed)
+	}
+}
+
+func propertyValue(e *graphEvent, id string) string {
+	for _, p := range e.SingleValueExtendedProperties {
+		if p.ID == id {
+			return p.Value
+		}
+	}
+	return ""
+}
+
+func newTestOutlook(t *testing.T, f *fakeServer) *Outlook {
+	srv := httptest.NewServer(f)
+	t.Cleanup(srv.Close)
+
+	o, err := NewOutlook(testCalendar, testRotation, srv.Client())
+	if err != nil {
+		t.Fatalf("cannot create new outlook: %v", err)
+	}
+	o.endpoint = srv.URL + "/v1.0/"
+	return o
+}
+
+func readTestSchedule(t *testing.T) *schedule.Schedule {
+	testSchedBytes, err := ioutil.ReadFile("testing/test_schedule.yaml")
+	if err != nil {
+		t.Fatalf("cannot read test schedule: %v", err)
+	}
+
+	testSched := &schedule.Schedule{}
+	if err := yaml.Unmarshal(testSchedBytes, testSched); err != nil {
+		t.Fatalf("cannot read schedule from yaml file: %v", err)
+	}
+	return testSched
+}
+
+// schedule.replay is recorded against a real calendar, whenever the requests Outlook makes change, with:
+// OUTLOOK_CLIENT_SECRET=... go run rotation.go calendar sync --record ./outlook/testing/schedule.replay \
+// --backend outlook --outlookTenantID $TENANT_ID --outlookClientID $CLIENT_ID \
+// --outlookCalendar users/spinbot@spinnaker.io/calendar --name build-cop ./outlook/testing/test_schedule.yaml
+func TestSchedule(t *testing.T) {
+	if _, err := os.Stat(replayFile); os.IsNotExist(err) {
+		t.Skipf("%v hasn't been recorded", replayFile)
+	}
+
+	replayer, err := httpreplay.NewReplayer(replayFile)
+	if err != nil {
+		t.Fatalf("cannot initialize HTTP replayer: %v", err)
+	}
+	defer replayer.Close()
+
+	client, err := replayer.Client(context.Background())
+	if err != nil {
+		t.Fatalf("cannot initialize client from replayer: %v", err)
+	}
+
+	outlookUnderTest, err := NewOutlook(testCalendar, testRotation, client)
+	if err != nil {
+		t.Fatalf("cannot create new outlook: %v", err)
+	}
+
+	if err := event.Sync(outlookUnderTest, readTestSchedule(t), testRotation, render.Default()); err != nil {
+		t.Errorf("error during scheduling: %v", err)
+	}
+}
+
+// TestScheduleFakeServer starts with a calendar holding:
+// * an untagged event from Mon 25 May 2020, and a release-manager event for Mon 01 Jun 2020, which are not listed and
+// left alone.
+// * a build-cop event from Mon 25 May 2020, which is deleted.
+// * tagged events for the shifts starting Mon 01 Jun 2020 and Mon 22 Jun 2020, which are unchanged.
+// * a tagged event for the shift starting Mon 08 Jun 2020 with the wrong user, which is updated.
+// The shift starting Mon 15 Jun 2020 has no event, so one is inserted. The events are listed in two pages.
+func TestScheduleFakeServer(t *testing.T) {
+	testSched := readTestSchedule(t)
+	events, err := event.Events(testSched, testRotation, render.Default())
+	if err != nil {
+		t.Fatalf("cannot create events: %v", err)
+	}
+
+	o := &Outlook{Rotation: testRotation}
+	allDay := func(summary, key string, start time.Time) *graphEvent {
+		return o.toGraphEvent(&event.Event{Summary: summary, Key: key, Start: start, End: start.AddDate(0, 0, 7)})
+	}
+
+	human := allDay("abc build-cop", "", time.Date(2020, 5, 25, 0, 0, 0, 0, time.UTC))
+	human.SingleValueExtendedProperties = nil
+	release := allDay("abc release-manager", "20200601", time.Date(2020, 6, 1, 0, 0, 0, 0, time.UTC))
+	release.SingleValueExtendedProperties[1].Value = "release-manager"
+	wrongUser := o.toGraphEvent(events[1])
+	wrongUser.Subject = "xyz build-cop"
+
+	f := &fakeServer{
+		events: map[string]*graphEvent{
+			"human":     human,
+			"release":   release,
+			"old0525":   allDay("abc build-cop", "20200525", time.Date(2020, 5, 25, 0, 0, 0, 0, time.UTC)),
+			"shift0601": o.toGraphEvent(events[0]),
+			"shift0608": wrongUser,
+			"shift0622": o.toGraphEvent(events[3]),
+		},
+		pageSize: 3,
+	}
+	for id, e := range f.events {
+		e.ID = id
+	}
+	outlookUnderTest := newTestOutlook(t, f)
+
+	p, err := event.NewPlan(outlookUnderTest, testSched, testRotation, render.Default())
+	if err != nil {
+		t.Fatalf("error planning: %v", err)
+	}
+
+	wantPlan := `- delete "abc build-cop" from 2020-05-25 to 2020-05-31
+~ update "xyz build-cop" from 2020-06-08 to 2020-06-14
+      to "lmn build-cop" from 2020-06-08 to 2020-06-14
++ insert "abc build-cop" from 2020-06-15 to 2020-06-21
+1 to insert, 1 to update, 1 to delete.
+`
+	if got := p.String(); wantPlan != got {
+		t.Errorf("want plan:\n%v\n\ngot:\n%v", wantPlan, got)
+	}
+
+	err = p.Apply(outlookUnderTest)
+	if err != nil {
+		t.Errorf("error during scheduling: %v", err)
+	}
+
+	if f.events["human"] != human || f.events["release"] != release {
+		t.Errorf("want events of other rotations left alone, got %v", f.events)
+	}
+
+	p, err = event.NewPlan(outlookUnderTest, testSched, testRotation, render.Default())
+	if err != nil {
+		t.Fatalf("error planning again: %v", err)
+	}
+	if !p.IsEmpty() {
+		t.Errorf("want calendar in sync with the schedule, got plan:\n%v", p)
+	}
+}
+
+func TestNewOutlook(t *testing.T) {
+	for _, tc := range []struct {
+		desc     string
+		calendar string
+		rotation string
+		wantErr  bool
+	}{
+		{
+			desc:     "user calendar",
+			calendar: "users/oncall@example.com/calendar",
+			rotation: testRotation,
+		},
+		{
+			desc:     "group calendar with slashes",
+			calendar: "/groups/abc123/calendar/",
+			rotation: testRotation,
+		},
+		{
+			desc:     "empty calendar",
+			rotation: testRotation,
+			wantErr:  true,
+		},
+		{
+			desc:     "calendar without owner",
+			calendar: "me/calendar",
+			rotation: testRotation,
+			wantErr:  true,
+		},
+		{
+			desc:     "empty rotation",
+			calendar: testCalendar,
+			wantErr:  true,
+		},
+	} {
+		t.Run(tc.desc, func(t *testing.T) {
+			_, err := NewOutlook(tc.calendar, tc.rotation, nil)
+			if tc.wantErr != (err != nil) {
+				t.Errorf("wantErr %v, got %v", tc.wantErr, err)
+			}
+		})
+	}
+}
+
+func TestGraphEvent(t *testing.T) {
+	o, err := NewOutlook(testCalendar, testRotation, nil)
+	if err != nil {
+		t.Fatalf("cannot create new outlook: %v", err)
+	}
+
+	e := &event.Event{
+		Key:         "20200101",
+		Summary:     "xyz@example.com build-cop",
+		Description: "Covering for abc.",
+		Location:    "#build-cop",
+		Start:       time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC),
+		End:         time.Date(2020, 1, 8, 0, 0, 0, 0, time.UTC),
+		Attendees:   []string{"xyz@example.com"},
+	}
+
+	ge := o.toGraphEvent(e)
+	if !ge.IsAllDay || ge.ShowAs != "free" {
+		t.Errorf("want free all-day event, got isAllDay %v, showAs %v", ge.IsAllDay, ge.ShowAs)
+	}
+	if want, got := "2020-01-01T00:00:00", ge.Start.DateTime; want != got {
+		t.Errorf("start: want %v, got %v", want, got)
+	}
+	if want, got := "2020-01-08T00:00:00", ge.End.DateTime; want != got {
+		t.Errorf("end: want %v, got %v", want, got)
+	}
+	wantProps := []*extendedProperty{
+		{ID: shiftKeyProperty, Value: "20200101"},
+		{ID: rotationProperty, Value: testRotation},
+	}
+	if !reflect.DeepEqual(wantProps, ge.SingleValueExtendedProperties) {
+		t.Errorf("want extended properties %v, got %v", wantProps, ge.SingleValueExtendedProperties)
+	}
+
+	// Graph returns dates with fractional seconds, and the body with a trailing line break.
+	ge.ID = "abc123"
+	ge.Start.DateTime += ".0000000"
+	ge.End.DateTime += ".0000000"
+	ge.Body.Content += "\r\n"
+	got, err := fromGraphEvent(ge)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	want := *e
+	want.ID = "abc123"
+	if !reflect.DeepEqual(&want, got) {
+		t.Errorf("round trip failed: want\n%+v\n\ngot\n%+v", &want, got)
+	}
+}
+
+func TestGraphEventTimed(t *testing.T) {
+	o, err := NewOutlook(testCalendar, testRotation, nil)
+	if err != nil {
+		t.Fatalf("cannot create new outlook: %v", err)
+	}
+
+	e := &event.Event{
+		Key:      "20200601",
+		Summary:  "abc build-cop",
+		Start:    time.Date(2020, 6, 1, 17, 0, 0, 0, time.UTC),
+		End:      time.Date(2020, 6, 8, 17, 0, 0, 0, time.UTC),
+		TimeZone: "America/Los_Angeles",
+	}
+
+	ge := o.toGraphEvent(e)
+	if ge.IsAllDay {
+		t.Error("want timed event, got all-day event")
+	}
+	wantStart := &dateTimeTimeZone{DateTime: "2020-06-01T10:00:00", TimeZone: "America/Los_Angeles"}
+	if !reflect.DeepEqual(wantStart, ge.Start) {
+		t.Errorf("start: want %+v, got %+v", wantStart, ge.Start)
+	}
+
+	// Graph returns times in UTC, along with the time zone the event was created in by its Windows name.
+	ge.OriginalStartTimeZone = "Pacific Standard Time"
+	ge.Start = &dateTimeTimeZone{DateTime: "2020-06-01T17:00:00.0000000", TimeZone: "UTC"}
+	ge.End = &dateTimeTimeZone{DateTime: "2020-06-08T17:00:00.0000000", TimeZone: "UTC"}
+	got, err := fromGraphEvent(ge)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !e.Matches(got) {
+		t.Errorf("round trip failed: want\n%+v\n\ngot\n%+v", e, got)
+	}
+}
+
+func TestFromGraphEventInvalid(t *testing.T) {
+	for _, tc := range []struct {
+		desc string
+		ge   *graphEvent
+	}{
+		{
+			desc: "missing start",
+			ge: &graphEvent{
+				End: &dateTimeTimeZone{DateTime: "2020-01-08T00:00:00.0000000", TimeZone: "UTC"},
+			},
+		},
+		{
+			desc: "invalid end",
+			ge: &graphEvent{
+				Start: &dateTimeTimeZone{DateTime: "2020-01-01T00:00:00.0000000", TimeZone: "UTC"},
+				End:   &dateTimeTimeZone{DateTime: "next week", TimeZone: "UTC"},
+			},
+		},
+	} {
+		t.Run(tc.desc, func(t *testing.T) {
+			if _, err := fromGraphEvent(tc.ge); err == nil {
+				t.Error("want error and didn't get one.")
+			}
+		})
+	}
+}
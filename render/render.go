@@ -0,0 +1,194 @@
+// Package render renders the text of calendar events for shifts from Go text/templates.
+package render
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/spinnaker/rotation-scheduler/schedule"
+)
+
+const (
+	DefaultSummary   = "{{.User}} {{.Rotation}}{{if .Type}} {{.Type}}{{end}}{{if .Roles}} ({{range $i, $r := .Roles}}{{if $i}}, {{end}}{{$r.Name}}: {{$r.User}}{{end}}){{end}}"
+	DefaultAttendees = "{{.User}}{{range .Roles}}, {{.User}}{{end}}"
+)
+
+// Data is the value every template is executed with.
+type Data struct {
+	// Rotation is the name of the rotation.
+	Rotation string
+
+	// User is the user on duty: the shift's UserOverride if set, otherwise its User.
+	User string
+
+	// ScheduledUser and UserOverride are the shift's User and UserOverride fields.
+	ScheduledUser string
+	UserOverride  string
+
+	// Start and Stop are both inclusive dates, except for timed shifts, when they're the times the shift starts and stops,
+	// in its time zone.
+	Start time.Time
+	Stop  time.Time
+
+	// Index is the shift's index in the schedule, starting from 0.
+	Index int
+
+	// Type is the shift's type, like "weekend", or empty for shifts of the default type.
+	Type string
+
+	// Roles are the shift's additional roles, sorted by name.
+	Roles []*RoleData
+}
+
+// RoleData describes who holds an additional role during a shift.
+type RoleData struct {
+	Name string
+
+	// User, ScheduledUser and UserOverride work like the Data fields of the same names.
+	User          string
+	ScheduledUser string
+	UserOverride  string
+}
+
+// ShiftData creates the Data for the shift at index i of sched.
+func ShiftData(sched *schedule.Schedule, i int, rotation string) *Data {
+	shift := sched.Shifts[i]
+	start := shift.StartDate
+	stopIncl, _ := sched.ShiftStopDates(i)
+	if loc, err := shift.Location(); err == nil && shift.Timed() {
+		start, stopIncl = start.In(loc), stopIncl.In(loc)
+	}
+	d := &Data{
+		Rotation:      rotation,
+		User:          shift.GetUser(),
+		ScheduledUser: shift.User,
+		UserOverride:  shift.UserOverride,
+		Start:         start,
+		Stop:          stopIncl,
+		Index:         i,
+		Type:          shift.Type,
+	}
+	for _, name := range shift.RoleNames() {
+		a := shift.Roles[name]
+		d.Roles = append(d.Roles, &RoleData{
+			Name:          name,
+			User:          a.GetUser(),
+			ScheduledUser: a.User,
+			UserOverride:  a.UserOverride,
+		})
+	}
+	return d
+}
+
+// Templates are the unparsed text/templates for each event field. Empty templates render empty strings, except for
+// Summary and Attendees, which fall back to DefaultSummary and DefaultAttendees.
+type Templates struct {
+	Summary     string `json:"summary,omitempty"`
+	Description string `json:"description,omitempty"`
+	Location    string `json:"location,omitempty"`
+
+	// Attendees renders a comma or whitespace separated list. Only values that look like email addresses are invited.
+	Attendees string `json:"attendees,omitempty"`
+}
+
+// Event holds the rendered fields of an event.
+type Event struct {
+	Summary     string
+	Description string
+	Location    string
+	Attendees   []string
+}
+
+// Renderer renders Events from parsed Templates.
+type Renderer struct {
+	summary     *template.Template
+	description *template.Template
+	location    *template.Template
+	attendees   *template.Template
+}
+
+// NewRenderer parses all templates, returning an error naming the first template that fails to parse.
+func NewRenderer(t *Templates) (*Renderer, error) {
+	if t == nil {
+		t = &Templates{}
+	}
+
+	summary := t.Summary
+	if summary == "" {
+		summary = DefaultSummary
+	}
+	attendees := t.Attendees
+	if attendees == "" {
+		attendees = DefaultAttendees
+	}
+
+	r := &Renderer{}
+	for _, f := range []struct {
+		name string
+		text string
+		dest **template.Template
+	}{
+		{"summary", summary, &r.summary},
+		{"description", t.Description, &r.description},
+		{"location", t.Location, &r.location},
+		{"attendees", attendees, &r.attendees},
+	} {
+		tmpl, err := template.New(f.name).Option("missingkey=error").Parse(f.text)
+		if err != nil {
+			return nil, fmt.Errorf("error parsing %v template: %v", f.name, err)
+		}
+		*f.dest = tmpl
+	}
+	return r, nil
+}
+
+// Default returns a Renderer that only uses DefaultSummary and DefaultAttendees.
+func Default() *Renderer {
+	r, err := NewRenderer(nil)
+	if err != nil {
+		panic(fmt.Sprintf("default templates are invalid: %v", err))
+	}
+	return r
+}
+
+// Render executes every template with d.
+func (r *Renderer) Render(d *Data) (*Event, error) {
+	e := &Event{}
+	for _, f := range []struct {
+		tmpl *template.Template
+		dest *string
+	}{
+		{r.summary, &e.Summary},
+		{r.description, &e.Description},
+		{r.location, &e.Location},
+	} {
+		s, err := execute(f.tmpl, d)
+		if err != nil {
+			return nil, err
+		}
+		*f.dest = s
+	}
+
+	attendees, err := execute(r.attendees, d)
+	if err != nil {
+		return nil, err
+	}
+	for _, a := range strings.FieldsFunc(attendees, func(r rune) bool { return r == ',' || r == ' ' || r == '\n' || r == '\t' }) {
+		if strings.Contains(a, "@") {
+			e.Attendees = append(e.Attendees, a)
+		}
+	}
+
+	return e, nil
+}
+
+func execute(tmpl *template.Template, d *Data) (string, error) {
+	buf := &bytes.Buffer{}
+	if err := tmpl.Execute(buf, d); err != nil {
+		return "", fmt.Errorf("error executing %v template: %v", tmpl.Name(), err)
+	}
+	return strings.TrimSpace(buf.String()), nil
+}
@@ -0,0 +1,166 @@
+package render
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/spinnaker/rotation-scheduler/schedule"
+)
+
+func TestShiftData(t *testing.T) {
+	sched := &schedule.Schedule{
+		Shifts: []*schedule.Shift{
+			{
+				StartDate: time.Date(2020, 6, 1, 0, 0, 0, 0, time.UTC),
+				User:      "abc",
+			},
+			{
+				StartDate:    time.Date(2020, 6, 8, 0, 0, 0, 0, time.UTC),
+				StopDate:     time.Date(2020, 6, 14, 0, 0, 0, 0, time.UTC),
+				User:         "lmn",
+				UserOverride: "xyz",
+				Type:         "weekend",
+				Roles: map[string]*schedule.Assignment{
+					"shadow":    {User: "def"},
+					"secondary": {User: "abc", UserOverride: "ghi"},
+				},
+			},
+		},
+	}
+
+	want := &Data{
+		Rotation:      "Release Manager",
+		User:          "xyz",
+		ScheduledUser: "lmn",
+		UserOverride:  "xyz",
+		Start:         time.Date(2020, 6, 8, 0, 0, 0, 0, time.UTC),
+		Stop:          time.Date(2020, 6, 14, 0, 0, 0, 0, time.UTC),
+		Index:         1,
+		Type:          "weekend",
+		Roles: []*RoleData{
+			{Name: "secondary", User: "ghi", ScheduledUser: "abc", UserOverride: "ghi"},
+			{Name: "shadow", User: "def", ScheduledUser: "def"},
+		},
+	}
+	if got := ShiftData(sched, 1, "Release Manager"); !reflect.DeepEqual(want, got) {
+		t.Errorf("want %+v, got %+v", want, got)
+	}
+}
+
+func TestRender(t *testing.T) {
+	data := &Data{
+		Rotation:      "Release Manager",
+		User:          "xyz@example.com",
+		ScheduledUser: "lmn",
+		UserOverride:  "xyz@example.com",
+		Start:         time.Date(2020, 6, 8, 0, 0, 0, 0, time.UTC),
+		Stop:          time.Date(2020, 6, 14, 0, 0, 0, 0, time.UTC),
+		Index:         1,
+	}
+
+	roleData := &Data{}
+	*roleData = *data
+	roleData.Roles = []*RoleData{
+		{Name: "secondary", User: "abc@example.com", ScheduledUser: "abc@example.com"},
+		{Name: "shadow", User: "def", ScheduledUser: "def"},
+	}
+
+	typeData := &Data{}
+	*typeData = *data
+	typeData.Type = "weekend"
+
+	for _, tc := range []struct {
+		desc      string
+		data      *Data
+		templates *Templates
+		wantErr   bool
+		want      *Event
+	}{
+		{
+			desc: "defaults",
+			want: &Event{
+				Summary:   "xyz@example.com Release Manager",
+				Attendees: []string{"xyz@example.com"},
+			},
+		},
+		{
+			desc: "all fields",
+			templates: &Templates{
+				Summary:     "{{.Rotation}}: {{.User}} (shift {{.Index}})",
+				Description: "Covering for {{.ScheduledUser}} until {{.Stop.Format \"Mon 02 Jan\"}}.",
+				Location:    "#release-{{.Start.Format \"2006-01-02\"}}",
+				Attendees:   "{{.User}}, releases@example.com, {{.ScheduledUser}}",
+			},
+			want: &Event{
+				Summary:     "Release Manager: xyz@example.com (shift 1)",
+				Description: "Covering for lmn until Sun 14 Jun.",
+				Location:    "#release-2020-06-08",
+				Attendees:   []string{"xyz@example.com", "releases@example.com"},
+			},
+		},
+		{
+			desc: "defaults with roles",
+			data: roleData,
+			want: &Event{
+				Summary:   "xyz@example.com Release Manager (secondary: abc@example.com, shadow: def)",
+				Attendees: []string{"xyz@example.com", "abc@example.com"},
+			},
+		},
+		{
+			desc: "defaults with type",
+			data: typeData,
+			want: &Event{
+				Summary:   "xyz@example.com Release Manager weekend",
+				Attendees: []string{"xyz@example.com"},
+			},
+		},
+		{
+			desc: "roles",
+			data: roleData,
+			templates: &Templates{
+				Summary: "{{.User}}{{range .Roles}} / {{.Name}} {{.User}}{{end}}",
+			},
+			want: &Event{
+				Summary:   "xyz@example.com / secondary abc@example.com / shadow def",
+				Attendees: []string{"xyz@example.com", "abc@example.com"},
+			},
+		},
+		{
+			desc: "parse error",
+			templates: &Templates{
+				Summary: "{{.User",
+			},
+			wantErr: true,
+		},
+		{
+			desc: "unknown field",
+			templates: &Templates{
+				Location: "{{.Room}}",
+			},
+			wantErr: true,
+		},
+	} {
+		t.Run(tc.desc, func(t *testing.T) {
+			r, err := NewRenderer(tc.templates)
+			if err == nil {
+				d := tc.data
+				if d == nil {
+					d = data
+				}
+
+				var got *Event
+				got, err = r.Render(d)
+				if err == nil && !reflect.DeepEqual(tc.want, got) {
+					t.Errorf("want %+v, got %+v", tc.want, got)
+				}
+			}
+
+			if tc.wantErr && err == nil {
+				t.Errorf("err expected and not received.")
+			} else if !tc.wantErr && err != nil {
+				t.Errorf("got unexpected error: %v:", err)
+			}
+		})
+	}
+}
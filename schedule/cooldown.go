@@ -0,0 +1,74 @@
+package schedule
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Cooldown is the minimum gap between two shifts someone is on duty for, in any role.
+type Cooldown struct {
+	// Days is the minimum number of days from the end of someone's shift to the start of their next one.
+	Days int `json:"days,omitempty"`
+
+	// Shifts is the minimum number of other shifts between two of someone's shifts.
+	Shifts int `json:"shifts,omitempty"`
+}
+
+// TooSoon is true if a shift starting on start, at index next of sch, is too soon after the shift at index i, which
+// must come before it. The shift at next doesn't have to be in sch yet.
+func (c *Cooldown) TooSoon(sch *Schedule, i, next int, start time.Time) bool {
+	if c == nil {
+		return false
+	}
+
+	stopExcl := start
+	if next > i+1 {
+		_, stopExcl = sch.ShiftStopDates(i)
+	}
+	return next-i-1 < c.Shifts || start.Before(stopExcl.AddDate(0, 0, c.Days))
+}
+
+func (c *Cooldown) Validate() error {
+	if c == nil {
+		return nil
+	}
+
+	if c.Days < 0 || c.Shifts < 0 {
+		return fmt.Errorf("cooldown cannot be negative")
+	}
+	return nil
+}
+
+func (c *Cooldown) String() string {
+	var parts []string
+	if c.Days != 0 {
+		parts = append(parts, fmt.Sprintf("%v day(s)", c.Days))
+	}
+	if c.Shifts != 0 {
+		parts = append(parts, fmt.Sprintf("%v shift(s)", c.Shifts))
+	}
+	return strings.Join(parts, " and ")
+}
+
+// PreviousShiftOf returns the index of the last shift before index i of sch that user is on duty for, or -1 if there
+// isn't one. i can be the index of a shift that isn't in sch yet.
+func (sch *Schedule) PreviousShiftOf(user string, i int) int {
+	for j := i - 1; j >= 0; j-- {
+		if sch.Shifts[j].OnDuty(user) {
+			return j
+		}
+	}
+	return -1
+}
+
+// NextShiftOf returns the index of the first shift after index i of sch that user is on duty for, or -1 if there
+// isn't one.
+func (sch *Schedule) NextShiftOf(user string, i int) int {
+	for j := i + 1; j < len(sch.Shifts); j++ {
+		if sch.Shifts[j].OnDuty(user) {
+			return j
+		}
+	}
+	return -1
+}
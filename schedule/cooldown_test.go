@@ -0,0 +1,76 @@
+package schedule
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestCooldownTooSoon(t *testing.T) {
+	day := func(d int) time.Time {
+		return time.Date(2020, 6, d, 0, 0, 0, 0, time.UTC)
+	}
+
+	// Shifts of 2 days, except the last, which stops early.
+	sched := &Schedule{Shifts: []*Shift{
+		{StartDate: day(1), User: "abc"},
+		{StartDate: day(3), User: "lmn"},
+		{StartDate: day(5), User: "xyz", StopDate: day(5)},
+	}}
+
+	for _, tc := range []struct {
+		desc     string
+		cooldown *Cooldown
+		i, next  int
+		start    time.Time
+		want     bool
+	}{
+		{desc: "no cooldown", i: 0, next: 1, start: day(3)},
+		{desc: "back to back", cooldown: &Cooldown{Shifts: 1}, i: 0, next: 1, start: day(3), want: true},
+		{desc: "one shift between", cooldown: &Cooldown{Shifts: 1}, i: 0, next: 2, start: day(5)},
+		{desc: "too few shifts between", cooldown: &Cooldown{Shifts: 2}, i: 0, next: 2, start: day(5), want: true},
+		{desc: "days after the shift", cooldown: &Cooldown{Days: 2}, i: 0, next: 2, start: day(5)},
+		{desc: "too few days after the shift", cooldown: &Cooldown{Days: 3}, i: 0, next: 2, start: day(5), want: true},
+		{desc: "shift not in the schedule yet", cooldown: &Cooldown{Days: 2}, i: 2, next: 3, start: day(7), want: true},
+		{desc: "shift not in the schedule yet, after the cooldown", cooldown: &Cooldown{Days: 1}, i: 1, next: 3, start: day(6)},
+	} {
+		t.Run(tc.desc, func(t *testing.T) {
+			if got := tc.cooldown.TooSoon(sched, tc.i, tc.next, tc.start); got != tc.want {
+				t.Errorf("want %v, got %v", tc.want, got)
+			}
+		})
+	}
+}
+
+func TestLintCooldown(t *testing.T) {
+	sched := &Schedule{
+		Cooldown: &Cooldown{Shifts: 1},
+		Shifts: []*Shift{
+			{StartDate: time.Date(2020, 6, 1, 0, 0, 0, 0, time.UTC), User: "abc"},
+			{StartDate: time.Date(2020, 6, 8, 0, 0, 0, 0, time.UTC), User: "lmn", Roles: map[string]*Assignment{"secondary": {User: "abc"}}},
+			{StartDate: time.Date(2020, 6, 15, 0, 0, 0, 0, time.UTC), User: "xyz"},
+			{StartDate: time.Date(2020, 6, 22, 0, 0, 0, 0, time.UTC), User: "abc", StopDate: time.Date(2020, 6, 28, 0, 0, 0, 0, time.UTC)},
+		},
+	}
+
+	want := []string{
+		"shift starting Mon 08 Jun 2020: abc is on duty as secondary, but was on duty in the shift starting Mon 01 Jun 2020, within the cooldown of 1 shift(s)",
+		"shift starting Mon 22 Jun 2020: abc is on duty as primary, but was on duty in the shift starting Mon 08 Jun 2020, within the cooldown of 14 day(s)",
+	}
+
+	var got []string
+	for _, p := range sched.Lint(nil, &Cooldown{Days: 14}, nil, nil) {
+		got = append(got, p.String())
+	}
+	if !reflect.DeepEqual(want, got) {
+		t.Errorf("want problems:\n%q\n\ngot:\n%q", want, got)
+	}
+
+	if err := sched.Validate(); err != nil {
+		t.Errorf("unexpected error validating schedule: %v", err)
+	}
+	sched.Cooldown.Days = -1
+	if err := sched.Validate(); err == nil {
+		t.Errorf("want error validating a negative cooldown and didn't get one.")
+	}
+}
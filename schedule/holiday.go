@@ -0,0 +1,56 @@
+package schedule
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+// Holiday is a day, like a public holiday, that the scheduler spreads evenly across the rotation.
+type Holiday struct {
+	// Date is the day of the holiday. Only the year, month, and day fields are relevant.
+	Date time.Time
+	Name string
+}
+
+// CountHolidays returns how many distinct dates of holidays fall from start (inclusive) to stop (exclusive), even
+// partly, for times that aren't whole days.
+func CountHolidays(holidays []*Holiday, start, stopExclusive time.Time) int {
+	dates := map[time.Time]bool{}
+	for _, h := range holidays {
+		if h.Date.Before(stopExclusive) && h.Date.AddDate(0, 0, 1).After(start) {
+			dates[h.Date] = true
+		}
+	}
+	return len(dates)
+}
+
+// MarshalJSON returns the date in the `RosterDateFormat` format.
+func (h *Holiday) MarshalJSON() ([]byte, error) {
+	return json.Marshal(struct {
+		Date string `json:"date"`
+		Name string `json:"name,omitempty"`
+	}{
+		Date: h.Date.Format(RosterDateFormat),
+		Name: h.Name,
+	})
+}
+
+// UnmarshalJSON reads the date in the `RosterDateFormat` format, and will throw parsing error otherwise.
+func (h *Holiday) UnmarshalJSON(data []byte) error {
+	aux := struct {
+		Date string `json:"date"`
+		Name string `json:"name,omitempty"`
+	}{}
+	if err := json.Unmarshal(data, &aux); err != nil {
+		return err
+	}
+
+	date, err := time.Parse(RosterDateFormat, aux.Date)
+	if err != nil {
+		return fmt.Errorf("error parsing holiday date: %v", err)
+	}
+	h.Date = date
+	h.Name = aux.Name
+	return nil
+}
@@ -0,0 +1,77 @@
+package schedule
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/ghodss/yaml"
+)
+
+func TestUnmarshalHolidays(t *testing.T) {
+	got := []*Holiday{}
+	err := yaml.Unmarshal([]byte(`- date: 2020-12-25
+  name: Christmas Day
+- date: "2021-01-01"
+`), &got)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	want := []*Holiday{
+		{Date: time.Date(2020, 12, 25, 0, 0, 0, 0, time.UTC), Name: "Christmas Day"},
+		{Date: time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC)},
+	}
+	if !reflect.DeepEqual(want, got) {
+		t.Errorf("want %+v, got %+v", want, got)
+	}
+
+	if err := yaml.Unmarshal([]byte(`- date: Fri 25 Dec 2020`), &got); err == nil {
+		t.Errorf("want error on invalid date and didn't get one.")
+	}
+}
+
+func TestCountHolidays(t *testing.T) {
+	holidays := []*Holiday{
+		{Date: time.Date(2020, 12, 24, 0, 0, 0, 0, time.UTC)},
+		{Date: time.Date(2020, 12, 25, 0, 0, 0, 0, time.UTC)},
+		{Date: time.Date(2020, 12, 25, 0, 0, 0, 0, time.UTC), Name: "another region"},
+		{Date: time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC)},
+	}
+
+	for _, tc := range []struct {
+		desc        string
+		start, stop time.Time
+		want        int
+	}{
+		{
+			desc:  "none",
+			start: time.Date(2020, 12, 1, 0, 0, 0, 0, time.UTC),
+			stop:  time.Date(2020, 12, 24, 0, 0, 0, 0, time.UTC),
+		},
+		{
+			desc:  "same date counted once",
+			start: time.Date(2020, 12, 21, 0, 0, 0, 0, time.UTC),
+			stop:  time.Date(2020, 12, 28, 0, 0, 0, 0, time.UTC),
+			want:  2,
+		},
+		{
+			desc:  "start inclusive, stop exclusive",
+			start: time.Date(2020, 12, 25, 0, 0, 0, 0, time.UTC),
+			stop:  time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC),
+			want:  1,
+		},
+		{
+			desc:  "part of a day",
+			start: time.Date(2020, 12, 31, 14, 0, 0, 0, time.UTC),
+			stop:  time.Date(2021, 1, 1, 1, 0, 0, 0, time.UTC),
+			want:  1,
+		},
+	} {
+		t.Run(tc.desc, func(t *testing.T) {
+			if got := CountHolidays(holidays, tc.start, tc.stop); tc.want != got {
+				t.Errorf("want %v, got %v", tc.want, got)
+			}
+		})
+	}
+}
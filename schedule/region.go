@@ -0,0 +1,74 @@
+package schedule
+
+import (
+	"fmt"
+	"time"
+)
+
+// Region is one part of the day in a follow-the-sun schedule, like "apac", which takes over at its HandoffTime in its
+// TimeZone every day, until the next region takes over. Shifts of a region have its Name as their Type.
+type Region struct {
+	Name string `json:"name"`
+
+	// HandoffTime is the time of day, in the `HandoffTimeFormat` format, that the region takes over.
+	HandoffTime string `json:"handoffTime"`
+
+	// TimeZone is the IANA time zone of the HandoffTime, like "Asia/Singapore". Defaults to UTC.
+	TimeZone string `json:"timeZone,omitempty"`
+}
+
+// Handoff parses the HandoffTime and TimeZone.
+func (r *Region) Handoff() (*Handoff, error) {
+	return (&Schedule{HandoffTime: r.HandoffTime, TimeZone: r.TimeZone}).Handoff()
+}
+
+// Location returns the time zone of the region, or UTC if it's invalid.
+func (r *Region) Location() *time.Location {
+	h, err := r.Handoff()
+	if err != nil {
+		return time.UTC
+	}
+	return h.Location
+}
+
+// Validate confirms the region has a name, and a valid handoff time and time zone.
+func (r *Region) Validate() error {
+	if r == nil {
+		return fmt.Errorf("region cannot be nil")
+	}
+
+	if r.Name == "" {
+		return fmt.Errorf("region must have a name")
+	}
+
+	if r.HandoffTime == "" {
+		return fmt.Errorf("region %v must have a handoff time", r.Name)
+	}
+
+	if _, err := r.Handoff(); err != nil {
+		return fmt.Errorf("invalid region %v: %v", r.Name, err)
+	}
+	return nil
+}
+
+// Next returns the first time the region takes over after t.
+func (r *Region) Next(t time.Time) time.Time {
+	h, _ := r.Handoff()
+	local := t.In(h.Location)
+	next := h.At(local)
+	if !next.After(t) {
+		next = h.At(local.AddDate(0, 0, 1))
+	}
+	return next
+}
+
+// Previous returns the last time the region took over at or before t.
+func (r *Region) Previous(t time.Time) time.Time {
+	h, _ := r.Handoff()
+	local := t.In(h.Location)
+	previous := h.At(local)
+	if previous.After(t) {
+		previous = h.At(local.AddDate(0, 0, -1))
+	}
+	return previous
+}
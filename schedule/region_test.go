@@ -0,0 +1,68 @@
+package schedule
+
+import (
+	"testing"
+	"time"
+)
+
+func TestRegionValidate(t *testing.T) {
+	for _, tc := range []struct {
+		desc    string
+		region  *Region
+		wantErr bool
+	}{
+		{desc: "valid", region: &Region{Name: "apac", HandoffTime: "09:00", TimeZone: "Asia/Singapore"}},
+		{desc: "UTC by default", region: &Region{Name: "emea", HandoffTime: "09:00"}},
+		{desc: "nil", wantErr: true},
+		{desc: "no name", region: &Region{HandoffTime: "09:00"}, wantErr: true},
+		{desc: "no handoff time", region: &Region{Name: "apac"}, wantErr: true},
+		{desc: "invalid handoff time", region: &Region{Name: "apac", HandoffTime: "9am"}, wantErr: true},
+		{desc: "invalid time zone", region: &Region{Name: "apac", HandoffTime: "09:00", TimeZone: "Asia/Nowhere"}, wantErr: true},
+	} {
+		t.Run(tc.desc, func(t *testing.T) {
+			err := tc.region.Validate()
+			if tc.wantErr != (err != nil) {
+				t.Errorf("want error %v, got %v", tc.wantErr, err)
+			}
+		})
+	}
+}
+
+func TestRegionNextPrevious(t *testing.T) {
+	amer := &Region{Name: "amer", HandoffTime: "09:00", TimeZone: "America/New_York"}
+
+	for _, tc := range []struct {
+		desc         string
+		t            time.Time
+		wantNext     time.Time
+		wantPrevious time.Time
+	}{
+		{
+			desc:         "before the handoff",
+			t:            time.Date(2020, 3, 2, 10, 0, 0, 0, time.UTC),
+			wantNext:     time.Date(2020, 3, 2, 14, 0, 0, 0, time.UTC),
+			wantPrevious: time.Date(2020, 3, 1, 14, 0, 0, 0, time.UTC),
+		},
+		{
+			desc:         "at the handoff",
+			t:            time.Date(2020, 3, 2, 14, 0, 0, 0, time.UTC),
+			wantNext:     time.Date(2020, 3, 3, 14, 0, 0, 0, time.UTC),
+			wantPrevious: time.Date(2020, 3, 2, 14, 0, 0, 0, time.UTC),
+		},
+		{
+			desc:         "daylight saving time starts",
+			t:            time.Date(2020, 3, 8, 0, 0, 0, 0, time.UTC),
+			wantNext:     time.Date(2020, 3, 8, 13, 0, 0, 0, time.UTC),
+			wantPrevious: time.Date(2020, 3, 7, 14, 0, 0, 0, time.UTC),
+		},
+	} {
+		t.Run(tc.desc, func(t *testing.T) {
+			if got := amer.Next(tc.t); !tc.wantNext.Equal(got) {
+				t.Errorf("next: want %v, got %v", tc.wantNext, got)
+			}
+			if got := amer.Previous(tc.t); !tc.wantPrevious.Equal(got) {
+				t.Errorf("previous: want %v, got %v", tc.wantPrevious, got)
+			}
+		})
+	}
+}
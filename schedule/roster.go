@@ -0,0 +1,290 @@
+package schedule
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/ghodss/yaml"
+)
+
+// RosterDateFormat is the format of dates in a Roster, which are typed by hand far more often than shift dates.
+const RosterDateFormat = "2006-01-02"
+
+// Roster holds what the scheduler needs to know about the members of a rotation beyond their names, like their
+// weights, and when they're unavailable. It can be part of a Schedule, or kept in a separate file.
+type Roster struct {
+	Members []*Member `json:"members,omitempty"`
+}
+
+// Member is someone in the rotation.
+type Member struct {
+	User string `json:"user"`
+
+	// Weight is the fraction of their turns the member takes, between 0 and 1, like 0.5 for someone allocated half
+	// time. Zero means the default of 1.
+	Weight float64 `json:"weight,omitempty"`
+
+	// Unavailable are the dates the member can't be on duty, like vacations.
+	Unavailable []*Period `json:"unavailable,omitempty"`
+
+	// JoinDate is the first day the member is in the rotation, and LeaveDate the last, both inclusive. Either can be
+	// zero for no limit.
+	JoinDate  time.Time `json:"-"`
+	LeaveDate time.Time `json:"-"`
+}
+
+// Period is a span of whole days.
+type Period struct {
+	// Start and Stop are both inclusive. Only the year, month, and day fields are relevant. A zero Stop means the
+	// period is the single day of Start.
+	Start time.Time
+	Stop  time.Time
+}
+
+// Member returns the member named user, case insensitively, or nil if there isn't one.
+func (r *Roster) Member(user string) *Member {
+	if r == nil {
+		return nil
+	}
+
+	for _, m := range r.Members {
+		if strings.EqualFold(m.User, user) {
+			return m
+		}
+	}
+	return nil
+}
+
+// Weights returns the weights of members that have one, keyed by lower-cased user.
+func (r *Roster) Weights() map[string]float64 {
+	weights := map[string]float64{}
+	if r == nil {
+		return weights
+	}
+
+	for _, m := range r.Members {
+		if m.Weight != 0 {
+			weights[strings.ToLower(m.User)] = m.Weight
+		}
+	}
+	return weights
+}
+
+// Active is true if user is in the rotation every day from start (inclusive) to stop (exclusive), according to their
+// JoinDate and LeaveDate. Users that aren't members of the roster are always active.
+func (r *Roster) Active(user string, start, stopExclusive time.Time) bool {
+	m := r.Member(user)
+	return m == nil || m.Active(start, stopExclusive)
+}
+
+// Available is true if user can be on duty every day from start (inclusive) to stop (exclusive). Users that aren't
+// members of the roster are always available.
+func (r *Roster) Available(user string, start, stopExclusive time.Time) bool {
+	return r.Unavailable(user, start, stopExclusive) == nil
+}
+
+// Unavailable returns the first of user's unavailable periods that overlaps start (inclusive) to stop (exclusive), or
+// nil if there isn't one.
+func (r *Roster) Unavailable(user string, start, stopExclusive time.Time) *Period {
+	m := r.Member(user)
+	if m == nil {
+		return nil
+	}
+
+	for _, p := range m.Unavailable {
+		if p.Overlaps(start, stopExclusive) {
+			return p
+		}
+	}
+	return nil
+}
+
+// Validate confirms every member has a name, is only listed once, and has a valid weight and periods.
+func (r *Roster) Validate() error {
+	if r == nil {
+		return nil
+	}
+
+	seen := map[string]bool{}
+	for i, m := range r.Members {
+		if m == nil || m.User == "" {
+			return fmt.Errorf("roster member %v has no user", i)
+		}
+
+		user := strings.ToLower(m.User)
+		if seen[user] {
+			return fmt.Errorf("%v is listed in the roster more than once", m.User)
+		}
+		seen[user] = true
+
+		if m.Weight < 0 || m.Weight > 1 {
+			return fmt.Errorf("invalid weight %v for %v. Must be between 0 and 1", m.Weight, m.User)
+		}
+
+		for _, p := range m.Unavailable {
+			if err := p.Validate(); err != nil {
+				return fmt.Errorf("invalid unavailable period for %v: %v", m.User, err)
+			}
+		}
+
+		if !m.JoinDate.IsZero() && !m.LeaveDate.IsZero() && m.LeaveDate.Before(m.JoinDate) {
+			return fmt.Errorf("leave date of %v must not be before their join date", m.User)
+		}
+	}
+	return nil
+}
+
+func (r *Roster) String() string {
+	if r == nil {
+		return ""
+	}
+	b, err := yaml.Marshal(r)
+	if err != nil {
+		return err.Error()
+	}
+	return string(b)
+}
+
+// Active is true if the member is in the rotation every day from start (inclusive) to stop (exclusive).
+func (m *Member) Active(start, stopExclusive time.Time) bool {
+	return (m.JoinDate.IsZero() || !start.Before(m.JoinDate)) &&
+		(m.LeaveDate.IsZero() || !stopExclusive.After(m.LeaveDate.Add(24*time.Hour)))
+}
+
+// Membership describes when the member is in the rotation, like "from Mon 01 Jun 2020 to Sat 15 Aug 2020".
+func (m *Member) Membership() string {
+	switch {
+	case m.JoinDate.IsZero() && m.LeaveDate.IsZero():
+		return "always"
+	case m.LeaveDate.IsZero():
+		return "from " + m.JoinDate.Format(DateFormat)
+	case m.JoinDate.IsZero():
+		return "until " + m.LeaveDate.Format(DateFormat)
+	}
+	return "from " + m.JoinDate.Format(DateFormat) + " to " + m.LeaveDate.Format(DateFormat)
+}
+
+// MarshalJSON returns the join and leave dates in the `RosterDateFormat` format.
+func (m *Member) MarshalJSON() ([]byte, error) {
+	type Alias Member
+	aux := &struct {
+		*Alias
+		JoinDate  string `json:"joinDate,omitempty"`
+		LeaveDate string `json:"leaveDate,omitempty"`
+	}{
+		Alias: (*Alias)(m),
+	}
+
+	if !m.JoinDate.IsZero() {
+		aux.JoinDate = m.JoinDate.Format(RosterDateFormat)
+	}
+	if !m.LeaveDate.IsZero() {
+		aux.LeaveDate = m.LeaveDate.Format(RosterDateFormat)
+	}
+	return json.Marshal(aux)
+}
+
+// UnmarshalJSON reads the join and leave dates in the `RosterDateFormat` format, and will throw parsing error otherwise.
+func (m *Member) UnmarshalJSON(data []byte) error {
+	type Alias Member
+	aux := &struct {
+		*Alias
+		JoinDate  string `json:"joinDate,omitempty"`
+		LeaveDate string `json:"leaveDate,omitempty"`
+	}{
+		Alias: (*Alias)(m),
+	}
+	if err := json.Unmarshal(data, &aux); err != nil {
+		return err
+	}
+
+	var err error
+	if aux.JoinDate != "" {
+		if m.JoinDate, err = time.Parse(RosterDateFormat, aux.JoinDate); err != nil {
+			return fmt.Errorf("error parsing join date: %v", err)
+		}
+	}
+	if aux.LeaveDate != "" {
+		if m.LeaveDate, err = time.Parse(RosterDateFormat, aux.LeaveDate); err != nil {
+			return fmt.Errorf("error parsing leave date: %v", err)
+		}
+	}
+	return nil
+}
+
+// StopDate returns the inclusive stop date of the period.
+func (p *Period) StopDate() time.Time {
+	if p.Stop.IsZero() {
+		return p.Start
+	}
+	return p.Stop
+}
+
+// Overlaps is true if any day of the period falls from start (inclusive) to stop (exclusive), even partly, for times
+// that aren't whole days.
+func (p *Period) Overlaps(start, stopExclusive time.Time) bool {
+	return p.Start.Before(stopExclusive) && p.StopDate().AddDate(0, 0, 1).After(start)
+}
+
+func (p *Period) Validate() error {
+	if p == nil {
+		return fmt.Errorf("period cannot be nil")
+	}
+
+	if p.Start.IsZero() {
+		return fmt.Errorf("start date cannot be zero value")
+	}
+
+	if p.StopDate().Before(p.Start) {
+		return fmt.Errorf("start date must be before stop date")
+	}
+	return nil
+}
+
+func (p *Period) String() string {
+	if p.Stop.IsZero() || p.Stop.Equal(p.Start) {
+		return p.Start.Format(DateFormat)
+	}
+	return p.Start.Format(DateFormat) + " to " + p.Stop.Format(DateFormat)
+}
+
+// MarshalJSON returns dates in the `RosterDateFormat` format.
+func (p *Period) MarshalJSON() ([]byte, error) {
+	aux := struct {
+		Start string `json:"start"`
+		Stop  string `json:"stop,omitempty"`
+	}{
+		Start: p.Start.Format(RosterDateFormat),
+	}
+
+	if !p.Stop.IsZero() {
+		aux.Stop = p.Stop.Format(RosterDateFormat)
+	}
+
+	return json.Marshal(aux)
+}
+
+// UnmarshalJSON reads dates in the `RosterDateFormat` format, and will throw parsing error otherwise.
+func (p *Period) UnmarshalJSON(data []byte) error {
+	aux := struct {
+		Start string `json:"start"`
+		Stop  string `json:"stop,omitempty"`
+	}{}
+	if err := json.Unmarshal(data, &aux); err != nil {
+		return err
+	}
+
+	var err error
+	if p.Start, err = time.Parse(RosterDateFormat, aux.Start); err != nil {
+		return fmt.Errorf("error parsing start date: %v", err)
+	}
+	if aux.Stop != "" {
+		if p.Stop, err = time.Parse(RosterDateFormat, aux.Stop); err != nil {
+			return fmt.Errorf("error parsing stop date: %v", err)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,316 @@
+package schedule
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/ghodss/yaml"
+)
+
+func TestUnmarshalRoster(t *testing.T) {
+	for _, tc := range []struct {
+		desc    string
+		roster  string
+		wantErr bool
+		want    *Roster
+	}{
+		{
+			desc: "valid",
+			roster: `members:
+- user: abc
+  unavailable:
+  - start: 2020-06-01
+    stop: 2020-06-07
+  - start: "2020-07-03"
+- user: xyz
+  weight: 0.5
+  joinDate: 2020-06-01
+  leaveDate: "2020-08-15"
+`,
+			want: &Roster{
+				Members: []*Member{
+					{
+						User: "abc",
+						Unavailable: []*Period{
+							{
+								Start: time.Date(2020, 6, 1, 0, 0, 0, 0, time.UTC),
+								Stop:  time.Date(2020, 6, 7, 0, 0, 0, 0, time.UTC),
+							},
+							{
+								Start: time.Date(2020, 7, 3, 0, 0, 0, 0, time.UTC),
+							},
+						},
+					},
+					{
+						User:      "xyz",
+						Weight:    0.5,
+						JoinDate:  time.Date(2020, 6, 1, 0, 0, 0, 0, time.UTC),
+						LeaveDate: time.Date(2020, 8, 15, 0, 0, 0, 0, time.UTC),
+					},
+				},
+			},
+		},
+		{
+			desc: "invalid start date",
+			roster: `members:
+- user: abc
+  unavailable:
+  - start: Mon 01 Jun 2020
+`,
+			wantErr: true,
+		},
+		{
+			desc: "invalid join date",
+			roster: `members:
+- user: abc
+  joinDate: 06/01/2020
+`,
+			wantErr: true,
+		},
+		{
+			desc: "invalid stop date",
+			roster: `members:
+- user: abc
+  unavailable:
+  - start: 2020-06-01
+    stop: 2020-06-31
+`,
+			wantErr: true,
+		},
+	} {
+		t.Run(tc.desc, func(t *testing.T) {
+			got := &Roster{}
+			err := yaml.Unmarshal([]byte(tc.roster), got)
+			if tc.wantErr {
+				if err == nil {
+					t.Errorf("want error and didn't get one.")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			if !reflect.DeepEqual(tc.want, got) {
+				t.Errorf("want:\n%v\n\ngot:\n%v", tc.want, got)
+			}
+
+			b, err := yaml.Marshal(got)
+			if err != nil {
+				t.Fatalf("error marshalling roster: %v", err)
+			}
+			roundTrip := &Roster{}
+			if err := yaml.Unmarshal(b, roundTrip); err != nil {
+				t.Fatalf("error unmarshalling marshalled roster: %v", err)
+			}
+			if !reflect.DeepEqual(tc.want, roundTrip) {
+				t.Errorf("round trip roster differs. want:\n%v\n\ngot:\n%v", tc.want, roundTrip)
+			}
+		})
+	}
+}
+
+func TestRosterValidate(t *testing.T) {
+	for _, tc := range []struct {
+		desc    string
+		roster  *Roster
+		wantErr bool
+	}{
+		{
+			desc: "nil roster",
+		},
+		{
+			desc: "valid",
+			roster: &Roster{Members: []*Member{
+				{User: "abc", Unavailable: []*Period{{Start: time.Date(2020, 6, 1, 0, 0, 0, 0, time.UTC)}}},
+				{User: "xyz"},
+			}},
+		},
+		{
+			desc:    "missing user",
+			roster:  &Roster{Members: []*Member{{}}},
+			wantErr: true,
+		},
+		{
+			desc:    "duplicate user",
+			roster:  &Roster{Members: []*Member{{User: "abc"}, {User: "ABC"}}},
+			wantErr: true,
+		},
+		{
+			desc:    "weight too large",
+			roster:  &Roster{Members: []*Member{{User: "abc", Weight: 1.5}}},
+			wantErr: true,
+		},
+		{
+			desc:    "negative weight",
+			roster:  &Roster{Members: []*Member{{User: "abc", Weight: -0.5}}},
+			wantErr: true,
+		},
+		{
+			desc: "missing start",
+			roster: &Roster{Members: []*Member{
+				{User: "abc", Unavailable: []*Period{{Stop: time.Date(2020, 6, 1, 0, 0, 0, 0, time.UTC)}}},
+			}},
+			wantErr: true,
+		},
+		{
+			desc: "stop before start",
+			roster: &Roster{Members: []*Member{
+				{User: "abc", Unavailable: []*Period{{
+					Start: time.Date(2020, 6, 2, 0, 0, 0, 0, time.UTC),
+					Stop:  time.Date(2020, 6, 1, 0, 0, 0, 0, time.UTC),
+				}}},
+			}},
+			wantErr: true,
+		},
+		{
+			desc: "leave date before join date",
+			roster: &Roster{Members: []*Member{{
+				User:      "abc",
+				JoinDate:  time.Date(2020, 6, 2, 0, 0, 0, 0, time.UTC),
+				LeaveDate: time.Date(2020, 6, 1, 0, 0, 0, 0, time.UTC),
+			}}},
+			wantErr: true,
+		},
+	} {
+		t.Run(tc.desc, func(t *testing.T) {
+			err := tc.roster.Validate()
+			if tc.wantErr && err == nil {
+				t.Errorf("err expected and not received.")
+			} else if !tc.wantErr && err != nil {
+				t.Errorf("got unexpected error: %v", err)
+			}
+		})
+	}
+}
+
+func TestRosterWeights(t *testing.T) {
+	roster := &Roster{Members: []*Member{{User: "ABC", Weight: 0.5}, {User: "xyz"}}}
+
+	want := map[string]float64{"abc": 0.5}
+	if got := roster.Weights(); !reflect.DeepEqual(want, got) {
+		t.Errorf("want %v, got %v", want, got)
+	}
+
+	var nilRoster *Roster
+	if got := nilRoster.Weights(); len(got) != 0 {
+		t.Errorf("want no weights from nil roster, got %v", got)
+	}
+}
+
+func TestRosterAvailable(t *testing.T) {
+	roster := &Roster{Members: []*Member{
+		{User: "abc", Unavailable: []*Period{
+			{Start: time.Date(2020, 6, 3, 0, 0, 0, 0, time.UTC), Stop: time.Date(2020, 6, 5, 0, 0, 0, 0, time.UTC)},
+			{Start: time.Date(2020, 6, 10, 0, 0, 0, 0, time.UTC)},
+		}},
+	}}
+
+	for _, tc := range []struct {
+		desc        string
+		roster      *Roster
+		user        string
+		start, stop int
+		// hours are added to start, and twice to stop, for times that aren't whole days.
+		hours int
+		want  bool
+	}{
+		{desc: "nil roster", user: "abc", start: 3, stop: 4, want: true},
+		{desc: "not a member", roster: roster, user: "xyz", start: 3, stop: 4, want: true},
+		{desc: "before", roster: roster, user: "abc", start: 1, stop: 3, want: true},
+		{desc: "overlaps start", roster: roster, user: "abc", start: 1, stop: 4},
+		{desc: "overlaps stop", roster: roster, user: "ABC", start: 5, stop: 8},
+		{desc: "after", roster: roster, user: "abc", start: 6, stop: 10, want: true},
+		{desc: "single day", roster: roster, user: "abc", start: 8, stop: 15},
+		{desc: "part of a day", roster: roster, user: "abc", start: 10, stop: 10, hours: 8},
+		{desc: "part of the day after", roster: roster, user: "abc", start: 11, stop: 11, hours: 8, want: true},
+	} {
+		t.Run(tc.desc, func(t *testing.T) {
+			start := time.Date(2020, 6, tc.start, tc.hours, 0, 0, 0, time.UTC)
+			stop := time.Date(2020, 6, tc.stop, 2*tc.hours, 0, 0, 0, time.UTC)
+			if got := tc.roster.Available(tc.user, start, stop); tc.want != got {
+				t.Errorf("want available %v, got %v", tc.want, got)
+			}
+		})
+	}
+}
+
+func TestRosterActive(t *testing.T) {
+	roster := &Roster{Members: []*Member{
+		{User: "abc", JoinDate: time.Date(2020, 6, 3, 0, 0, 0, 0, time.UTC), LeaveDate: time.Date(2020, 6, 10, 0, 0, 0, 0, time.UTC)},
+		{User: "lmn", LeaveDate: time.Date(2020, 6, 10, 0, 0, 0, 0, time.UTC)},
+	}}
+
+	for _, tc := range []struct {
+		desc        string
+		roster      *Roster
+		user        string
+		start, stop int
+		want        bool
+	}{
+		{desc: "nil roster", user: "abc", start: 1, stop: 2, want: true},
+		{desc: "not a member", roster: roster, user: "xyz", start: 1, stop: 2, want: true},
+		{desc: "before joining", roster: roster, user: "abc", start: 1, stop: 3},
+		{desc: "overlaps join date", roster: roster, user: "abc", start: 2, stop: 4},
+		{desc: "from join date to leave date", roster: roster, user: "ABC", start: 3, stop: 11, want: true},
+		{desc: "overlaps leave date", roster: roster, user: "abc", start: 10, stop: 12},
+		{desc: "no join date", roster: roster, user: "lmn", start: 1, stop: 11, want: true},
+		{desc: "after leaving", roster: roster, user: "lmn", start: 11, stop: 12},
+	} {
+		t.Run(tc.desc, func(t *testing.T) {
+			start := time.Date(2020, 6, tc.start, 0, 0, 0, 0, time.UTC)
+			stop := time.Date(2020, 6, tc.stop, 0, 0, 0, 0, time.UTC)
+			if got := tc.roster.Active(tc.user, start, stop); tc.want != got {
+				t.Errorf("want active %v, got %v", tc.want, got)
+			}
+		})
+	}
+}
+
+func TestLint(t *testing.T) {
+	sched := &Schedule{
+		Roster: &Roster{Members: []*Member{
+			{User: "abc", Unavailable: []*Period{{Start: time.Date(2020, 6, 3, 0, 0, 0, 0, time.UTC)}}},
+		}},
+		Shifts: []*Shift{
+			{
+				StartDate: time.Date(2020, 6, 1, 0, 0, 0, 0, time.UTC),
+				User:      "abc",
+			},
+			{
+				StartDate: time.Date(2020, 6, 8, 0, 0, 0, 0, time.UTC),
+				User:      "lmn",
+				Roles:     map[string]*Assignment{"secondary": {User: "xyz"}},
+			},
+			{
+				StartDate:    time.Date(2020, 6, 15, 0, 0, 0, 0, time.UTC),
+				StopDate:     time.Date(2020, 6, 21, 0, 0, 0, 0, time.UTC),
+				User:         "xyz",
+				UserOverride: "lmn",
+			},
+		},
+	}
+
+	roster := &Roster{Members: []*Member{
+		{User: "xyz", Unavailable: []*Period{{
+			Start: time.Date(2020, 6, 12, 0, 0, 0, 0, time.UTC),
+			Stop:  time.Date(2020, 6, 20, 0, 0, 0, 0, time.UTC),
+		}}},
+		{User: "lmn", JoinDate: time.Date(2020, 6, 10, 0, 0, 0, 0, time.UTC)},
+	}}
+
+	want := []string{
+		"shift starting Mon 01 Jun 2020: abc is on duty as primary, but unavailable Wed 03 Jun 2020",
+		"shift starting Mon 08 Jun 2020: lmn is on duty as primary, but only in the rotation from Wed 10 Jun 2020",
+		"shift starting Mon 08 Jun 2020: xyz is on duty as secondary, but unavailable Fri 12 Jun 2020 to Sat 20 Jun 2020",
+	}
+
+	var got []string
+	for _, p := range sched.Lint(roster, nil, nil, nil) {
+		got = append(got, p.String())
+	}
+	if !reflect.DeepEqual(want, got) {
+		t.Errorf("want problems:\n%q\n\ngot:\n%q", want, got)
+	}
+}
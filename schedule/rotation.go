@@ -0,0 +1,94 @@
+package schedule
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/spinnaker/rotation-scheduler/users"
+)
+
+// Rotation is where the rotation of each role left off at the end of a schedule, so extending it continues the exact
+// order, rather than working it out again from the shifts.
+type Rotation struct {
+	// GeneratedAt is when the schedule was last generated or extended.
+	GeneratedAt time.Time `json:"generatedAt"`
+
+	// ShiftDurationDays is the duration of the schedule's shifts, unless they follow a ShiftPattern.
+	ShiftDurationDays int `json:"shiftDurationDays,omitempty"`
+
+	// ShiftPattern is the pattern of the schedule's shifts, like "months:1", if they follow one.
+	ShiftPattern string `json:"shiftPattern,omitempty"`
+
+	// LastShiftStart is the start date of the last shift when the Cursors were saved. They only apply if it's still the
+	// last shift.
+	LastShiftStart time.Time `json:"-"`
+
+	// Cursors are the positions of each role's rotation, keyed by role name, including the PrimaryRole.
+	Cursors map[string]*users.Cursor `json:"cursors,omitempty"`
+}
+
+// Cursors returns the Rotation's Cursors if sch still ends with the shift they were saved after, or nil if it doesn't.
+func (sch *Schedule) Cursors() map[string]*users.Cursor {
+	if sch.Rotation == nil || sch.LastShift() == nil || !sch.Rotation.LastShiftStart.Equal(sch.LastShift().StartDate) {
+		return nil
+	}
+	return sch.Rotation.Cursors
+}
+
+func (r *Rotation) Validate() error {
+	if r == nil {
+		return nil
+	}
+
+	if r.ShiftDurationDays < 0 {
+		return fmt.Errorf("shift duration cannot be negative")
+	}
+
+	for role, c := range r.Cursors {
+		if err := c.Validate(); err != nil {
+			return fmt.Errorf("invalid cursor for the %v role: %v", role, err)
+		}
+	}
+	return nil
+}
+
+// MarshalJSON returns LastShiftStart in the `DateFormat` format, or as an RFC 3339 time if it's the start of a timed
+// shift.
+func (r *Rotation) MarshalJSON() ([]byte, error) {
+	type Alias Rotation
+	start := r.LastShiftStart.Format(DateFormat)
+	if date, err := time.Parse(DateFormat, start); err != nil || !date.Equal(r.LastShiftStart) {
+		start = r.LastShiftStart.Format(time.RFC3339)
+	}
+	return json.Marshal(&struct {
+		*Alias
+		LastShiftStart string `json:"lastShiftStart"`
+	}{
+		Alias:          (*Alias)(r),
+		LastShiftStart: start,
+	})
+}
+
+// UnmarshalJSON reads LastShiftStart in the `DateFormat` format, or as an RFC 3339 time, and will throw parsing error
+// otherwise.
+func (r *Rotation) UnmarshalJSON(data []byte) error {
+	type Alias Rotation
+	aux := &struct {
+		*Alias
+		LastShiftStart string `json:"lastShiftStart"`
+	}{
+		Alias: (*Alias)(r),
+	}
+	if err := json.Unmarshal(data, &aux); err != nil {
+		return err
+	}
+
+	var err error
+	if r.LastShiftStart, err = time.Parse(DateFormat, aux.LastShiftStart); err != nil {
+		if r.LastShiftStart, err = time.Parse(time.RFC3339, aux.LastShiftStart); err != nil {
+			return fmt.Errorf("error parsing last shift start date: %v", err)
+		}
+	}
+	return nil
+}
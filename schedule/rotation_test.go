@@ -0,0 +1,96 @@
+package schedule
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/ghodss/yaml"
+	"github.com/spinnaker/rotation-scheduler/users"
+)
+
+func TestUnmarshalRotation(t *testing.T) {
+	for _, tc := range []struct {
+		desc     string
+		rotation string
+		wantErr  bool
+		want     *Rotation
+	}{
+		{
+			desc: "valid",
+			rotation: `cursors:
+  primary:
+    credits:
+      abc: 0.5
+    cycles: 3
+    next: 1
+    users:
+    - xyz
+    - abc
+generatedAt: "2020-01-01T12:00:00Z"
+lastShiftStart: Mon 06 Jan 2020
+shiftDurationDays: 7
+`,
+			want: &Rotation{
+				GeneratedAt:       time.Date(2020, 1, 1, 12, 0, 0, 0, time.UTC),
+				ShiftDurationDays: 7,
+				LastShiftStart:    time.Date(2020, 1, 6, 0, 0, 0, 0, time.UTC),
+				Cursors: map[string]*users.Cursor{
+					PrimaryRole: {Users: []string{"xyz", "abc"}, Next: 1, Cycles: 3, Credits: map[string]float64{"abc": 0.5}},
+				},
+			},
+		},
+		{
+			desc: "invalid last shift start",
+			rotation: `generatedAt: "2020-01-01T12:00:00Z"
+lastShiftStart: 2020-01-06
+`,
+			wantErr: true,
+		},
+	} {
+		t.Run(tc.desc, func(t *testing.T) {
+			got := &Rotation{}
+			err := yaml.Unmarshal([]byte(tc.rotation), got)
+			if tc.wantErr {
+				if err == nil {
+					t.Errorf("want error and didn't get one.")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			if !reflect.DeepEqual(tc.want, got) {
+				t.Errorf("want:\n%+v\n\ngot:\n%+v", tc.want, got)
+			}
+
+			b, err := yaml.Marshal(got)
+			if err != nil {
+				t.Fatalf("error marshalling rotation: %v", err)
+			}
+			if tc.rotation != string(b) {
+				t.Errorf("round trip rotation differs. want:\n%v\n\ngot:\n%v", tc.rotation, string(b))
+			}
+		})
+	}
+}
+
+func TestScheduleCursors(t *testing.T) {
+	cursors := map[string]*users.Cursor{PrimaryRole: {Users: []string{"abc"}}}
+	sched := &Schedule{
+		Rotation: &Rotation{
+			LastShiftStart: time.Date(2020, 1, 6, 0, 0, 0, 0, time.UTC),
+			Cursors:        cursors,
+		},
+		Shifts: []*Shift{{StartDate: time.Date(2020, 1, 6, 0, 0, 0, 0, time.UTC)}},
+	}
+	if got := sched.Cursors(); !reflect.DeepEqual(cursors, got) {
+		t.Errorf("want cursors %v, got %v", cursors, got)
+	}
+
+	sched.Shifts = append(sched.Shifts, &Shift{StartDate: time.Date(2020, 1, 13, 0, 0, 0, 0, time.UTC)})
+	if got := sched.Cursors(); got != nil {
+		t.Errorf("want no cursors once the last shift changes, got %v", got)
+	}
+}
@@ -0,0 +1,164 @@
+package schedule
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Rule is a declarative constraint on who's on duty, beyond taking turns, like two users never being on duty in
+// consecutive shifts. Exactly one of NotConsecutive, AtLeastOne, Unavailable, or MaxHolidayShifts is set.
+type Rule struct {
+	// Name identifies the rule in errors, like "senior on duty".
+	Name string `json:"name"`
+
+	// Users are the users the rule is about. They can only be left out for MaxHolidayShifts, to mean everyone.
+	Users []string `json:"users,omitempty"`
+
+	// NotConsecutive keeps each of the Users off duty in the shift before or after one another of them is on duty in.
+	NotConsecutive bool `json:"notConsecutive,omitempty"`
+
+	// AtLeastOne keeps at least one of the Users on duty in every shift, in any role.
+	AtLeastOne bool `json:"atLeastOne,omitempty"`
+
+	// Unavailable keeps the Users off duty in shifts overlapping any of the periods, like a team's release week.
+	Unavailable []*Period `json:"unavailable,omitempty"`
+
+	// MaxHolidayShifts is the most shifts containing a holiday each of the Users is on duty in, in any role, per
+	// calendar year of the shifts' start dates. Zero keeps them off duty on holidays.
+	MaxHolidayShifts *int `json:"maxHolidayShifts,omitempty"`
+}
+
+// Applies is true if the rule is about user.
+func (r *Rule) Applies(user string) bool {
+	if len(r.Users) == 0 {
+		return true
+	}
+	for _, u := range r.Users {
+		if strings.EqualFold(u, user) {
+			return true
+		}
+	}
+	return false
+}
+
+// Validate confirms the rule has a name and exactly one kind of constraint, with the users it needs.
+func (r *Rule) Validate() error {
+	if r == nil {
+		return fmt.Errorf("rule cannot be nil")
+	}
+
+	if r.Name == "" {
+		return fmt.Errorf("rule must have a name")
+	}
+
+	kinds := 0
+	for _, set := range []bool{r.NotConsecutive, r.AtLeastOne, len(r.Unavailable) != 0, r.MaxHolidayShifts != nil} {
+		if set {
+			kinds++
+		}
+	}
+	if kinds != 1 {
+		return fmt.Errorf("rule %q must have exactly one of notConsecutive, atLeastOne, unavailable, or maxHolidayShifts", r.Name)
+	}
+
+	for _, u := range r.Users {
+		if u == "" {
+			return fmt.Errorf("rule %q has an empty user", r.Name)
+		}
+	}
+
+	switch {
+	case r.NotConsecutive && len(r.Users) < 2:
+		return fmt.Errorf("rule %q must have at least 2 users", r.Name)
+	case (r.AtLeastOne || len(r.Unavailable) != 0) && len(r.Users) == 0:
+		return fmt.Errorf("rule %q must have users", r.Name)
+	case r.MaxHolidayShifts != nil && *r.MaxHolidayShifts < 0:
+		return fmt.Errorf("maxHolidayShifts of rule %q cannot be negative", r.Name)
+	}
+
+	for _, p := range r.Unavailable {
+		if err := p.Validate(); err != nil {
+			return fmt.Errorf("invalid unavailable period for rule %q: %v", r.Name, err)
+		}
+	}
+	return nil
+}
+
+// String describes the rule, like `"senior on duty": at least one of abc, lmn on duty in every shift`.
+func (r *Rule) String() string {
+	users := strings.Join(r.Users, ", ")
+	var desc string
+	switch {
+	case r.NotConsecutive:
+		desc = fmt.Sprintf("%v never on duty in consecutive shifts", users)
+	case r.AtLeastOne:
+		desc = fmt.Sprintf("at least one of %v on duty in every shift", users)
+	case len(r.Unavailable) != 0:
+		var periods []string
+		for _, p := range r.Unavailable {
+			periods = append(periods, p.String())
+		}
+		desc = fmt.Sprintf("%v unavailable %v", users, strings.Join(periods, ", "))
+	default:
+		if users == "" {
+			users = "everyone"
+		}
+		desc = fmt.Sprintf("%v on duty in at most %v shift(s) containing a holiday per year", users, *r.MaxHolidayShifts)
+	}
+	return fmt.Sprintf("%q: %v", r.Name, desc)
+}
+
+// Violations describes each way the shift at index i of sch breaks the rule, counting holidays for MaxHolidayShifts.
+// Shifts are only compared with the shift before them for NotConsecutive, so each pair is described once.
+func (r *Rule) Violations(sch *Schedule, i int, holidays []*Holiday) []string {
+	shift := sch.Shifts[i]
+	_, stopExcl := sch.ShiftStopDates(i)
+	if r.AtLeastOne {
+		for _, u := range shift.Users() {
+			if r.Applies(u) {
+				return nil
+			}
+		}
+		return []string{fmt.Sprintf("none of %v is on duty, breaking rule %v", strings.Join(r.Users, ", "), r)}
+	}
+
+	var violations []string
+	for _, user := range shift.Users() {
+		if !r.Applies(user) {
+			continue
+		}
+
+		switch {
+		case r.NotConsecutive:
+			if i == 0 {
+				continue
+			}
+			for _, u := range sch.Shifts[i-1].Users() {
+				if !strings.EqualFold(u, user) && r.Applies(u) {
+					violations = append(violations, fmt.Sprintf("%v is on duty in the shift after %v, breaking rule %v", user, u, r))
+				}
+			}
+		case len(r.Unavailable) != 0:
+			for _, p := range r.Unavailable {
+				if p.Overlaps(shift.StartDate, stopExcl) {
+					violations = append(violations, fmt.Sprintf("%v is on duty, but unavailable %v, breaking rule %v", user, p, r))
+					break
+				}
+			}
+		case CountHolidays(holidays, shift.StartDate, stopExcl) > 0:
+			n := 0
+			for j := 0; j <= i; j++ {
+				_, stop := sch.ShiftStopDates(j)
+				if sch.Shifts[j].StartDate.Year() == shift.StartDate.Year() && sch.Shifts[j].OnDuty(user) &&
+					CountHolidays(holidays, sch.Shifts[j].StartDate, stop) > 0 {
+					n++
+				}
+			}
+			if n > *r.MaxHolidayShifts {
+				violations = append(violations, fmt.Sprintf("%v is on duty in %v shift(s) containing a holiday in %v, breaking rule %v",
+					user, n, shift.StartDate.Year(), r))
+			}
+		}
+	}
+	return violations
+}
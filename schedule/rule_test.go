@@ -0,0 +1,148 @@
+package schedule
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/ghodss/yaml"
+)
+
+func limit(n int) *int {
+	return &n
+}
+
+func TestUnmarshalRules(t *testing.T) {
+	in := `
+- name: apart
+  users: [abc, lmn]
+  notConsecutive: true
+- name: release week
+  users: [xyz]
+  unavailable:
+  - start: 2020-03-09
+    stop: 2020-03-13
+- name: holidays
+  maxHolidayShifts: 2
+- name: never on holidays
+  users: [abc]
+  maxHolidayShifts: 0
+`
+	want := []*Rule{
+		{Name: "apart", Users: []string{"abc", "lmn"}, NotConsecutive: true},
+		{Name: "release week", Users: []string{"xyz"}, Unavailable: []*Period{{
+			Start: time.Date(2020, 3, 9, 0, 0, 0, 0, time.UTC),
+			Stop:  time.Date(2020, 3, 13, 0, 0, 0, 0, time.UTC),
+		}}},
+		{Name: "holidays", MaxHolidayShifts: limit(2)},
+		{Name: "never on holidays", Users: []string{"abc"}, MaxHolidayShifts: limit(0)},
+	}
+
+	var got []*Rule
+	if err := yaml.Unmarshal([]byte(in), &got); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !reflect.DeepEqual(want, got) {
+		t.Errorf("want %v, got %v", want, got)
+	}
+
+	for _, r := range got {
+		if err := r.Validate(); err != nil {
+			t.Errorf("unexpected error validating %v: %v", r, err)
+		}
+	}
+}
+
+func TestRuleValidate(t *testing.T) {
+	week := []*Period{{Start: time.Date(2020, 3, 9, 0, 0, 0, 0, time.UTC)}}
+
+	for _, tc := range []struct {
+		desc string
+		rule *Rule
+	}{
+		{desc: "nil", rule: nil},
+		{desc: "no name", rule: &Rule{Users: []string{"abc"}, AtLeastOne: true}},
+		{desc: "no kind", rule: &Rule{Name: "r", Users: []string{"abc"}}},
+		{desc: "two kinds", rule: &Rule{Name: "r", Users: []string{"abc", "lmn"}, AtLeastOne: true, NotConsecutive: true}},
+		{desc: "not consecutive with one user", rule: &Rule{Name: "r", Users: []string{"abc"}, NotConsecutive: true}},
+		{desc: "at least one of nobody", rule: &Rule{Name: "r", AtLeastOne: true}},
+		{desc: "nobody unavailable", rule: &Rule{Name: "r", Unavailable: week}},
+		{desc: "empty user", rule: &Rule{Name: "r", Users: []string{""}, Unavailable: week}},
+		{desc: "invalid period", rule: &Rule{Name: "r", Users: []string{"abc"}, Unavailable: []*Period{{}}}},
+		{desc: "negative max holiday shifts", rule: &Rule{Name: "r", MaxHolidayShifts: limit(-1)}},
+	} {
+		t.Run(tc.desc, func(t *testing.T) {
+			if err := tc.rule.Validate(); err == nil {
+				t.Errorf("want error and didn't get one.")
+			}
+		})
+	}
+}
+
+func TestRuleString(t *testing.T) {
+	for _, tc := range []struct {
+		rule *Rule
+		want string
+	}{
+		{
+			rule: &Rule{Name: "apart", Users: []string{"abc", "lmn"}, NotConsecutive: true},
+			want: `"apart": abc, lmn never on duty in consecutive shifts`,
+		},
+		{
+			rule: &Rule{Name: "senior on duty", Users: []string{"abc", "lmn"}, AtLeastOne: true},
+			want: `"senior on duty": at least one of abc, lmn on duty in every shift`,
+		},
+		{
+			rule: &Rule{Name: "release week", Users: []string{"xyz"}, Unavailable: []*Period{{
+				Start: time.Date(2020, 3, 9, 0, 0, 0, 0, time.UTC),
+				Stop:  time.Date(2020, 3, 13, 0, 0, 0, 0, time.UTC),
+			}}},
+			want: `"release week": xyz unavailable Mon 09 Mar 2020 to Fri 13 Mar 2020`,
+		},
+		{
+			rule: &Rule{Name: "holidays", MaxHolidayShifts: limit(2)},
+			want: `"holidays": everyone on duty in at most 2 shift(s) containing a holiday per year`,
+		},
+	} {
+		if got := tc.rule.String(); got != tc.want {
+			t.Errorf("want %v, got %v", tc.want, got)
+		}
+	}
+}
+
+func TestLintRules(t *testing.T) {
+	day := func(d int) time.Time {
+		return time.Date(2020, 6, d, 0, 0, 0, 0, time.UTC)
+	}
+
+	sched := &Schedule{
+		Rules: []*Rule{{Name: "apart", Users: []string{"abc", "lmn"}, NotConsecutive: true}},
+		Shifts: []*Shift{
+			{StartDate: day(1), User: "abc", Roles: map[string]*Assignment{"secondary": {User: "xyz"}}},
+			{StartDate: day(2), User: "lmn", Roles: map[string]*Assignment{"secondary": {User: "abc"}}},
+			{StartDate: day(3), User: "xyz", Roles: map[string]*Assignment{"secondary": {User: "def"}}},
+			{StartDate: day(4), StopDate: day(4), User: "abc"},
+		},
+	}
+	rules := []*Rule{
+		{Name: "senior", Users: []string{"abc", "lmn"}, AtLeastOne: true},
+		{Name: "release", Users: []string{"xyz"}, Unavailable: []*Period{{Start: day(3)}}},
+		{Name: "never on holidays", Users: []string{"abc"}, MaxHolidayShifts: limit(0)},
+	}
+	holidays := []*Holiday{{Date: day(4), Name: "holiday"}}
+
+	want := []string{
+		`shift starting Tue 02 Jun 2020: lmn is on duty in the shift after abc, breaking rule "apart": abc, lmn never on duty in consecutive shifts`,
+		`shift starting Wed 03 Jun 2020: none of abc, lmn is on duty, breaking rule "senior": at least one of abc, lmn on duty in every shift`,
+		`shift starting Wed 03 Jun 2020: xyz is on duty, but unavailable Wed 03 Jun 2020, breaking rule "release": xyz unavailable Wed 03 Jun 2020`,
+		`shift starting Thu 04 Jun 2020: abc is on duty in 1 shift(s) containing a holiday in 2020, breaking rule "never on holidays": abc on duty in at most 0 shift(s) containing a holiday per year`,
+	}
+
+	var got []string
+	for _, p := range sched.Lint(nil, nil, rules, holidays) {
+		got = append(got, p.String())
+	}
+	if !reflect.DeepEqual(want, got) {
+		t.Errorf("want problems:\n%q\n\ngot:\n%q", want, got)
+	}
+}
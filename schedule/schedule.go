@@ -3,6 +3,8 @@ package schedule
 import (
 	"encoding/json"
 	"fmt"
+	"sort"
+	"strings"
 	"time"
 
 	"github.com/ghodss/yaml"
@@ -10,10 +12,48 @@ import (
 
 const (
 	DateFormat = "Mon 02 Jan 2006"
+	// ShiftTimeFormat is the format of the start and stop of timed shifts, in their own time zone.
+	ShiftTimeFormat = "Mon 02 Jan 2006 15:04"
+	// HandoffTimeFormat is the format of Schedule.HandoffTime.
+	HandoffTimeFormat = "15:04"
+
+	// PrimaryRole is the name of the role held by a shift's User. It can't be used for any other role.
+	PrimaryRole = "primary"
+
+	keyFormat      = "20060102"
+	timedKeyFormat = "20060102T1504Z"
 )
 
 // Schedule represents a series of Shifts, in temporal order.
 type Schedule struct {
+	// HandoffTime is the time of day, in the `HandoffTimeFormat` format, that shifts change hands. When empty, shifts
+	// are whole days, and change hands at midnight.
+	HandoffTime string `json:"handoffTime,omitempty"`
+
+	// TimeZone is the IANA time zone, like "America/Los_Angeles", of the HandoffTime. Defaults to UTC.
+	TimeZone string `json:"timeZone,omitempty"`
+
+	// Roster optionally describes the members of the rotation, like when they're unavailable.
+	Roster *Roster `json:"roster,omitempty"`
+
+	// Cooldown is optionally the minimum gap between two shifts of the same user.
+	Cooldown *Cooldown `json:"cooldown,omitempty"`
+
+	// Rules are optional constraints on who's on duty, followed when extending the schedule, and checked by Lint.
+	Rules []*Rule `json:"rules,omitempty"`
+
+	// Regions are the parts of the day of a follow-the-sun schedule, whose shifts are timed, in the order they take
+	// over each day. It's kept up to date by the scheduler.
+	Regions []*Region `json:"regions,omitempty"`
+
+	// HolidayCounts is how many holidays each user has been scheduled to be on duty for as the primary, keyed by
+	// lower-cased user. It's kept up to date by the scheduler, and includes shifts that have since been pruned, so
+	// holidays stay evenly spread from one year to the next.
+	HolidayCounts map[string]int `json:"holidayCounts,omitempty"`
+
+	// Rotation is kept up to date by the scheduler, so extending the schedule continues where it left off.
+	Rotation *Rotation `json:"rotation,omitempty"`
+
 	// Shifts is the list of shifts in order. The last Shift, and only the last Shift, should have a StopTime value.
 	Shifts []*Shift `json:"shifts"`
 }
@@ -35,6 +75,45 @@ func (sch *Schedule) Validate() error {
 		return fmt.Errorf("shifts cannot be empty")
 	}
 
+	if _, err := sch.Handoff(); err != nil {
+		return err
+	}
+
+	if err := sch.Roster.Validate(); err != nil {
+		return fmt.Errorf("invalid roster: %v", err)
+	}
+
+	if err := sch.Cooldown.Validate(); err != nil {
+		return fmt.Errorf("invalid cooldown: %v", err)
+	}
+
+	for _, r := range sch.Rules {
+		if err := r.Validate(); err != nil {
+			return fmt.Errorf("invalid rule: %v", err)
+		}
+	}
+
+	regions := map[string]bool{}
+	for _, r := range sch.Regions {
+		if err := r.Validate(); err != nil {
+			return err
+		}
+		if regions[r.Name] {
+			return fmt.Errorf("region %v is listed more than once", r.Name)
+		}
+		regions[r.Name] = true
+	}
+
+	for user, count := range sch.HolidayCounts {
+		if count < 0 {
+			return fmt.Errorf("holiday count for %v cannot be negative", user)
+		}
+	}
+
+	if err := sch.Rotation.Validate(); err != nil {
+		return fmt.Errorf("invalid rotation: %v", err)
+	}
+
 	var previousStartDate time.Time
 	for i, shift := range sch.Shifts {
 		if err := shift.Validate(); err != nil {
@@ -61,6 +140,111 @@ func (sch *Schedule) Validate() error {
 	return nil // It's all good.
 }
 
+// ShiftStopDates returns the inclusive and exclusive stop dates of the shift at index i. Only the last shift has an
+// explicit stop date; every other shift stops the day before the next shift starts, or, if the next shift is timed,
+// when it starts, which is both its inclusive and exclusive stop.
+func (sch *Schedule) ShiftStopDates(i int) (inclusive, exclusive time.Time) {
+	shift := sch.Shifts[i]
+	if shift == sch.LastShift() {
+		return shift.StopDate, shift.StopDateExclusive()
+	}
+	nextShift := sch.Shifts[i+1]
+	return nextShift.StartDateExclusive(), nextShift.StartDate
+}
+
+// Lock sets Locked to locked on every shift that overlaps start to stopInclusive, and returns how many shifts changed.
+func (sch *Schedule) Lock(start, stopInclusive time.Time, locked bool) int {
+	changed := 0
+	for i, shift := range sch.Shifts {
+		_, stopExcl := sch.ShiftStopDates(i)
+		if !shift.StartDate.Before(stopInclusive.AddDate(0, 0, 1)) || !stopExcl.After(start) || shift.Locked == locked {
+			continue
+		}
+		shift.Locked = locked
+		changed++
+	}
+	return changed
+}
+
+// Lint finds shifts that don't make a valid schedule invalid, but likely need someone's attention, like a user on duty
+// while they're unavailable, or not in the rotation, according to the schedule's Roster or roster, too soon after
+// their previous shift, according to the schedule's Cooldown or cooldown, or breaking any of the schedule's Rules or
+// rules, counting holidays for maxHolidayShifts. roster and cooldown may be nil.
+func (sch *Schedule) Lint(roster *Roster, cooldown *Cooldown, rules []*Rule, holidays []*Holiday) []*Problem {
+	var problems []*Problem
+	for i, shift := range sch.Shifts {
+		_, stopExcl := sch.ShiftStopDates(i)
+
+		roles := append([]string{PrimaryRole}, shift.RoleNames()...)
+		for j, user := range shift.Users() {
+			for _, r := range []*Roster{sch.Roster, roster} {
+				if !r.Active(user, shift.StartDate, stopExcl) {
+					problems = append(problems, &Problem{
+						Shift:   shift,
+						Message: fmt.Sprintf("%v is on duty as %v, but only in the rotation %v", user, roles[j], r.Member(user).Membership()),
+					})
+					break
+				}
+				if p := r.Unavailable(user, shift.StartDate, stopExcl); p != nil {
+					problems = append(problems, &Problem{
+						Shift:   shift,
+						Message: fmt.Sprintf("%v is on duty as %v, but unavailable %v", user, roles[j], p),
+					})
+					break
+				}
+			}
+
+			if prev := sch.PreviousShiftOf(user, i); prev != -1 {
+				for _, c := range []*Cooldown{sch.Cooldown, cooldown} {
+					if c.TooSoon(sch, prev, i, shift.StartDate) {
+						problems = append(problems, &Problem{
+							Shift: shift,
+							Message: fmt.Sprintf("%v is on duty as %v, but was on duty in the shift starting %v, within the cooldown of %v",
+								user, roles[j], sch.Shifts[prev].FormatStart(), c),
+						})
+						break
+					}
+				}
+			}
+		}
+
+		for _, rule := range append(append([]*Rule{}, sch.Rules...), rules...) {
+			for _, v := range rule.Violations(sch, i, holidays) {
+				problems = append(problems, &Problem{Shift: shift, Message: v})
+			}
+		}
+	}
+	return problems
+}
+
+// Handoff parses the HandoffTime and TimeZone.
+func (sch *Schedule) Handoff() (*Handoff, error) {
+	h := &Handoff{
+		Location: time.UTC,
+	}
+
+	if sch.TimeZone != "" {
+		loc, err := time.LoadLocation(sch.TimeZone)
+		if err != nil {
+			return nil, fmt.Errorf("invalid time zone(%v): %v", sch.TimeZone, err)
+		}
+		h.Location = loc
+	}
+
+	if sch.HandoffTime != "" {
+		t, err := time.Parse(HandoffTimeFormat, sch.HandoffTime)
+		if err != nil {
+			return nil, fmt.Errorf("invalid handoff time(%v). Must be in the format %v: %v", sch.HandoffTime,
+				HandoffTimeFormat, err)
+		}
+		h.Timed = true
+		h.Hour = t.Hour()
+		h.Minute = t.Minute()
+	}
+
+	return h, nil
+}
+
 func (sch *Schedule) String() string {
 	if sch == nil {
 		return ""
@@ -72,6 +256,43 @@ func (sch *Schedule) String() string {
 	return string(b)
 }
 
+// Problem is something about a shift found by Lint.
+type Problem struct {
+	Shift   *Shift
+	Message string
+}
+
+func (p *Problem) String() string {
+	return fmt.Sprintf("shift starting %v: %v", p.Shift.FormatStart(), p.Message)
+}
+
+// Handoff is when shifts change hands.
+type Handoff struct {
+	// Timed is false for schedules of whole-day shifts, which change hands at midnight.
+	Timed bool
+
+	// Hour and Minute are the time of day shifts change hands, in Location.
+	Hour, Minute int
+
+	Location *time.Location
+}
+
+// At returns the time that shifts change hands on date. Only the year, month and day of date are relevant.
+func (h *Handoff) At(date time.Time) time.Time {
+	return time.Date(date.Year(), date.Month(), date.Day(), h.Hour, h.Minute, 0, 0, h.Location)
+}
+
+// Date returns the date of the shift day that t falls in, as a UTC date like Shift.StartDate. Before the handoff time,
+// that's the previous day.
+func (h *Handoff) Date(t time.Time) time.Time {
+	local := t.In(h.Location)
+	date := time.Date(local.Year(), local.Month(), local.Day(), 0, 0, 0, 0, time.UTC)
+	if local.Before(h.At(local)) {
+		date = date.AddDate(0, 0, -1)
+	}
+	return date
+}
+
 // A shift represents a span of time someone is on duty.
 type Shift struct {
 	// The start date, inclusive, of when the User goes on duty. Only year, month, and day field are relevant.
@@ -85,6 +306,38 @@ type Shift struct {
 	// StopDate is inclusive, and must only be used on the last Shift of a Schedule. For all other Shifts, the stop date
 	// is implied by the next shift's StartDate, and this value should remain the zero `time.Time` value.
 	StopDate time.Time `json:"stopDate,omitempty"`
+
+	// Type is the kind of shift, like "weekend", when a schedule interleaves shifts whose Users take turns in separate
+	// rotations. It's empty for shifts of the default rotation.
+	Type string `json:"type,omitempty"`
+
+	// Roles are any additional people on duty alongside the User, who holds the PrimaryRole, keyed by role name, like
+	// "secondary" or "shadow".
+	Roles map[string]*Assignment `json:"roles,omitempty"`
+
+	// Locked shifts are promised to their users, so the scheduler never changes them, even when pruning or rescheduling
+	// the shifts around them.
+	Locked bool `json:"locked,omitempty"`
+
+	// TimeZone is the IANA time zone of a timed shift, like a region's part of the day in a follow-the-sun schedule.
+	// The StartDate of a timed shift is the time it starts, and its StopDate the time it stops, exclusive, both in the
+	// `ShiftTimeFormat` format in the time zone. It's empty for shifts of whole days.
+	TimeZone string `json:"timeZone,omitempty"`
+}
+
+// Assignment is a user holding an additional role during a shift.
+type Assignment struct {
+	User string `json:"user,omitempty"`
+
+	// UserOverride works like Shift.UserOverride, for just this role.
+	UserOverride string `json:"userOverride,omitempty"`
+}
+
+func (a *Assignment) GetUser() string {
+	if a.UserOverride != "" {
+		return a.UserOverride
+	}
+	return a.User
 }
 
 func (sh *Shift) GetUser() string {
@@ -94,9 +347,71 @@ func (sh *Shift) GetUser() string {
 	return sh.User
 }
 
-// StartDateExclusive returns the date before the start date, which is the StopDateInclusive of the previous shift.
+// RoleNames returns the names of the shift's additional roles, sorted.
+func (sh *Shift) RoleNames() []string {
+	names := make([]string, 0, len(sh.Roles))
+	for name := range sh.Roles {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Users returns everyone on duty during the shift: the user holding the PrimaryRole, followed by the users of any
+// additional roles, sorted by role name.
+func (sh *Shift) Users() []string {
+	users := []string{sh.GetUser()}
+	for _, name := range sh.RoleNames() {
+		users = append(users, sh.Roles[name].GetUser())
+	}
+	return users
+}
+
+// OnDuty is true if user, case insensitively, is on duty during the shift, in any role.
+func (sh *Shift) OnDuty(user string) bool {
+	for _, u := range sh.Users() {
+		if strings.EqualFold(u, user) {
+			return true
+		}
+	}
+	return false
+}
+
+// Key identifies this shift within its Schedule. It stays the same as long as the StartDate does, so external systems
+// can use it to recognize a shift they've seen before, even if its user has changed.
+func (sh *Shift) Key() string {
+	if sh.Timed() {
+		return sh.StartDate.UTC().Format(timedKeyFormat)
+	}
+	return sh.StartDate.Format(keyFormat)
+}
+
+// Timed is true for shifts with a TimeZone, which start and stop at a time of day, rather than on whole days.
+func (sh *Shift) Timed() bool {
+	return sh.TimeZone != ""
+}
+
+// Location loads the TimeZone of a timed shift. Shifts of whole days are in UTC.
+func (sh *Shift) Location() (*time.Location, error) {
+	if !sh.Timed() {
+		return time.UTC, nil
+	}
+	return time.LoadLocation(sh.TimeZone)
+}
+
+// FormatStart formats the start of the shift, like "Mon 02 Mar 2020", or "Mon 02 Mar 2020 09:00 +08" for timed shifts.
+func (sh *Shift) FormatStart() string {
+	loc, err := sh.Location()
+	if !sh.Timed() || err != nil {
+		return sh.StartDate.Format(DateFormat)
+	}
+	return sh.StartDate.In(loc).Format(ShiftTimeFormat + " MST")
+}
+
+// StartDateExclusive returns the date before the start date, which is the StopDateInclusive of the previous shift. For
+// timed shifts, it's the start date itself.
 func (sh *Shift) StartDateExclusive() time.Time {
-	if sh.StartDate.IsZero() {
+	if sh.StartDate.IsZero() || sh.Timed() {
 		return sh.StartDate // zero value is both inclusive and exclusive.
 	}
 	return sh.StartDate.Add(-24 * time.Hour)
@@ -105,7 +420,7 @@ func (sh *Shift) StartDateExclusive() time.Time {
 // StopDateExclusive is used when an API uses (start, stop) date ranges as (inclusive, exclusive). May returns a zero
 // `time.Time` when stop is not set.
 func (sh *Shift) StopDateExclusive() time.Time {
-	if sh.StopDate.IsZero() {
+	if sh.StopDate.IsZero() || sh.Timed() {
 		return sh.StopDate // zero value is both inclusive and exclusive.
 	}
 	return sh.StopDate.Add(24 * time.Hour)
@@ -113,8 +428,9 @@ func (sh *Shift) StopDateExclusive() time.Time {
 
 // StopDateExclusive can be used when an API uses (start, stop) date ranges as (inclusive, exclusive).
 func (sh *Shift) SetStopDateExclusive(excl time.Time) {
-	if excl.IsZero() {
+	if excl.IsZero() || sh.Timed() {
 		sh.StopDate = excl // zero value is both inclusive and exclusive.
+		return
 	}
 	sh.StopDate = excl.Add(-24 * time.Hour)
 }
@@ -140,31 +456,67 @@ func (sh *Shift) Validate() error {
 		return fmt.Errorf("start date must be before stop date")
 	}
 
+	if sh.Timed() {
+		if _, err := sh.Location(); err != nil {
+			return fmt.Errorf("invalid time zone(%v): %v", sh.TimeZone, err)
+		}
+		if !sh.StopDate.IsZero() && !sh.StartDate.Before(sh.StopDate) {
+			return fmt.Errorf("start time must be before stop time")
+		}
+	}
+
+	roles := map[string]string{strings.ToLower(sh.GetUser()): PrimaryRole}
+	for _, name := range sh.RoleNames() {
+		a := sh.Roles[name]
+		if name == "" || name == PrimaryRole {
+			return fmt.Errorf("invalid role name %q", name)
+		}
+		if a == nil || a.User == "" {
+			return fmt.Errorf("user cannot be empty for role %v", name)
+		}
+
+		user := strings.ToLower(a.GetUser())
+		if other, ok := roles[user]; ok {
+			return fmt.Errorf("%v cannot hold both the %v and %v roles", a.GetUser(), other, name)
+		}
+		roles[user] = name
+	}
+
 	return nil
 }
 
-// MarshalJSON returns timestamps in the `DateFormat` format.
+// MarshalJSON returns timestamps in the `DateFormat` format, or the `ShiftTimeFormat` format for timed shifts.
 func (sh *Shift) MarshalJSON() ([]byte, error) {
 	// Technique borrowed from http://choly.ca/post/go-json-marshalling/
 	type Alias Shift
 
+	loc, err := sh.Location()
+	if err != nil {
+		return nil, fmt.Errorf("invalid time zone(%v): %v", sh.TimeZone, err)
+	}
+	format := DateFormat
+	if sh.Timed() {
+		format = ShiftTimeFormat
+	}
+
 	aux := &struct {
 		*Alias
 		StartDate string `json:"startDate"`
 		StopDate  string `json:"stopDate,omitempty"`
 	}{
 		Alias:     (*Alias)(sh),
-		StartDate: sh.StartDate.Format(DateFormat),
+		StartDate: sh.StartDate.In(loc).Format(format),
 	}
 
 	if !sh.StopDate.IsZero() {
-		aux.StopDate = sh.StopDate.Format(DateFormat)
+		aux.StopDate = sh.StopDate.In(loc).Format(format)
 	}
 
 	return json.Marshal(aux)
 }
 
-// UnmarshalJSON reads timestamps in the `DateFormat` format, and will throw parsing error otherwise.
+// UnmarshalJSON reads timestamps in the `DateFormat` format, or the `ShiftTimeFormat` format in the shift's time zone
+// for timed shifts, and will throw parsing error otherwise.
 func (sh *Shift) UnmarshalJSON(data []byte) error {
 	// Technique borrowed from http://choly.ca/post/go-json-marshalling/
 	type Alias Shift
@@ -179,12 +531,20 @@ func (sh *Shift) UnmarshalJSON(data []byte) error {
 		return err
 	}
 
-	var err error
-	if sh.StartDate, err = time.Parse(DateFormat, aux.StartDate); err != nil {
+	loc, err := sh.Location()
+	if err != nil {
+		return fmt.Errorf("invalid time zone(%v): %v", sh.TimeZone, err)
+	}
+	format := DateFormat
+	if sh.Timed() {
+		format = ShiftTimeFormat
+	}
+
+	if sh.StartDate, err = time.ParseInLocation(format, aux.StartDate, loc); err != nil {
 		return fmt.Errorf("erroring parsing start date: %v", err)
 	}
 	if aux.StopDate != "" {
-		if sh.StopDate, err = time.Parse(DateFormat, aux.StopDate); err != nil {
+		if sh.StopDate, err = time.ParseInLocation(format, aux.StopDate, loc); err != nil {
 			return fmt.Errorf("erroring parsing stop date: %v", err)
 		}
 	}
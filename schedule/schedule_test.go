@@ -6,6 +6,7 @@ import (
 	"time"
 
 	"github.com/ghodss/yaml"
+	"github.com/spinnaker/rotation-scheduler/users"
 )
 
 func TestScheduleValidate(t *testing.T) {
@@ -31,6 +32,34 @@ func TestScheduleValidate(t *testing.T) {
 			},
 			wantErr: true,
 		},
+		{
+			desc: "invalid roster",
+			schedule: &Schedule{
+				Roster: &Roster{Members: []*Member{{User: "foo"}, {User: "foo"}}},
+				Shifts: []*Shift{
+					{
+						User:      "foo",
+						StartDate: time.Date(2020, 6, 1, 0, 0, 0, 0, time.UTC),
+						StopDate:  time.Date(2020, 6, 7, 0, 0, 0, 0, time.UTC),
+					},
+				},
+			},
+			wantErr: true,
+		},
+		{
+			desc: "invalid rotation",
+			schedule: &Schedule{
+				Rotation: &Rotation{Cursors: map[string]*users.Cursor{PrimaryRole: {Next: 1}}},
+				Shifts: []*Shift{
+					{
+						User:      "foo",
+						StartDate: time.Date(2020, 6, 1, 0, 0, 0, 0, time.UTC),
+						StopDate:  time.Date(2020, 6, 7, 0, 0, 0, 0, time.UTC),
+					},
+				},
+			},
+			wantErr: true,
+		},
 		{
 			desc: "invalid shift",
 			schedule: &Schedule{
@@ -40,6 +69,34 @@ func TestScheduleValidate(t *testing.T) {
 			},
 			wantErr: true,
 		},
+		{
+			desc: "invalid time zone",
+			schedule: &Schedule{
+				TimeZone: "Mars/Olympus_Mons",
+				Shifts: []*Shift{
+					{
+						User:      "foo",
+						StartDate: time.Date(2020, 6, 1, 0, 0, 0, 0, time.UTC),
+						StopDate:  time.Date(2020, 6, 7, 0, 0, 0, 0, time.UTC),
+					},
+				},
+			},
+			wantErr: true,
+		},
+		{
+			desc: "invalid handoff time",
+			schedule: &Schedule{
+				HandoffTime: "10am",
+				Shifts: []*Shift{
+					{
+						User:      "foo",
+						StartDate: time.Date(2020, 6, 1, 0, 0, 0, 0, time.UTC),
+						StopDate:  time.Date(2020, 6, 7, 0, 0, 0, 0, time.UTC),
+					},
+				},
+			},
+			wantErr: true,
+		},
 		{
 			desc: "shifts out of order",
 			schedule: &Schedule{
@@ -149,6 +206,27 @@ func TestMarshalSchedule(t *testing.T) {
 - startDate: Mon 08 Jun 2020
   stopDate: Sun 14 Jun 2020
   user: bar
+`,
+		},
+		{
+			desc: "timed",
+			schedule: &Schedule{
+				HandoffTime: "10:00",
+				TimeZone:    "America/Los_Angeles",
+				Shifts: []*Shift{
+					{
+						User:      "foo",
+						StartDate: time.Date(2020, 6, 1, 0, 0, 0, 0, time.UTC),
+						StopDate:  time.Date(2020, 6, 7, 0, 0, 0, 0, time.UTC),
+					},
+				},
+			},
+			want: `handoffTime: "10:00"
+shifts:
+- startDate: Mon 01 Jun 2020
+  stopDate: Sun 07 Jun 2020
+  user: foo
+timeZone: America/Los_Angeles
 `,
 		},
 	} {
@@ -242,6 +320,18 @@ userOverride: bar
 			},
 			want: `startDate: Mon 01 Jun 2020
 user: foo
+`,
+		},
+		{
+			desc: "locked",
+			shift: &Shift{
+				StartDate: time.Date(2020, 6, 1, 0, 0, 0, 0, time.UTC),
+				User:      "foo",
+				Locked:    true,
+			},
+			want: `locked: true
+startDate: Mon 01 Jun 2020
+user: foo
 `,
 		},
 	} {
@@ -281,6 +371,26 @@ userOverride: bar
 				UserOverride: "bar",
 			},
 		},
+		{
+			desc: "roles",
+			shift: `roles:
+  secondary:
+    user: baz
+    userOverride: qux
+  shadow:
+    user: bar
+startDate: Mon 01 Jun 2020
+user: foo
+`,
+			want: Shift{
+				StartDate: time.Date(2020, 6, 1, 0, 0, 0, 0, time.UTC),
+				User:      "foo",
+				Roles: map[string]*Assignment{
+					"secondary": {User: "baz", UserOverride: "qux"},
+					"shadow":    {User: "bar"},
+				},
+			},
+		},
 		{
 			desc: "optional fields omitted",
 			shift: `startDate: Mon 01 Jun 2020
@@ -322,7 +432,7 @@ user: foo
 				return
 			}
 
-			if tc.want != *got {
+			if !reflect.DeepEqual(tc.want, *got) {
 				t.Errorf("want %v, got %v", tc.want, got)
 			}
 		})
@@ -345,6 +455,50 @@ func TestShiftValidate(t *testing.T) {
 			shift:   &Shift{},
 			wantErr: true,
 		},
+		{
+			desc: "roles",
+			shift: &Shift{
+				StartDate: time.Date(2020, 6, 1, 0, 0, 0, 0, time.UTC),
+				User:      "foo",
+				Roles: map[string]*Assignment{
+					"secondary": {User: "bar"},
+					"shadow":    {User: "foo", UserOverride: "baz"},
+				},
+			},
+		},
+		{
+			desc: "same user in two roles",
+			shift: &Shift{
+				StartDate: time.Date(2020, 6, 1, 0, 0, 0, 0, time.UTC),
+				User:      "foo",
+				Roles: map[string]*Assignment{
+					"secondary": {User: "bar", UserOverride: "FOO"},
+				},
+			},
+			wantErr: true,
+		},
+		{
+			desc: "role without user",
+			shift: &Shift{
+				StartDate: time.Date(2020, 6, 1, 0, 0, 0, 0, time.UTC),
+				User:      "foo",
+				Roles: map[string]*Assignment{
+					"secondary": {},
+				},
+			},
+			wantErr: true,
+		},
+		{
+			desc: "primary role name reserved",
+			shift: &Shift{
+				StartDate: time.Date(2020, 6, 1, 0, 0, 0, 0, time.UTC),
+				User:      "foo",
+				Roles: map[string]*Assignment{
+					PrimaryRole: {User: "bar"},
+				},
+			},
+			wantErr: true,
+		},
 		{
 			desc: "no user",
 			shift: &Shift{
@@ -394,3 +548,294 @@ func TestShiftValidate(t *testing.T) {
 		})
 	}
 }
+
+func TestShiftStopDates(t *testing.T) {
+	sched := &Schedule{
+		Shifts: []*Shift{
+			{
+				User:      "foo",
+				StartDate: time.Date(2020, 6, 1, 0, 0, 0, 0, time.UTC),
+			},
+			{
+				User:      "bar",
+				StartDate: time.Date(2020, 6, 8, 0, 0, 0, 0, time.UTC),
+				StopDate:  time.Date(2020, 6, 10, 0, 0, 0, 0, time.UTC),
+			},
+		},
+	}
+
+	for _, tc := range []struct {
+		desc     string
+		index    int
+		wantIncl time.Time
+		wantExcl time.Time
+	}{
+		{
+			desc:     "implied by next shift",
+			index:    0,
+			wantIncl: time.Date(2020, 6, 7, 0, 0, 0, 0, time.UTC),
+			wantExcl: time.Date(2020, 6, 8, 0, 0, 0, 0, time.UTC),
+		},
+		{
+			desc:     "last shift",
+			index:    1,
+			wantIncl: time.Date(2020, 6, 10, 0, 0, 0, 0, time.UTC),
+			wantExcl: time.Date(2020, 6, 11, 0, 0, 0, 0, time.UTC),
+		},
+	} {
+		t.Run(tc.desc, func(t *testing.T) {
+			gotIncl, gotExcl := sched.ShiftStopDates(tc.index)
+			if tc.wantIncl != gotIncl {
+				t.Errorf("inclusive: want %v, got %v", tc.wantIncl, gotIncl)
+			}
+			if tc.wantExcl != gotExcl {
+				t.Errorf("exclusive: want %v, got %v", tc.wantExcl, gotExcl)
+			}
+		})
+	}
+}
+
+func TestScheduleLock(t *testing.T) {
+	for _, tc := range []struct {
+		desc        string
+		start       time.Time
+		stop        time.Time
+		locked      bool
+		wantChanged int
+		wantLocked  []bool
+	}{
+		{
+			desc:        "overlapping shifts",
+			start:       time.Date(2020, 6, 7, 0, 0, 0, 0, time.UTC),
+			stop:        time.Date(2020, 6, 8, 0, 0, 0, 0, time.UTC),
+			locked:      true,
+			wantChanged: 2,
+			wantLocked:  []bool{true, true, true},
+		},
+		{
+			desc:        "last shift",
+			start:       time.Date(2020, 6, 15, 0, 0, 0, 0, time.UTC),
+			stop:        time.Date(2020, 6, 30, 0, 0, 0, 0, time.UTC),
+			locked:      true,
+			wantChanged: 0,
+			wantLocked:  []bool{false, false, true},
+		},
+		{
+			desc:        "outside of the schedule",
+			start:       time.Date(2020, 6, 22, 0, 0, 0, 0, time.UTC),
+			stop:        time.Date(2020, 6, 30, 0, 0, 0, 0, time.UTC),
+			locked:      true,
+			wantChanged: 0,
+			wantLocked:  []bool{false, false, true},
+		},
+		{
+			desc:        "unlock",
+			start:       time.Date(2020, 6, 1, 0, 0, 0, 0, time.UTC),
+			stop:        time.Date(2020, 6, 30, 0, 0, 0, 0, time.UTC),
+			locked:      false,
+			wantChanged: 1,
+			wantLocked:  []bool{false, false, false},
+		},
+	} {
+		t.Run(tc.desc, func(t *testing.T) {
+			sched := &Schedule{
+				Shifts: []*Shift{
+					{User: "foo", StartDate: time.Date(2020, 6, 1, 0, 0, 0, 0, time.UTC)},
+					{User: "bar", StartDate: time.Date(2020, 6, 8, 0, 0, 0, 0, time.UTC)},
+					{
+						User:      "baz",
+						StartDate: time.Date(2020, 6, 15, 0, 0, 0, 0, time.UTC),
+						StopDate:  time.Date(2020, 6, 21, 0, 0, 0, 0, time.UTC),
+						Locked:    true,
+					},
+				},
+			}
+
+			if got := sched.Lock(tc.start, tc.stop, tc.locked); tc.wantChanged != got {
+				t.Errorf("changed: want %v, got %v", tc.wantChanged, got)
+			}
+
+			var gotLocked []bool
+			for _, shift := range sched.Shifts {
+				gotLocked = append(gotLocked, shift.Locked)
+			}
+			if !reflect.DeepEqual(tc.wantLocked, gotLocked) {
+				t.Errorf("locked: want %v, got %v", tc.wantLocked, gotLocked)
+			}
+		})
+	}
+}
+
+func TestShiftKey(t *testing.T) {
+	shift := &Shift{
+		User:      "foo",
+		StartDate: time.Date(2020, 6, 1, 0, 0, 0, 0, time.UTC),
+	}
+	if got := shift.Key(); "20200601" != got {
+		t.Errorf("want 20200601, got %v", got)
+	}
+}
+
+func TestHandoff(t *testing.T) {
+	la, err := time.LoadLocation("America/Los_Angeles")
+	if err != nil {
+		t.Fatalf("error loading location: %v", err)
+	}
+
+	sched := &Schedule{HandoffTime: "10:00", TimeZone: "America/Los_Angeles"}
+	h, err := sched.Handoff()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !h.Timed || h.Hour != 10 || h.Minute != 0 || h.Location.String() != "America/Los_Angeles" {
+		t.Errorf("unexpected handoff: %+v", h)
+	}
+
+	for _, tc := range []struct {
+		desc string
+		date time.Time
+		want time.Time
+	}{
+		{
+			desc: "daylight time",
+			date: time.Date(2020, 6, 1, 0, 0, 0, 0, time.UTC),
+			want: time.Date(2020, 6, 1, 17, 0, 0, 0, time.UTC),
+		},
+		{
+			desc: "standard time",
+			date: time.Date(2020, 12, 7, 0, 0, 0, 0, time.UTC),
+			want: time.Date(2020, 12, 7, 18, 0, 0, 0, time.UTC),
+		},
+	} {
+		t.Run("At "+tc.desc, func(t *testing.T) {
+			if got := h.At(tc.date); !tc.want.Equal(got) {
+				t.Errorf("want %v, got %v", tc.want, got)
+			}
+		})
+	}
+
+	for _, tc := range []struct {
+		desc string
+		t    time.Time
+		want time.Time
+	}{
+		{
+			desc: "before handoff",
+			t:    time.Date(2020, 6, 1, 9, 59, 0, 0, la),
+			want: time.Date(2020, 5, 31, 0, 0, 0, 0, time.UTC),
+		},
+		{
+			desc: "at handoff",
+			t:    time.Date(2020, 6, 1, 10, 0, 0, 0, la),
+			want: time.Date(2020, 6, 1, 0, 0, 0, 0, time.UTC),
+		},
+		{
+			desc: "evening, already the next day in UTC",
+			t:    time.Date(2020, 6, 1, 20, 0, 0, 0, la),
+			want: time.Date(2020, 6, 1, 0, 0, 0, 0, time.UTC),
+		},
+	} {
+		t.Run("Date "+tc.desc, func(t *testing.T) {
+			if got := h.Date(tc.t); tc.want != got {
+				t.Errorf("want %v, got %v", tc.want, got)
+			}
+		})
+	}
+
+	untimed, err := (&Schedule{}).Handoff()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if untimed.Timed || untimed.Location != time.UTC {
+		t.Errorf("want untimed UTC handoff, got %+v", untimed)
+	}
+	if want, got := time.Date(2020, 6, 2, 0, 0, 0, 0, time.UTC), untimed.Date(time.Date(2020, 6, 1, 20, 0, 0, 0, la)); want != got {
+		t.Errorf("want %v, got %v", want, got)
+	}
+}
+
+func TestShiftUsers(t *testing.T) {
+	shift := &Shift{
+		StartDate:    time.Date(2020, 6, 1, 0, 0, 0, 0, time.UTC),
+		User:         "foo",
+		UserOverride: "qux",
+		Roles: map[string]*Assignment{
+			"shadow":    {User: "bar"},
+			"secondary": {User: "baz", UserOverride: "quux"},
+		},
+	}
+
+	if want, got := []string{"secondary", "shadow"}, shift.RoleNames(); !reflect.DeepEqual(want, got) {
+		t.Errorf("want role names %v, got %v", want, got)
+	}
+	if want, got := []string{"qux", "quux", "bar"}, shift.Users(); !reflect.DeepEqual(want, got) {
+		t.Errorf("want users %v, got %v", want, got)
+	}
+}
+
+func TestTimedShift(t *testing.T) {
+	sched := &Schedule{
+		Shifts: []*Shift{
+			{
+				User:      "foo",
+				StartDate: time.Date(2020, 6, 1, 1, 0, 0, 0, time.UTC),
+				TimeZone:  "Asia/Singapore",
+			},
+			{
+				User:      "bar",
+				StartDate: time.Date(2020, 6, 1, 9, 0, 0, 0, time.UTC),
+				StopDate:  time.Date(2020, 6, 1, 13, 0, 0, 0, time.UTC),
+				TimeZone:  "Europe/London",
+			},
+		},
+	}
+	if err := sched.Validate(); err != nil {
+		t.Fatalf("unexpected error validating schedule: %v", err)
+	}
+
+	// Timed shifts stop when the next one starts, which is both their inclusive and exclusive stop.
+	for i, want := range []time.Time{sched.Shifts[1].StartDate, sched.Shifts[1].StopDate} {
+		incl, excl := sched.ShiftStopDates(i)
+		if !want.Equal(incl) || !want.Equal(excl) {
+			t.Errorf("shift %v: want stop %v, got %v and %v", i, want, incl, excl)
+		}
+	}
+
+	if want, got := "20200601T0100Z", sched.Shifts[0].Key(); want != got {
+		t.Errorf("want key %v, got %v", want, got)
+	}
+	if want, got := "Mon 01 Jun 2020 09:00 +08", sched.Shifts[0].FormatStart(); want != got {
+		t.Errorf("want start %v, got %v", want, got)
+	}
+
+	b, err := yaml.Marshal(sched.Shifts[1])
+	if err != nil {
+		t.Fatalf("marshal shift error: %v", err)
+	}
+	want := `startDate: Mon 01 Jun 2020 10:00
+stopDate: Mon 01 Jun 2020 14:00
+timeZone: Europe/London
+user: bar
+`
+	if want != string(b) {
+		t.Errorf("want:\n%v\n\ngot:\n%v", want, string(b))
+	}
+
+	got := &Shift{}
+	if err := yaml.Unmarshal(b, got); err != nil {
+		t.Fatalf("unmarshal shift error: %v", err)
+	}
+	if !got.StartDate.Equal(sched.Shifts[1].StartDate) || !got.StopDate.Equal(sched.Shifts[1].StopDate) {
+		t.Errorf("want %v, got %v", sched.Shifts[1], got)
+	}
+
+	sched.Shifts[1].StopDate = sched.Shifts[1].StartDate
+	if err := sched.Validate(); err == nil {
+		t.Errorf("want error for a timed shift stopping when it starts and didn't get one.")
+	}
+
+	sched.Shifts[1].TimeZone = "Europe/Nowhere"
+	if _, err := yaml.Marshal(sched.Shifts[1]); err == nil {
+		t.Errorf("want error marshalling a shift with an invalid time zone and didn't get one.")
+	}
+}
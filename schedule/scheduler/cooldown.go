@@ -0,0 +1,42 @@
+package scheduler
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/spinnaker/rotation-scheduler/schedule"
+)
+
+// WithCooldown keeps a gap of at least cooldown between two shifts of the same user, in any role, in addition to any
+// Cooldown of a schedule being extended. Users still cooling down are skipped, and take the next shift they can
+// instead.
+func WithCooldown(cooldown *schedule.Cooldown) Option {
+	return func(s *Scheduler) error {
+		if err := cooldown.Validate(); err != nil {
+			return fmt.Errorf("invalid cooldown: %v", err)
+		}
+		s.cooldown = cooldown
+		return nil
+	}
+}
+
+// coolingDown is true if user can't be on duty in a shift starting on start, at index i of sched, because it's too
+// soon after their previous shift, or before their next one, according to the Scheduler's cooldown or that of sched. i
+// can be the index of a shift that isn't in sched yet.
+func (s *Scheduler) coolingDown(sched *schedule.Schedule, user string, i int, start time.Time) bool {
+	prev := sched.PreviousShiftOf(user, i)
+	next := -1
+	if i < len(sched.Shifts) {
+		next = sched.NextShiftOf(user, i)
+	}
+
+	for _, c := range []*schedule.Cooldown{s.cooldown, sched.Cooldown} {
+		if prev != -1 && c.TooSoon(sched, prev, i, start) {
+			return true
+		}
+		if next != -1 && c.TooSoon(sched, i, next, sched.Shifts[next].StartDate) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,51 @@
+package scheduler
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/spinnaker/rotation-scheduler/schedule"
+	"github.com/spinnaker/rotation-scheduler/users"
+)
+
+func TestScheduleCooldown(t *testing.T) {
+	day := func(d int) time.Time {
+		return time.Date(2020, 3, d, 0, 0, 0, 0, time.UTC)
+	}
+
+	if _, err := NewScheduler(users.NewStaticSource("a"), 1, WithCooldown(&schedule.Cooldown{Days: -1})); err == nil {
+		t.Errorf("want error for a negative cooldown and didn't get one.")
+	}
+
+	// b is away on the 2nd, so they take the primary role on the 3rd instead. Without the cooldown, they'd be on duty
+	// again as the secondary on the 4th.
+	roster := &schedule.Roster{Members: []*schedule.Member{
+		{User: "b", Unavailable: []*schedule.Period{{Start: day(2)}}},
+	}}
+	s, err := NewScheduler(users.NewStaticSource("a", "b", "c", "d"), 1,
+		WithRoster(roster),
+		WithCooldown(&schedule.Cooldown{Shifts: 1}),
+		WithRole("secondary", users.NewStaticSource("a", "b", "c", "d")))
+	if err != nil {
+		t.Fatalf("error creating scheduler: %v", err)
+	}
+
+	sched, err := s.Schedule(day(1), day(6))
+	if err != nil {
+		t.Fatalf("got error from Schedule: %v", err)
+	}
+
+	var got []string
+	for _, shift := range sched.Shifts {
+		got = append(got, shift.StartDate.Format("02")+":"+shift.User+"/"+shift.Roles["secondary"].User)
+	}
+	want := []string{"01:a/b", "02:c/d", "03:b/a", "04:d/c", "05:a/b", "06:c/d"}
+	if !reflect.DeepEqual(want, got) {
+		t.Errorf("want shifts %q, got %q", want, got)
+	}
+
+	if problems := sched.Lint(nil, &schedule.Cooldown{Shifts: 1}, nil, nil); len(problems) != 0 {
+		t.Errorf("want no problems, got %v", problems)
+	}
+}
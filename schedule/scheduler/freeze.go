@@ -0,0 +1,59 @@
+package scheduler
+
+import (
+	"fmt"
+
+	"github.com/spinnaker/rotation-scheduler/schedule"
+)
+
+// KeptShift is a locked or frozen shift held by a user no longer in the rotation. It's kept as it is, so someone needs
+// to set a userOverride to replace them by hand.
+type KeptShift struct {
+	Shift *schedule.Shift
+	Role  string
+	User  string
+
+	// Why is "locked" or "frozen".
+	Why string
+}
+
+func (k *KeptShift) String() string {
+	return fmt.Sprintf("shift starting %v: %v left the rotation, but is still on duty as %v because the shift is %v. Set a userOverride to replace them",
+		k.Shift.FormatStart(), k.User, k.Role, k.Why)
+}
+
+// WithFreezeDays freezes shifts starting within days of the current date when pruning, so they're never rescheduled or
+// reassigned, like locked shifts. Frozen or locked shifts held by users no longer in the rotation are reported as
+// *KeptShifts to any report given WithReport.
+func WithFreezeDays(days int) Option {
+	return func(s *Scheduler) error {
+		if days < 0 {
+			return fmt.Errorf("freeze days cannot be negative, was: %v", days)
+		}
+		s.freezeDays = days
+		return nil
+	}
+}
+
+// keep is true if the shift at index i of sched must be kept as it is when pruning, because it's locked or frozen. If
+// it's held by users no longer in the rotation, they're reported.
+func (s *Scheduler) keep(sched *schedule.Schedule, i int) bool {
+	shift := sched.Shifts[i]
+	why := ""
+	switch {
+	case shift.Locked:
+		why = "locked"
+	case s.freezeDays > 0 && shift.StartDate.Before(s.frozenBefore):
+		why = "frozen"
+	default:
+		return false
+	}
+
+	_, stopExcl := sched.ShiftStopDates(i)
+	for _, r := range s.rolesOf(shift) {
+		if user := roleUser(shift, r.name); user != "" && !s.inRotation(sched, r, user, shift.StartDate, stopExcl) {
+			s.notify(&KeptShift{Shift: shift, Role: r.name, User: user, Why: why})
+		}
+	}
+	return true
+}
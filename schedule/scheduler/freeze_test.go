@@ -0,0 +1,91 @@
+package scheduler
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/spinnaker/rotation-scheduler/schedule"
+	"github.com/spinnaker/rotation-scheduler/users"
+)
+
+func TestExtendScheduleFreeze(t *testing.T) {
+	day := func(d int) time.Time {
+		return time.Date(2020, 1, d, 0, 0, 0, 0, time.UTC)
+	}
+
+	for _, tc := range []struct {
+		desc       string
+		opts       []Option
+		locked     bool
+		stop       time.Time
+		want       []string
+		wantReport []string
+	}{
+		{
+			desc: "not frozen",
+			stop: day(7),
+			want: []string{"a", "b", "c", "a", "b", "c", "a"},
+		},
+		{
+			desc: "frozen",
+			opts: []Option{WithFreezeDays(2)},
+			stop: day(7),
+			want: []string{"a", "gone", "c", "a", "b", "c", "a"},
+			wantReport: []string{
+				"shift starting Thu 02 Jan 2020: gone left the rotation, but is still on duty as primary because the shift is frozen. Set a userOverride to replace them",
+			},
+		},
+		{
+			desc: "frozen and reassigned",
+			opts: []Option{WithFreezeDays(2), WithReassign()},
+			stop: day(7),
+			want: []string{"a", "gone", "c", "a", "b", "c", "a"},
+			wantReport: []string{
+				"shift starting Thu 02 Jan 2020: gone left the rotation, but is still on duty as primary because the shift is frozen. Set a userOverride to replace them",
+				"shift starting Sat 04 Jan 2020: primary changed hands from gone to a",
+			},
+		},
+		{
+			desc:   "locked",
+			locked: true,
+			stop:   day(7),
+			want:   []string{"a", "gone", "c", "a", "b", "c", "a"},
+			wantReport: []string{
+				"shift starting Thu 02 Jan 2020: gone left the rotation, but is still on duty as primary because the shift is locked. Set a userOverride to replace them",
+			},
+		},
+	} {
+		t.Run(tc.desc, func(t *testing.T) {
+			sched := &schedule.Schedule{}
+			for i, u := range []string{"a", "gone", "c", "gone", "b", "c"} {
+				sched.Shifts = append(sched.Shifts, &schedule.Shift{StartDate: day(i + 1), User: u})
+			}
+			sched.Shifts[1].Locked = tc.locked
+			sched.LastShift().StopDate = day(6)
+
+			var report []string
+			opts := append(tc.opts, WithAsOf(day(1)), WithReport(func(r Report) { report = append(report, r.String()) }))
+			s, err := NewScheduler(users.NewStaticSource("a", "b", "c"), 1, opts...)
+			if err != nil {
+				t.Fatalf("error creating scheduler: %v", err)
+			}
+
+			if err := s.ExtendSchedule(sched, tc.stop, true); err != nil {
+				t.Fatalf("got error from ExtendSchedule: %v", err)
+			}
+
+			var got []string
+			for _, shift := range sched.Shifts {
+				got = append(got, shift.GetUser())
+			}
+			if !reflect.DeepEqual(tc.want, got) {
+				t.Errorf("want users %q, got %q", tc.want, got)
+			}
+
+			if !reflect.DeepEqual(tc.wantReport, report) {
+				t.Errorf("want report:\n%q\n\ngot:\n%q", tc.wantReport, report)
+			}
+		})
+	}
+}
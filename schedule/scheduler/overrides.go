@@ -0,0 +1,132 @@
+package scheduler
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/spinnaker/rotation-scheduler/schedule"
+)
+
+// LostOverride is a userOverride of a rescheduled shift that couldn't be carried over to the new schedule, so the people
+// involved know to rearrange it.
+type LostOverride struct {
+	// Shift is the shift as it was before it was rescheduled.
+	Shift        *schedule.Shift
+	Role         string
+	User         string
+	UserOverride string
+	Reason       string
+}
+
+func (l *LostOverride) String() string {
+	return fmt.Sprintf("shift starting %v: %v covering %v as %v was not kept, because %v",
+		l.Shift.FormatStart(), l.UserOverride, l.User, l.Role, l.Reason)
+}
+
+// removedShift is a shift removed by pruning, with its exclusive stop date, since only the last shift has one.
+type removedShift struct {
+	shift    *schedule.Shift
+	stopExcl time.Time
+}
+
+// restoreOverrides carries the userOverrides of the shifts removed by pruning over to the rescheduled shifts, where
+// they're still valid: both users are still in the rotation for the role, a shift still starts and stops on the same
+// dates with the same user scheduled for the role, and the user covering it is available, and not already on duty in it.
+// The rest are reported as LostOverrides.
+func (s *Scheduler) restoreOverrides(sched *schedule.Schedule) {
+	for _, removed := range s.removed {
+		for _, name := range append([]string{schedule.PrimaryRole}, removed.shift.RoleNames()...) {
+			a := assignment(removed.shift, name)
+			if a == nil || a.UserOverride == "" {
+				continue
+			}
+
+			if reason := s.restoreOverride(sched, removed, name, a); reason != "" {
+				s.notify(&LostOverride{
+					Shift:        removed.shift,
+					Role:         name,
+					User:         a.User,
+					UserOverride: a.UserOverride,
+					Reason:       reason,
+				})
+			}
+		}
+	}
+	s.removed = nil
+}
+
+// restoreOverride carries the userOverride of a, for the named role of removed, over to the rescheduled shift on the
+// same dates. If it can't, it returns the reason why.
+func (s *Scheduler) restoreOverride(sched *schedule.Schedule, removed *removedShift, name string, a *schedule.Assignment) string {
+	var r *role
+	for _, sr := range s.rolesOf(removed.shift) {
+		if sr.name == name {
+			r = sr
+		}
+	}
+	if r == nil {
+		return fmt.Sprintf("the %v role isn't scheduled any more", name)
+	}
+
+	for _, u := range []string{a.User, a.UserOverride} {
+		if !r.source.Contains(strings.ToLower(u)) {
+			return fmt.Sprintf("%v left the rotation", u)
+		}
+		if !s.inRotation(sched, r, u, removed.shift.StartDate, removed.stopExcl) {
+			return fmt.Sprintf("%v isn't in the rotation for the whole shift", u)
+		}
+	}
+
+	var shift *schedule.Shift
+	for i, sh := range sched.Shifts {
+		if _, stopExcl := sched.ShiftStopDates(i); sh.StartDate.Equal(removed.shift.StartDate) && stopExcl.Equal(removed.stopExcl) {
+			shift = sh
+			break
+		}
+	}
+	if shift == nil {
+		return "no shift starts and stops on the same dates any more"
+	}
+
+	for _, other := range append([]string{schedule.PrimaryRole}, shift.RoleNames()...) {
+		if other != name && strings.EqualFold(roleUser(shift, other), a.UserOverride) {
+			return fmt.Sprintf("%v is already on duty as %v", a.UserOverride, other)
+		}
+	}
+
+	for _, roster := range []*schedule.Roster{s.roster, sched.Roster} {
+		if p := roster.Unavailable(a.UserOverride, removed.shift.StartDate, removed.stopExcl); p != nil {
+			return fmt.Sprintf("%v is unavailable %v", a.UserOverride, p)
+		}
+	}
+
+	current := assignment(shift, name)
+	if current == nil {
+		return fmt.Sprintf("the rescheduled shift has no %v", name)
+	}
+	if strings.EqualFold(current.User, a.UserOverride) {
+		// They're scheduled for it anyway.
+		return ""
+	}
+	if !strings.EqualFold(current.User, a.User) {
+		// The override was agreed with whoever was scheduled, not whoever is now.
+		return fmt.Sprintf("%v is no longer scheduled for this shift", a.User)
+	}
+
+	if name == schedule.PrimaryRole {
+		shift.UserOverride = a.UserOverride
+	} else {
+		current.UserOverride = a.UserOverride
+	}
+	return ""
+}
+
+// assignment returns the assignment of the named role in shift, or nil if the shift doesn't have it. The primary
+// role's assignment is a copy.
+func assignment(shift *schedule.Shift, name string) *schedule.Assignment {
+	if name == schedule.PrimaryRole {
+		return &schedule.Assignment{User: shift.User, UserOverride: shift.UserOverride}
+	}
+	return shift.Roles[name]
+}
@@ -0,0 +1,146 @@
+package scheduler
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/spinnaker/rotation-scheduler/schedule"
+	"github.com/spinnaker/rotation-scheduler/users"
+)
+
+func TestExtendScheduleRestoreOverrides(t *testing.T) {
+	day := func(d int) time.Time {
+		return time.Date(2020, 1, d, 0, 0, 0, 0, time.UTC)
+	}
+
+	sched := &schedule.Schedule{}
+	for i, u := range []string{"a", "gone", "c/a", "c/b", "d/a", "d/zz", "b/c"} {
+		shift := &schedule.Shift{StartDate: day(i + 1), User: u}
+		if parts := strings.SplitN(u, "/", 2); len(parts) == 2 {
+			shift.User, shift.UserOverride = parts[0], parts[1]
+		}
+		sched.Shifts = append(sched.Shifts, shift)
+	}
+	sched.LastShift().StopDate = day(7)
+
+	var report []string
+	s, err := NewScheduler(users.NewStaticSource("a", "b", "c", "d"), 1,
+		WithAsOf(day(1)),
+		WithRoster(&schedule.Roster{Members: []*schedule.Member{{User: "b", Unavailable: []*schedule.Period{{Start: day(4)}}}}}),
+		WithReport(func(r Report) { report = append(report, r.String()) }))
+	if err != nil {
+		t.Fatalf("error creating scheduler: %v", err)
+	}
+
+	if err := s.ExtendSchedule(sched, day(6), true); err != nil {
+		t.Fatalf("got error from ExtendSchedule: %v", err)
+	}
+
+	want := []string{"a", "b", "c/a", "d", "a", "b"}
+	var got []string
+	for _, shift := range sched.Shifts {
+		u := shift.User
+		if shift.UserOverride != "" {
+			u += "/" + shift.UserOverride
+		}
+		got = append(got, u)
+	}
+	if !reflect.DeepEqual(want, got) {
+		t.Errorf("want users %q, got %q", want, got)
+	}
+
+	wantReport := []string{
+		"shift starting Sat 04 Jan 2020: b covering c as primary was not kept, because b is unavailable Sat 04 Jan 2020",
+		"shift starting Mon 06 Jan 2020: zz covering d as primary was not kept, because zz left the rotation",
+		"shift starting Tue 07 Jan 2020: c covering b as primary was not kept, because no shift starts and stops on the same dates any more",
+	}
+	if !reflect.DeepEqual(wantReport, report) {
+		t.Errorf("want report:\n%q\n\ngot:\n%q", wantReport, report)
+	}
+}
+
+func TestRestoreOverridesRoles(t *testing.T) {
+	day := func(d int) time.Time {
+		return time.Date(2020, 1, d, 0, 0, 0, 0, time.UTC)
+	}
+
+	s, err := NewScheduler(users.NewStaticSource("a", "b"), 1, WithRole("secondary", users.NewStaticSource("a", "x", "y")))
+	if err != nil {
+		t.Fatalf("error creating scheduler: %v", err)
+	}
+	var report []string
+	s.report = func(r Report) { report = append(report, r.String()) }
+
+	sched := &schedule.Schedule{Shifts: []*schedule.Shift{
+		{StartDate: day(1), User: "a", Roles: map[string]*schedule.Assignment{"secondary": {User: "x"}}},
+		{StartDate: day(2), StopDate: day(2), User: "b", Roles: map[string]*schedule.Assignment{"secondary": {User: "y"}}},
+	}}
+	s.removed = []*removedShift{
+		{
+			shift: &schedule.Shift{StartDate: day(1), User: "b", Roles: map[string]*schedule.Assignment{
+				"secondary": {User: "y", UserOverride: "a"},
+				"shadow":    {User: "z", UserOverride: "q"},
+			}},
+			stopExcl: day(2),
+		},
+		{
+			shift:    &schedule.Shift{StartDate: day(2), User: "b", Roles: map[string]*schedule.Assignment{"secondary": {User: "x", UserOverride: "y"}}},
+			stopExcl: day(3),
+		},
+	}
+	s.restoreOverrides(sched)
+
+	if got := sched.Shifts[1].Roles["secondary"].GetUser(); got != "y" {
+		t.Errorf("want y to keep covering the secondary role, got %v", got)
+	}
+	if s.removed != nil {
+		t.Errorf("want removed shifts forgotten, got %v", s.removed)
+	}
+
+	wantReport := []string{
+		"shift starting Wed 01 Jan 2020: a covering y as secondary was not kept, because a is already on duty as primary",
+		"shift starting Wed 01 Jan 2020: q covering z as shadow was not kept, because the shadow role isn't scheduled any more",
+	}
+	if !reflect.DeepEqual(wantReport, report) {
+		t.Errorf("want report:\n%q\n\ngot:\n%q", wantReport, report)
+	}
+}
+
+func TestExtendScheduleRestoreOverridesUserMoved(t *testing.T) {
+	day := func(d int) time.Time {
+		return time.Date(2020, 1, d, 0, 0, 0, 0, time.UTC)
+	}
+
+	sched := &schedule.Schedule{Shifts: []*schedule.Shift{
+		{StartDate: day(1), User: "a"},
+		{StartDate: day(2), User: "gone"},
+		{StartDate: day(3), User: "b", UserOverride: "d"},
+		{StartDate: day(4), StopDate: day(4), User: "c"},
+	}}
+
+	var report []string
+	s, err := NewScheduler(users.NewStaticSource("a", "b", "c", "d"), 1,
+		WithAsOf(day(1)),
+		WithReport(func(r Report) { report = append(report, r.String()) }))
+	if err != nil {
+		t.Fatalf("error creating scheduler: %v", err)
+	}
+
+	if err := s.ExtendSchedule(sched, day(4), true); err != nil {
+		t.Fatalf("got error from ExtendSchedule: %v", err)
+	}
+
+	// Pruning moves c onto the shift d swapped with b, so d doesn't end up covering c.
+	if got := sched.Shifts[2]; got.User != "c" || got.UserOverride != "" {
+		t.Errorf("want c on duty without an override, got %v covered by %q", got.User, got.UserOverride)
+	}
+
+	wantReport := []string{
+		"shift starting Fri 03 Jan 2020: d covering b as primary was not kept, because b is no longer scheduled for this shift",
+	}
+	if !reflect.DeepEqual(wantReport, report) {
+		t.Errorf("want report:\n%q\n\ngot:\n%q", wantReport, report)
+	}
+}
@@ -0,0 +1,152 @@
+package scheduler
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Pattern decides when shifts change hands.
+type Pattern interface {
+	// Next returns the start date of the shift after the one starting on start. A shift starting out of line with the
+	// pattern, like on a Wednesday for shifts starting on Mondays, stops at the next date in line with it, so schedules
+	// line up with the pattern however they start.
+	Next(start time.Time) time.Time
+
+	// String returns the pattern in the format read by ParsePattern.
+	String() string
+}
+
+// DaysPattern is shifts of a fixed number of days, which is what a Scheduler uses unless it's created WithPattern.
+type DaysPattern struct {
+	Days int
+}
+
+func (d *DaysPattern) Next(start time.Time) time.Time {
+	return start.AddDate(0, 0, d.Days)
+}
+
+func (d *DaysPattern) String() string {
+	return fmt.Sprintf("days:%v", d.Days)
+}
+
+// WeeksPattern is shifts of a number of whole weeks, starting on a weekday, like two weeks starting on Monday.
+type WeeksPattern struct {
+	Weeks   int
+	Weekday time.Weekday
+}
+
+func (w *WeeksPattern) Next(start time.Time) time.Time {
+	if start.Weekday() == w.Weekday {
+		return start.AddDate(0, 0, 7*w.Weeks)
+	}
+	return start.AddDate(0, 0, (int(w.Weekday)-int(start.Weekday())+7)%7)
+}
+
+func (w *WeeksPattern) String() string {
+	return fmt.Sprintf("weeks:%v:%v", w.Weeks, strings.ToLower(w.Weekday.String()))
+}
+
+// MonthsPattern is shifts of a number of calendar months, starting on the first of the month.
+type MonthsPattern struct {
+	Months int
+}
+
+func (m *MonthsPattern) Next(start time.Time) time.Time {
+	if start.Day() == 1 {
+		return start.AddDate(0, m.Months, 0)
+	}
+	return time.Date(start.Year(), start.Month()+1, 1, 0, 0, 0, 0, start.Location())
+}
+
+func (m *MonthsPattern) String() string {
+	return fmt.Sprintf("months:%v", m.Months)
+}
+
+// WorkweekPattern is a shift from Monday to Friday, then a separate weekend shift.
+type WorkweekPattern struct{}
+
+func (WorkweekPattern) Next(start time.Time) time.Time {
+	next := start.AddDate(0, 0, 1)
+	for next.Weekday() != time.Monday && next.Weekday() != time.Saturday {
+		next = next.AddDate(0, 0, 1)
+	}
+	return next
+}
+
+func (WorkweekPattern) String() string {
+	return "workweek"
+}
+
+// ParsePattern reads a Pattern written as one of:
+// * "days:N" for shifts of N days.
+// * "weeks:N:WEEKDAY" for shifts of N weeks starting on WEEKDAY, like "weeks:2:monday".
+// * "months:N" for shifts of N calendar months, starting on the first.
+// * "workweek" for a shift from Monday to Friday, then a weekend shift.
+func ParsePattern(value string) (Pattern, error) {
+	parts := strings.Split(strings.ToLower(value), ":")
+
+	count := func() (int, error) {
+		n, err := strconv.Atoi(parts[1])
+		if err != nil {
+			return 0, fmt.Errorf("invalid count in shift pattern %q: %v", value, err)
+		}
+		if n <= 0 {
+			return 0, fmt.Errorf("invalid count in shift pattern %q. Must be greater than 0", value)
+		}
+		return n, nil
+	}
+
+	switch {
+	case parts[0] == "days" && len(parts) == 2:
+		n, err := count()
+		if err != nil {
+			return nil, err
+		}
+		return &DaysPattern{Days: n}, nil
+
+	case parts[0] == "weeks" && len(parts) == 3:
+		n, err := count()
+		if err != nil {
+			return nil, err
+		}
+		d, err := ParseWeekday(parts[2])
+		if err != nil {
+			return nil, fmt.Errorf("invalid shift pattern %q: %v", value, err)
+		}
+		return &WeeksPattern{Weeks: n, Weekday: d}, nil
+
+	case parts[0] == "months" && len(parts) == 2:
+		n, err := count()
+		if err != nil {
+			return nil, err
+		}
+		return &MonthsPattern{Months: n}, nil
+
+	case parts[0] == "workweek" && len(parts) == 1:
+		return WorkweekPattern{}, nil
+	}
+	return nil, fmt.Errorf("invalid shift pattern %q. Must be one of days:N, weeks:N:WEEKDAY, months:N or workweek", value)
+}
+
+// ParseWeekday reads a weekday's name, like "monday", or its first three letters, like "mon", case insensitively.
+func ParseWeekday(name string) (time.Weekday, error) {
+	for d := time.Sunday; d <= time.Saturday; d++ {
+		if full := strings.ToLower(d.String()); strings.EqualFold(name, full) || strings.EqualFold(name, full[:3]) {
+			return d, nil
+		}
+	}
+	return 0, fmt.Errorf("invalid weekday %q", name)
+}
+
+// WithPattern makes shifts change hands according to pattern, instead of every shiftDurationDays.
+func WithPattern(pattern Pattern) Option {
+	return func(s *Scheduler) error {
+		if pattern == nil {
+			return fmt.Errorf("pattern cannot be nil")
+		}
+		s.pattern = pattern
+		return nil
+	}
+}
@@ -0,0 +1,124 @@
+package scheduler
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/spinnaker/rotation-scheduler/users"
+)
+
+func TestParsePattern(t *testing.T) {
+	for _, tc := range []struct {
+		desc    string
+		value   string
+		want    Pattern
+		wantErr bool
+	}{
+		{desc: "days", value: "days:3", want: &DaysPattern{Days: 3}},
+		{desc: "weeks", value: "weeks:2:monday", want: &WeeksPattern{Weeks: 2, Weekday: time.Monday}},
+		{desc: "abbreviated weekday", value: "Weeks:1:Fri", want: &WeeksPattern{Weeks: 1, Weekday: time.Friday}},
+		{desc: "months", value: "months:1", want: &MonthsPattern{Months: 1}},
+		{desc: "workweek", value: "workweek", want: WorkweekPattern{}},
+		{desc: "unknown", value: "fortnight", wantErr: true},
+		{desc: "missing count", value: "days", wantErr: true},
+		{desc: "zero count", value: "months:0", wantErr: true},
+		{desc: "invalid count", value: "days:x", wantErr: true},
+		{desc: "missing weekday", value: "weeks:2", wantErr: true},
+		{desc: "invalid weekday", value: "weeks:2:someday", wantErr: true},
+	} {
+		t.Run(tc.desc, func(t *testing.T) {
+			got, err := ParsePattern(tc.value)
+			if tc.wantErr {
+				if err == nil {
+					t.Errorf("want error and didn't get one.")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			if !reflect.DeepEqual(tc.want, got) {
+				t.Errorf("want %#v, got %#v", tc.want, got)
+			}
+
+			roundTrip, err := ParsePattern(got.String())
+			if err != nil || !reflect.DeepEqual(got, roundTrip) {
+				t.Errorf("want %v to parse back to itself, got %#v, %v", got, roundTrip, err)
+			}
+		})
+	}
+}
+
+func TestSchedulePattern(t *testing.T) {
+	day := func(m time.Month, d int) time.Time {
+		return time.Date(2020, m, d, 0, 0, 0, 0, time.UTC)
+	}
+
+	for _, tc := range []struct {
+		desc      string
+		pattern   Pattern
+		start     time.Time
+		stop      time.Time
+		want      []string
+		wantStop  time.Time
+		wantSaved string
+	}{
+		{
+			desc:      "weeks aligned to a weekday",
+			pattern:   &WeeksPattern{Weeks: 2, Weekday: time.Monday},
+			start:     day(3, 4),
+			stop:      day(4, 12),
+			want:      []string{"Wed 04 Mar 2020", "Mon 09 Mar 2020", "Mon 23 Mar 2020"},
+			wantStop:  day(4, 5),
+			wantSaved: "weeks:2:monday",
+		},
+		{
+			desc:      "calendar months",
+			pattern:   &MonthsPattern{Months: 1},
+			start:     day(1, 15),
+			stop:      day(4, 15),
+			want:      []string{"Wed 15 Jan 2020", "Sat 01 Feb 2020", "Sun 01 Mar 2020"},
+			wantStop:  day(3, 31),
+			wantSaved: "months:1",
+		},
+		{
+			desc:      "workweek",
+			pattern:   WorkweekPattern{},
+			start:     day(3, 1),
+			stop:      day(3, 15),
+			want:      []string{"Sun 01 Mar 2020", "Mon 02 Mar 2020", "Sat 07 Mar 2020", "Mon 09 Mar 2020", "Sat 14 Mar 2020"},
+			wantStop:  day(3, 15),
+			wantSaved: "workweek",
+		},
+	} {
+		t.Run(tc.desc, func(t *testing.T) {
+			s, err := NewScheduler(users.NewStaticSource("a", "b", "c"), 7, WithPattern(tc.pattern))
+			if err != nil {
+				t.Fatalf("error creating scheduler: %v", err)
+			}
+
+			sched, err := s.Schedule(tc.start, tc.stop)
+			if err != nil {
+				t.Fatalf("got error from Schedule: %v", err)
+			}
+
+			var got []string
+			for _, shift := range sched.Shifts {
+				got = append(got, shift.StartDate.Format(DateFormat))
+			}
+			if !reflect.DeepEqual(tc.want, got) {
+				t.Errorf("want shifts starting %q, got %q", tc.want, got)
+			}
+
+			if !tc.wantStop.Equal(sched.LastShift().StopDate) {
+				t.Errorf("want last shift to stop %v, got %v", tc.wantStop, sched.LastShift().StopDate)
+			}
+
+			if sched.Rotation.ShiftPattern != tc.wantSaved || sched.Rotation.ShiftDurationDays != 0 {
+				t.Errorf("want saved pattern %v and no duration, got %v and %v", tc.wantSaved, sched.Rotation.ShiftPattern, sched.Rotation.ShiftDurationDays)
+			}
+		})
+	}
+}
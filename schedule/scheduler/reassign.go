@@ -0,0 +1,194 @@
+package scheduler
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/spinnaker/rotation-scheduler/schedule"
+)
+
+// Change is a shift that changed hands when users no longer in the rotation were reassigned.
+type Change struct {
+	Shift *schedule.Shift
+	Role  string
+	From  string
+	To    string
+}
+
+func (c *Change) String() string {
+	return fmt.Sprintf("shift starting %v: %v changed hands from %v to %v",
+		c.Shift.FormatStart(), c.Role, c.From, c.To)
+}
+
+// WithReassign makes pruning reassign only the shifts held by users no longer in the rotation, instead of rescheduling
+// every shift from the first of them, so nobody else's shifts move. Every shift that changes hands is reported as a
+// *Change to any report given WithReport.
+//
+// A userOverride held by someone who left falls back to the shift's scheduled user, if they're still in the rotation
+// and free. Otherwise, the shift goes to the best replacement: someone available, not cooling down, not breaking any
+// rules, not already on duty in the shift, preferably not on duty in the shift before or after, with the fewest shifts
+// in the role, then whose nearest shift in the role is furthest away, in rotation order. Shifts containing holidays go
+// to whoever has had the fewest.
+func WithReassign() Option {
+	return func(s *Scheduler) error {
+		s.reassign = true
+		return nil
+	}
+}
+
+// reassignNotFoundUsers reassigns every shift of sched held by a user no longer in the rotation for their role. Roles
+// the Scheduler doesn't fill, and locked or frozen shifts, aren't checked.
+func (s *Scheduler) reassignNotFoundUsers(sched *schedule.Schedule) error {
+	for i, shift := range sched.Shifts {
+		if s.keep(sched, i) {
+			continue
+		}
+		for _, r := range s.rolesOf(shift) {
+			if err := s.reassignRole(sched, i, r); err != nil {
+				return fmt.Errorf("error reassigning shift starting %v: %v", shift.FormatStart(), err)
+			}
+		}
+	}
+	return nil
+}
+
+// reassignRole reassigns role r of the shift at index i of sched, if it's held by a user no longer in the rotation.
+func (s *Scheduler) reassignRole(sched *schedule.Schedule, i int, r *role) error {
+	shift := sched.Shifts[i]
+	a := &schedule.Assignment{User: shift.User, UserOverride: shift.UserOverride}
+	if r.name != schedule.PrimaryRole {
+		if a = shift.Roles[r.name]; a == nil {
+			return nil
+		}
+	}
+
+	from := a.GetUser()
+	_, stopExcl := sched.ShiftStopDates(i)
+	if s.inRotation(sched, r, from, shift.StartDate, stopExcl) {
+		return nil
+	}
+
+	onDuty := map[string]bool{}
+	var others []string
+	for _, u := range shift.Users() {
+		if !strings.EqualFold(u, from) {
+			onDuty[strings.ToLower(u)] = true
+			others = append(others, u)
+		}
+	}
+	var broken *schedule.Rule
+	eligible := func(user string) bool {
+		if onDuty[strings.ToLower(user)] ||
+			!s.inRotation(sched, r, user, shift.StartDate, stopExcl) ||
+			!s.roster.Available(user, shift.StartDate, stopExcl) ||
+			!sched.Roster.Available(user, shift.StartDate, stopExcl) ||
+			s.coolingDown(sched, user, i, shift.StartDate) {
+			return false
+		}
+		if rule := s.broken(sched, i, shift.StartDate, stopExcl, user, others, true); rule != nil {
+			if broken == nil {
+				broken = rule
+			}
+			return false
+		}
+		return true
+	}
+
+	if a.UserOverride != "" && eligible(a.User) {
+		a.UserOverride = ""
+	} else {
+		if r.name == schedule.PrimaryRole && schedule.CountHolidays(s.holidays, shift.StartDate, stopExcl) > 0 {
+			eligible = s.fewestHolidays(sched, r, eligible)
+		}
+		to := s.replacement(sched, i, r, eligible)
+		if to == "" && broken != nil {
+			return fmt.Errorf("nobody in the %v role can replace %v without breaking rule %v", r.name, from, broken)
+		}
+		if to == "" {
+			return fmt.Errorf("nobody in the %v role is available to replace %v", r.name, from)
+		}
+
+		if r.name == schedule.PrimaryRole {
+			s.countHolidays(sched, shift, stopExcl, -1)
+			shift.User = to
+			s.countHolidays(sched, shift, stopExcl, 1)
+		}
+		a.User, a.UserOverride = to, ""
+	}
+
+	if r.name == schedule.PrimaryRole {
+		shift.User, shift.UserOverride = a.User, a.UserOverride
+	}
+
+	s.notify(&Change{Shift: shift, Role: r.name, From: from, To: a.GetUser()})
+	return nil
+}
+
+// replacement returns the best eligible user of role r to take the shift at index i of sched, or an empty string if
+// there isn't one. See WithReassign.
+func (s *Scheduler) replacement(sched *schedule.Schedule, i int, r *role, eligible func(user string) bool) string {
+	type candidate struct {
+		user     string
+		adjacent bool
+		shifts   int
+		nearest  int
+	}
+
+	var candidates []*candidate
+	for _, u := range r.source.Users() {
+		if !eligible(u) {
+			continue
+		}
+
+		c := &candidate{user: u, nearest: len(sched.Shifts)}
+		for j, shift := range sched.Shifts {
+			if j == i || !strings.EqualFold(roleUser(shift, r.name), u) {
+				continue
+			}
+			distance := j - i
+			if distance < 0 {
+				distance = -distance
+			}
+			if distance == 1 {
+				c.adjacent = true
+			}
+			if r.name == schedule.PrimaryRole && shift.Type != sched.Shifts[i].Type {
+				// Primary users of other shift types take turns in another rotation.
+				continue
+			}
+			c.shifts++
+			if distance < c.nearest {
+				c.nearest = distance
+			}
+		}
+		candidates = append(candidates, c)
+	}
+
+	if len(candidates) == 0 {
+		return ""
+	}
+
+	sort.SliceStable(candidates, func(a, b int) bool {
+		ca, cb := candidates[a], candidates[b]
+		if ca.adjacent != cb.adjacent {
+			return !ca.adjacent
+		}
+		if ca.shifts != cb.shifts {
+			return ca.shifts < cb.shifts
+		}
+		return ca.nearest > cb.nearest
+	})
+	return candidates[0].user
+}
+
+// roleUser returns the user on duty for the named role in shift, or an empty string if the shift doesn't have it.
+func roleUser(shift *schedule.Shift, name string) string {
+	if name == schedule.PrimaryRole {
+		return shift.GetUser()
+	}
+	if a, ok := shift.Roles[name]; ok {
+		return a.GetUser()
+	}
+	return ""
+}
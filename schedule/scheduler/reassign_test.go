@@ -0,0 +1,205 @@
+package scheduler
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/spinnaker/rotation-scheduler/schedule"
+	"github.com/spinnaker/rotation-scheduler/users"
+)
+
+func TestReassignNotFoundUsers(t *testing.T) {
+	day := func(d int) time.Time {
+		return time.Date(2020, 1, d, 0, 0, 0, 0, time.UTC)
+	}
+	shifts := func(users ...string) []*schedule.Shift {
+		var shifts []*schedule.Shift
+		for i, u := range users {
+			shift := &schedule.Shift{StartDate: day(i + 1), User: u}
+			if parts := strings.SplitN(u, "/", 2); len(parts) == 2 {
+				shift.User, shift.UserOverride = parts[0], parts[1]
+			}
+			shifts = append(shifts, shift)
+		}
+		shifts[len(shifts)-1].StopDate = day(len(shifts))
+		return shifts
+	}
+
+	for _, tc := range []struct {
+		desc       string
+		users      []string
+		opts       []Option
+		sched      *schedule.Schedule
+		want       []string
+		wantCounts map[string]int
+		wantReport []string
+		wantErr    bool
+	}{
+		{
+			desc:  "nobody left",
+			users: []string{"a", "b", "c"},
+			sched: &schedule.Schedule{Shifts: shifts("a", "b", "c", "a")},
+			want:  []string{"a", "b", "c", "a"},
+		},
+		{
+			desc:  "only departed user's shifts move",
+			users: []string{"a", "b", "d"},
+			sched: &schedule.Schedule{Shifts: shifts("a", "b", "c", "d", "a", "b", "c", "d")},
+			want:  []string{"a", "b", "a", "d", "a", "b", "a", "d"},
+			wantReport: []string{
+				"shift starting Fri 03 Jan 2020: primary changed hands from c to a",
+				"shift starting Tue 07 Jan 2020: primary changed hands from c to a",
+			},
+		},
+		{
+			desc:  "fewest shifts",
+			users: []string{"a", "b", "d"},
+			sched: &schedule.Schedule{Shifts: shifts("a", "c", "d", "b", "a", "d", "b", "a")},
+			want:  []string{"a", "b", "d", "b", "a", "d", "b", "a"},
+			wantReport: []string{
+				"shift starting Thu 02 Jan 2020: primary changed hands from c to b",
+			},
+		},
+		{
+			desc:  "override falls back to scheduled user",
+			users: []string{"a", "b", "c"},
+			sched: &schedule.Schedule{Shifts: shifts("a", "b/gone", "c", "a/b")},
+			want:  []string{"a", "b", "c", "a/b"},
+			wantReport: []string{
+				"shift starting Thu 02 Jan 2020: primary changed hands from gone to b",
+			},
+		},
+		{
+			desc:  "override kept",
+			users: []string{"a", "b"},
+			sched: &schedule.Schedule{Shifts: shifts("a", "gone/b", "a")},
+			want:  []string{"a", "gone/b", "a"},
+		},
+		{
+			desc:  "unavailable and on duty users skipped",
+			users: []string{"a", "b", "c", "x"},
+			opts: []Option{
+				WithRole("secondary", users.NewStaticSource("x", "y")),
+				WithRoster(&schedule.Roster{Members: []*schedule.Member{{User: "a", Unavailable: []*schedule.Period{{Start: day(3)}}}}}),
+			},
+			sched: func() *schedule.Schedule {
+				sched := &schedule.Schedule{Shifts: shifts("a", "b", "gone", "c", "b")}
+				sched.Shifts[2].Roles = map[string]*schedule.Assignment{"secondary": {User: "x"}}
+				sched.Shifts[3].Roles = map[string]*schedule.Assignment{"secondary": {User: "z"}}
+				return sched
+			}(),
+			want: []string{"a", "b", "c x", "c y", "b"},
+			wantReport: []string{
+				"shift starting Fri 03 Jan 2020: primary changed hands from gone to c",
+				"shift starting Sat 04 Jan 2020: secondary changed hands from z to y",
+			},
+		},
+		{
+			desc:  "holidays",
+			users: []string{"a", "b", "c"},
+			opts:  []Option{WithHolidays([]*schedule.Holiday{{Date: day(2)}})},
+			sched: &schedule.Schedule{
+				HolidayCounts: map[string]int{"a": 1, "gone": 1},
+				Shifts:        shifts("c", "gone", "a", "b"),
+			},
+			want:       []string{"c", "b", "a", "b"},
+			wantCounts: map[string]int{"a": 1, "b": 1},
+			wantReport: []string{
+				"shift starting Thu 02 Jan 2020: primary changed hands from gone to b",
+			},
+		},
+		{
+			desc:    "nobody available",
+			users:   []string{"a"},
+			opts:    []Option{WithRoster(&schedule.Roster{Members: []*schedule.Member{{User: "a", Unavailable: []*schedule.Period{{Start: day(2)}}}}})},
+			sched:   &schedule.Schedule{Shifts: shifts("a", "gone")},
+			wantErr: true,
+		},
+	} {
+		t.Run(tc.desc, func(t *testing.T) {
+			var gotReport []string
+			opts := append(tc.opts, WithReassign(), WithReport(func(r Report) {
+				gotReport = append(gotReport, r.String())
+			}))
+			s, err := NewScheduler(users.NewStaticSource(tc.users...), 1, opts...)
+			if err != nil {
+				t.Fatalf("error creating scheduler: %v", err)
+			}
+
+			err = s.reassignNotFoundUsers(tc.sched)
+			if tc.wantErr {
+				if err == nil {
+					t.Errorf("err expected and not received.")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("got unexpected error: %v", err)
+			}
+
+			var got []string
+			for _, shift := range tc.sched.Shifts {
+				u := shift.User
+				if shift.UserOverride != "" {
+					u += "/" + shift.UserOverride
+				}
+				if a, ok := shift.Roles["secondary"]; ok {
+					u += " " + a.GetUser()
+				}
+				got = append(got, u)
+			}
+			if !reflect.DeepEqual(tc.want, got) {
+				t.Errorf("want users %q, got %q", tc.want, got)
+			}
+			if !reflect.DeepEqual(tc.wantReport, gotReport) {
+				t.Errorf("want report:\n%q\n\ngot:\n%q", tc.wantReport, gotReport)
+			}
+			if tc.wantCounts != nil && !reflect.DeepEqual(tc.wantCounts, tc.sched.HolidayCounts) {
+				t.Errorf("want holiday counts %v, got %v", tc.wantCounts, tc.sched.HolidayCounts)
+			}
+		})
+	}
+}
+
+func TestExtendScheduleReassign(t *testing.T) {
+	sched := &schedule.Schedule{
+		Shifts: []*schedule.Shift{
+			{StartDate: time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC), User: "a"},
+			{StartDate: time.Date(2020, 1, 2, 0, 0, 0, 0, time.UTC), User: "gone"},
+			{StartDate: time.Date(2020, 1, 3, 0, 0, 0, 0, time.UTC), User: "c"},
+			{
+				StartDate: time.Date(2020, 1, 4, 0, 0, 0, 0, time.UTC),
+				StopDate:  time.Date(2020, 1, 4, 0, 0, 0, 0, time.UTC),
+				User:      "b",
+			},
+		},
+	}
+
+	var report []*Change
+	s, err := NewScheduler(users.NewStaticSource("a", "b", "c"), 1,
+		WithAsOf(time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)),
+		WithReassign(),
+		WithReport(func(r Report) { report = append(report, r.(*Change)) }))
+	if err != nil {
+		t.Fatalf("error creating scheduler: %v", err)
+	}
+
+	if err := s.ExtendSchedule(sched, time.Date(2020, 1, 6, 0, 0, 0, 0, time.UTC), true); err != nil {
+		t.Fatalf("got error from ExtendSchedule: %v", err)
+	}
+
+	want := []string{"a", "b", "c", "b", "c", "a"}
+	var got []string
+	for _, shift := range sched.Shifts {
+		got = append(got, shift.GetUser())
+	}
+	if !reflect.DeepEqual(want, got) {
+		t.Errorf("want users %q, got %q", want, got)
+	}
+
+	if len(report) != 1 || report[0].Shift != sched.Shifts[1] || report[0].From != "gone" || report[0].To != "b" {
+		t.Errorf("want a single change of the second shift from gone to b, got %v", report)
+	}
+}
@@ -0,0 +1,108 @@
+package scheduler
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/spinnaker/rotation-scheduler/schedule"
+	"github.com/spinnaker/rotation-scheduler/users"
+)
+
+// FollowTheSunPattern hands shifts off between Regions every day, as each one takes over at its handoff time in its
+// time zone. It's the Pattern of a Scheduler created WithRegion.
+type FollowTheSunPattern struct {
+	Regions []*schedule.Region
+}
+
+func (f *FollowTheSunPattern) Next(start time.Time) time.Time {
+	var next time.Time
+	for _, r := range f.Regions {
+		if t := r.Next(start); next.IsZero() || t.Before(next) {
+			next = t
+		}
+	}
+	return next
+}
+
+// Previous returns the last time any region took over at or before t.
+func (f *FollowTheSunPattern) Previous(t time.Time) time.Time {
+	region := f.Region(t)
+	return region.Previous(t)
+}
+
+// Region returns the region on duty at t: the one that took over most recently.
+func (f *FollowTheSunPattern) Region(t time.Time) *schedule.Region {
+	var region *schedule.Region
+	var latest time.Time
+	for _, r := range f.Regions {
+		if p := r.Previous(t); region == nil || p.After(latest) {
+			region, latest = r, p
+		}
+	}
+	return region
+}
+
+// endOfDay returns the end of date in the time zone of the region on duty at t.
+func (f *FollowTheSunPattern) endOfDay(date, t time.Time) time.Time {
+	y, m, d := date.Date()
+	return time.Date(y, m, d+1, 0, 0, 0, 0, f.Region(t).Location())
+}
+
+// String isn't read by ParsePattern, because the regions are given WithRegion.
+func (f *FollowTheSunPattern) String() string {
+	return "follow-the-sun"
+}
+
+// WithRegion makes the schedule follow the sun: every day, the region named name takes over at handoffTime, in the
+// `schedule.HandoffTimeFormat` format, in the IANA timeZone, until the next region does. Its shifts are filled from
+// source, in its own order, instead of the Scheduler's userSource. Shifts are timed, in the time zone of their region,
+// and have its name as their Type. Regions replace any Pattern, and can't be combined WithShiftType.
+func WithRegion(name, handoffTime, timeZone string, source users.Source) Option {
+	return func(s *Scheduler) error {
+		region := &schedule.Region{Name: name, HandoffTime: handoffTime, TimeZone: timeZone}
+		if err := region.Validate(); err != nil {
+			return err
+		}
+
+		if source == nil {
+			return fmt.Errorf("no user source specified for region %v", name)
+		}
+
+		for _, r := range s.regions {
+			if r.Name == name {
+				return fmt.Errorf("region %v specified more than once", name)
+			}
+		}
+
+		s.regions = append(s.regions, region)
+		s.types = append(s.types, &role{
+			name:      schedule.PrimaryRole,
+			source:    source,
+			shiftType: name,
+		})
+		return nil
+	}
+}
+
+// followTheSun returns the Scheduler's pattern if it was created WithRegion, or nil otherwise.
+func (s *Scheduler) followTheSun() *FollowTheSunPattern {
+	if len(s.regions) == 0 {
+		return nil
+	}
+	return s.pattern.(*FollowTheSunPattern)
+}
+
+// useRegions makes a Scheduler created WithRegion follow the sun, once all its options are applied.
+func (s *Scheduler) useRegions() error {
+	if len(s.regions) == 0 {
+		return nil
+	}
+
+	for _, t := range s.types {
+		if len(t.weekdays) != 0 {
+			return fmt.Errorf("regions cannot be combined with shift types")
+		}
+	}
+	s.pattern = &FollowTheSunPattern{Regions: s.regions}
+	return nil
+}
@@ -0,0 +1,94 @@
+package scheduler
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/spinnaker/rotation-scheduler/users"
+)
+
+func TestWithRegion(t *testing.T) {
+	apac := WithRegion("apac", "09:00", "Asia/Singapore", users.NewStaticSource("c", "d"))
+
+	for _, tc := range []struct {
+		desc string
+		opts []Option
+	}{
+		{desc: "empty name", opts: []Option{WithRegion("", "09:00", "", users.NewStaticSource("c"))}},
+		{desc: "invalid handoff time", opts: []Option{WithRegion("apac", "9am", "", users.NewStaticSource("c"))}},
+		{desc: "invalid time zone", opts: []Option{WithRegion("apac", "09:00", "Asia/Nowhere", users.NewStaticSource("c"))}},
+		{desc: "nil source", opts: []Option{WithRegion("apac", "09:00", "", nil)}},
+		{desc: "duplicate region", opts: []Option{apac, WithRegion("apac", "10:00", "", users.NewStaticSource("e"))}},
+		{desc: "with shift type", opts: []Option{apac, WithShiftType("weekend", users.NewStaticSource("e"), time.Saturday)}},
+	} {
+		t.Run(tc.desc, func(t *testing.T) {
+			if _, err := NewScheduler(users.NewStaticSource("a"), 1, tc.opts...); err == nil {
+				t.Errorf("want error and didn't get one.")
+			}
+		})
+	}
+}
+
+func TestScheduleFollowTheSun(t *testing.T) {
+	day := func(d int) time.Time {
+		return time.Date(2020, 3, d, 0, 0, 0, 0, time.UTC)
+	}
+
+	s, err := NewScheduler(users.NewStaticSource("a", "b", "c", "d", "e", "f"), 1,
+		WithRegion("apac", "09:00", "Asia/Singapore", users.NewStaticSource("a", "b")),
+		WithRegion("emea", "09:00", "Europe/London", users.NewStaticSource("c", "d")),
+		WithRegion("amer", "09:00", "America/New_York", users.NewStaticSource("e", "f")))
+	if err != nil {
+		t.Fatalf("error creating scheduler: %v", err)
+	}
+
+	sched, err := s.Schedule(day(2), day(3))
+	if err != nil {
+		t.Fatalf("got error from Schedule: %v", err)
+	}
+
+	got := func() []string {
+		var got []string
+		for _, shift := range sched.Shifts {
+			got = append(got, shift.StartDate.UTC().Format("02 15:04")+" "+shift.Type+":"+shift.User)
+		}
+		return got
+	}
+
+	// Midnight UTC on the start date is in the middle of amer's shift, so the first shift starts when amer took over the
+	// day before. amer's shift on the 3rd stops by the end of the 3rd in New York, so it's the last one.
+	want := []string{
+		"01 14:00 amer:e", "02 01:00 apac:a", "02 09:00 emea:c", "02 14:00 amer:f", "03 01:00 apac:b", "03 09:00 emea:d",
+		"03 14:00 amer:e",
+	}
+	if !reflect.DeepEqual(want, got()) {
+		t.Errorf("want shifts %q, got %q", want, got())
+	}
+
+	for _, shift := range sched.Shifts {
+		loc, err := shift.Location()
+		if err != nil {
+			t.Fatalf("invalid time zone of shift %v: %v", shift, err)
+		}
+		if got := shift.StartDate.In(loc).Format("15:04"); got != "09:00" {
+			t.Errorf("want shift %v to start at its region's handoff, got %v", shift, got)
+		}
+	}
+
+	if len(sched.Regions) != 3 {
+		t.Errorf("want the schedule's 3 regions, got %v", sched.Regions)
+	}
+	if want, got := "Asia/Singapore", sched.Shifts[1].TimeZone; want != got {
+		t.Errorf("want apac shift in time zone %v, got %v", want, got)
+	}
+
+	if err := s.ExtendSchedule(sched, day(4), false); err != nil {
+		t.Fatalf("got error from ExtendSchedule: %v", err)
+	}
+
+	want = append(want, "04 01:00 apac:a", "04 09:00 emea:c", "04 14:00 amer:f")
+	if !reflect.DeepEqual(want, got()) {
+		t.Errorf("want extended shifts %q, got %q", want, got())
+	}
+}
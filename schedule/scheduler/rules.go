@@ -0,0 +1,92 @@
+package scheduler
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/spinnaker/rotation-scheduler/schedule"
+)
+
+// WithRules makes every assignment follow rules, in addition to any Rules of a schedule being extended. Users who'd
+// break a rule are skipped, and take the next shift they can instead. When nobody can take a role without breaking a
+// rule, the error names the shift and the rule.
+func WithRules(rules []*schedule.Rule) Option {
+	return func(s *Scheduler) error {
+		for _, r := range rules {
+			if err := r.Validate(); err != nil {
+				return fmt.Errorf("invalid rule: %v", err)
+			}
+		}
+		s.rules = rules
+		return nil
+	}
+}
+
+// broken returns the first rule, of the Scheduler's or sched's, that user would break by being on duty from start to
+// stopExcl, in the shift at index i of sched, or nil if there isn't one. i can be the index of a shift that isn't in
+// sched yet. others are the other users on duty in the shift so far, and last is true if user fills its last role.
+func (s *Scheduler) broken(sched *schedule.Schedule, i int, start, stopExcl time.Time, user string, others []string, last bool) *schedule.Rule {
+	for _, rules := range [][]*schedule.Rule{s.rules, sched.Rules} {
+		for _, rule := range rules {
+			if !s.follows(rule, sched, i, start, stopExcl, user, others, last) {
+				return rule
+			}
+		}
+	}
+	return nil
+}
+
+// follows is true if user being on duty doesn't break rule. See broken.
+func (s *Scheduler) follows(rule *schedule.Rule, sched *schedule.Schedule, i int, start, stopExcl time.Time, user string, others []string, last bool) bool {
+	switch {
+	case rule.AtLeastOne:
+		if !last || rule.Applies(user) {
+			return true
+		}
+		for _, u := range others {
+			if rule.Applies(u) {
+				return true
+			}
+		}
+		return false
+
+	case !rule.Applies(user):
+		return true
+
+	case rule.NotConsecutive:
+		for _, j := range []int{i - 1, i + 1} {
+			if j < 0 || j >= len(sched.Shifts) {
+				continue
+			}
+			for _, u := range sched.Shifts[j].Users() {
+				if !strings.EqualFold(u, user) && rule.Applies(u) {
+					return false
+				}
+			}
+		}
+		return true
+
+	case len(rule.Unavailable) != 0:
+		for _, p := range rule.Unavailable {
+			if p.Overlaps(start, stopExcl) {
+				return false
+			}
+		}
+		return true
+	}
+
+	if schedule.CountHolidays(s.holidays, start, stopExcl) == 0 {
+		return true
+	}
+	n := 0
+	for j, shift := range sched.Shifts {
+		if j == i || shift.StartDate.Year() != start.Year() || !shift.OnDuty(user) {
+			continue
+		}
+		if _, stop := sched.ShiftStopDates(j); schedule.CountHolidays(s.holidays, shift.StartDate, stop) > 0 {
+			n++
+		}
+	}
+	return n < *rule.MaxHolidayShifts
+}
@@ -0,0 +1,148 @@
+package scheduler
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/spinnaker/rotation-scheduler/schedule"
+	"github.com/spinnaker/rotation-scheduler/users"
+)
+
+func TestScheduleRules(t *testing.T) {
+	day := func(d int) time.Time {
+		return time.Date(2020, 3, d, 0, 0, 0, 0, time.UTC)
+	}
+	limit := func(n int) *int {
+		return &n
+	}
+	holidays := WithHolidays([]*schedule.Holiday{{Date: day(1)}, {Date: day(2)}, {Date: day(3)}, {Date: day(4)}, {Date: day(5)}})
+
+	for _, tc := range []struct {
+		desc string
+		opts []Option
+		want []string
+	}{
+		{
+			desc: "not consecutive",
+			opts: []Option{WithRules([]*schedule.Rule{{Name: "apart", Users: []string{"a", "b"}, NotConsecutive: true}})},
+			want: []string{"a", "c", "b", "d", "a", "c"},
+		},
+		{
+			desc: "at least one",
+			opts: []Option{
+				WithRole("secondary", users.NewStaticSource("a", "b", "c", "d")),
+				WithRules([]*schedule.Rule{{Name: "senior", Users: []string{"c", "d"}, AtLeastOne: true}}),
+			},
+			want: []string{"a/c", "b/d", "c/a", "d/b", "a/c", "b/d"},
+		},
+		{
+			desc: "unavailable",
+			opts: []Option{WithRules([]*schedule.Rule{{Name: "release", Users: []string{"a", "b"}, Unavailable: []*schedule.Period{{Start: day(2), Stop: day(3)}}}})},
+			want: []string{"a", "c", "d", "b", "a", "b"},
+		},
+		{
+			desc: "max holiday shifts",
+			opts: []Option{
+				holidays,
+				WithRules([]*schedule.Rule{{Name: "holidays", Users: []string{"a"}, MaxHolidayShifts: limit(1)}}),
+			},
+			// Everyone has had a holiday by the 5th, so it'd be a's turn again.
+			want: []string{"a", "b", "c", "d", "b", "a"},
+		},
+		{
+			desc: "never on holidays",
+			opts: []Option{
+				holidays,
+				WithRules([]*schedule.Rule{{Name: "holidays", Users: []string{"a"}, MaxHolidayShifts: limit(0)}}),
+			},
+			// a is only on duty once there are no holidays left.
+			want: []string{"b", "c", "d", "b", "c", "a"},
+		},
+	} {
+		t.Run(tc.desc, func(t *testing.T) {
+			s, err := NewScheduler(users.NewStaticSource("a", "b", "c", "d"), 1, tc.opts...)
+			if err != nil {
+				t.Fatalf("error creating scheduler: %v", err)
+			}
+
+			sched, err := s.Schedule(day(1), day(6))
+			if err != nil {
+				t.Fatalf("got error from Schedule: %v", err)
+			}
+
+			var got []string
+			for _, shift := range sched.Shifts {
+				got = append(got, strings.Join(shift.Users(), "/"))
+			}
+			if !reflect.DeepEqual(tc.want, got) {
+				t.Errorf("want shifts %q, got %q", tc.want, got)
+			}
+		})
+	}
+}
+
+func TestScheduleRulesUnsatisfiable(t *testing.T) {
+	day := func(d int) time.Time {
+		return time.Date(2020, 3, d, 0, 0, 0, 0, time.UTC)
+	}
+
+	if _, err := NewScheduler(users.NewStaticSource("a"), 1, WithRules([]*schedule.Rule{{Name: "empty"}})); err == nil {
+		t.Errorf("want error for an invalid rule and didn't get one.")
+	}
+
+	s, err := NewScheduler(users.NewStaticSource("a", "b"), 1,
+		WithRules([]*schedule.Rule{{Name: "release", Users: []string{"a", "b"}, Unavailable: []*schedule.Period{{Start: day(3)}}}}))
+	if err != nil {
+		t.Fatalf("error creating scheduler: %v", err)
+	}
+
+	_, err = s.Schedule(day(1), day(6))
+	want := `error scheduling shift starting Tue 03 Mar 2020: nobody in the primary role can be on duty without breaking rule "release"`
+	if err == nil || !strings.Contains(err.Error(), want) {
+		t.Errorf("want error containing %q, got %v", want, err)
+	}
+}
+
+func TestReassignRules(t *testing.T) {
+	day := func(d int) time.Time {
+		return time.Date(2020, 3, d, 0, 0, 0, 0, time.UTC)
+	}
+
+	newSchedule := func(first string) *schedule.Schedule {
+		return &schedule.Schedule{
+			Rules: []*schedule.Rule{{Name: "apart", Users: []string{"c", "d"}, NotConsecutive: true}},
+			Shifts: []*schedule.Shift{
+				{StartDate: day(2), User: first},
+				{StartDate: day(3), User: "a"},
+				{StartDate: day(4), StopDate: day(4), User: "d"},
+			},
+		}
+	}
+
+	// a leaves, and c would replace them, being the only one not on duty in the shift before or after, but can't be on
+	// duty right before d.
+	s, err := NewScheduler(users.NewStaticSource("b", "c", "d"), 1, WithReassign(), WithAsOf(day(1)))
+	if err != nil {
+		t.Fatalf("error creating scheduler: %v", err)
+	}
+	sched := newSchedule("b")
+	if err := s.ExtendSchedule(sched, day(5), true); err != nil {
+		t.Fatalf("got error from ExtendSchedule: %v", err)
+	}
+	if got := sched.Shifts[1].User; got != "b" {
+		t.Errorf("want b to replace a, got %v", got)
+	}
+
+	// Nobody left can take the shift between c and d.
+	s, err = NewScheduler(users.NewStaticSource("c", "d"), 1, WithReassign(), WithAsOf(day(1)))
+	if err != nil {
+		t.Fatalf("error creating scheduler: %v", err)
+	}
+	err = s.ExtendSchedule(newSchedule("c"), day(5), true)
+	want := `error reassigning shift starting Tue 03 Mar 2020: nobody in the primary role can replace a without breaking rule "apart"`
+	if err == nil || !strings.Contains(err.Error(), want) {
+		t.Errorf("want error containing %q, got %v", want, err)
+	}
+}
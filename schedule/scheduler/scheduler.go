@@ -2,6 +2,7 @@ package scheduler
 
 import (
 	"fmt"
+	"strings"
 	"time"
 
 	"github.com/spinnaker/rotation-scheduler/schedule"
@@ -12,18 +13,193 @@ const (
 	DateFormat = "Mon 02 Jan 2006"
 )
 
-var (
-	today = func() time.Time { return time.Now().Truncate(24 * time.Hour) }
-)
-
 // Scheduler creates or extends a new rotation schedule.
 type Scheduler struct {
-	userSource        users.Source
-	shiftDurationDays int
+	pattern Pattern
+
+	handoffTime string
+	timeZone    string
+
+	clock func() time.Time
+	asOf  time.Time
+
+	primary *role
+	roles   []*role
+
+	// types are the primary roles of each shift type, filled instead of the primary role for shifts starting on their
+	// weekdays, or of each region.
+	types []*role
+
+	// regions are the regions of a schedule that follows the sun, in the order they were given.
+	regions []*schedule.Region
+
+	roster *schedule.Roster
+
+	holidays []*schedule.Holiday
+
+	cooldown *schedule.Cooldown
+	rules    []*schedule.Rule
+
+	reassign bool
+	report   func(r Report)
+
+	freezeDays int
+	// frozenBefore is the date shifts must start on or after to not be frozen, set when pruning.
+	frozenBefore time.Time
+
+	// removed are the shifts removed by pruning, to restore their overrides once they're rescheduled.
+	removed []*removedShift
+
+	// locked are the locked shifts set aside by pruning, in order, to put back as they were once the shifts around them
+	// are rescheduled.
+	locked []*removedShift
+	// putBack is the last locked shift put back, so nobody in it is on duty again in the shift after.
+	putBack *schedule.Shift
+}
+
+// role is filled for every shift from its own source.
+type role struct {
+	name   string
+	source users.Source
+
+	// pending holds users skipped because they were unavailable, or already held another role in a shift. They're
+	// first in line for the next shift they can take.
+	pending []string
+
+	// shiftType is the type of the shifts a primary role is filled for, like "weekend", and weekdays are the days they
+	// start on. Both are empty for the default primary role, and other roles, which are filled for every shift.
+	shiftType string
+	weekdays  []time.Weekday
+}
+
+// key identifies the role's cursor in a Rotation.
+func (r *role) key() string {
+	if r.shiftType != "" {
+		return r.shiftType + "/" + r.name
+	}
+	return r.name
+}
+
+// Option configures optional Scheduler behavior.
+type Option func(s *Scheduler) error
+
+// WithHandoff makes shifts change hands at handoffTime, in the `schedule.HandoffTimeFormat` format, in the IANA
+// timeZone, instead of at midnight. Either can be empty to keep a schedule's existing value when extending it.
+func WithHandoff(handoffTime, timeZone string) Option {
+	return func(s *Scheduler) error {
+		check := &schedule.Schedule{HandoffTime: handoffTime, TimeZone: timeZone}
+		if _, err := check.Handoff(); err != nil {
+			return err
+		}
+
+		s.handoffTime = handoffTime
+		s.timeZone = timeZone
+		return nil
+	}
+}
+
+// WithClock replaces time.Now as the source of the current time, which decides the current shift when pruning.
+func WithClock(clock func() time.Time) Option {
+	return func(s *Scheduler) error {
+		if clock == nil {
+			return fmt.Errorf("clock cannot be nil")
+		}
+		s.clock = clock
+		return nil
+	}
+}
+
+// WithAsOf prunes as if it were the date asOf, regardless of the clock, handoff time or time zone. Only the year,
+// month and day of asOf are relevant.
+func WithAsOf(asOf time.Time) Option {
+	return func(s *Scheduler) error {
+		if asOf.IsZero() {
+			return fmt.Errorf("as-of date cannot be zero value")
+		}
+		s.asOf = time.Date(asOf.Year(), asOf.Month(), asOf.Day(), 0, 0, 0, 0, time.UTC)
+		return nil
+	}
+}
+
+// WithRole fills an additional role, like "secondary" or "shadow", in every shift from its own source, in its own
+// order. Nobody holds more than one role in a shift: users already on duty are skipped, and get the role in the next
+// shift instead. Roles are filled in the order they're given, after the primary role.
+func WithRole(name string, source users.Source) Option {
+	return func(s *Scheduler) error {
+		if name == "" || name == schedule.PrimaryRole {
+			return fmt.Errorf("invalid role name %q", name)
+		}
+
+		if source == nil {
+			return fmt.Errorf("no user source specified for role %v", name)
+		}
+
+		for _, r := range s.roles {
+			if r.name == name {
+				return fmt.Errorf("role %v specified more than once", name)
+			}
+		}
+
+		s.roles = append(s.roles, &role{name: name, source: source})
+		return nil
+	}
+}
+
+// WithRoster skips users while they're unavailable according to roster, in addition to any Roster in a schedule being
+// extended. Skipped users take the next shift they're available for, and the rotation carries on in order after that.
+func WithRoster(roster *schedule.Roster) Option {
+	return func(s *Scheduler) error {
+		if err := roster.Validate(); err != nil {
+			return fmt.Errorf("invalid roster: %v", err)
+		}
+		s.roster = roster
+		return nil
+	}
+}
+
+// Report is something that happened to existing shifts while extending a schedule, that people may need to know
+// about, like a *Change or a *LostOverride.
+type Report interface {
+	String() string
+}
+
+// WithReport calls report with everything that happens to existing shifts while extending a schedule that people may
+// need to know about.
+func WithReport(report func(r Report)) Option {
+	return func(s *Scheduler) error {
+		if report == nil {
+			return fmt.Errorf("report cannot be nil")
+		}
+		s.report = report
+		return nil
+	}
+}
+
+// notify reports r, if the Scheduler was created WithReport.
+func (s *Scheduler) notify(r Report) {
+	if s.report != nil {
+		s.report(r)
+	}
 }
 
-// NewScheduler creates a new Scheduler. All args are required.
-func NewScheduler(userSource users.Source, shiftDurationDays int) (*Scheduler, error) {
+// WithHolidays spreads shifts containing holidays evenly: each one goes to whoever, of the users available for it,
+// has the fewest holidays in the schedule's HolidayCounts, in rotation order. Anyone skipped takes the next shift
+// instead.
+func WithHolidays(holidays []*schedule.Holiday) Option {
+	return func(s *Scheduler) error {
+		for i, h := range holidays {
+			if h == nil || h.Date.IsZero() {
+				return fmt.Errorf("holiday %v has no date", i)
+			}
+		}
+		s.holidays = holidays
+		return nil
+	}
+}
+
+// NewScheduler creates a new Scheduler. userSource and shiftDurationDays are required, though shiftDurationDays is
+// unused if the Scheduler is created WithPattern.
+func NewScheduler(userSource users.Source, shiftDurationDays int, opts ...Option) (*Scheduler, error) {
 	if userSource == nil {
 		return nil, fmt.Errorf("no user source specificed")
 	}
@@ -32,14 +208,26 @@ func NewScheduler(userSource users.Source, shiftDurationDays int) (*Scheduler, e
 		return nil, fmt.Errorf("shift duration invalid. Must be greater than 0, was: %v", shiftDurationDays)
 	}
 
-	return &Scheduler{
-		userSource:        userSource,
-		shiftDurationDays: shiftDurationDays,
-	}, nil
+	s := &Scheduler{
+		pattern: &DaysPattern{Days: shiftDurationDays},
+		clock:   time.Now,
+		primary: &role{name: schedule.PrimaryRole, source: userSource},
+	}
+	for _, opt := range opts {
+		if err := opt(s); err != nil {
+			return nil, err
+		}
+	}
+	if err := s.useRegions(); err != nil {
+		return nil, err
+	}
+	return s, nil
 }
 
 // Schedule creates a new Schedule that includes whole shifts of `Scheduler.shiftDuration` from start (inclusive) to
 // stop (inclusive).  Will return an error if stop is before start, or either start are stop are zero values.
+// Schedules that follow the sun start when the region on duty at start took over, and their shifts stop by the end of
+// stop in the time zone of their region.
 func (s *Scheduler) Schedule(start, stop time.Time) (*schedule.Schedule, error) {
 	if start.IsZero() || stop.IsZero() {
 		return nil, fmt.Errorf("neither start (%v) nor stop (%v) can be zero values", start, stop)
@@ -49,110 +237,441 @@ func (s *Scheduler) Schedule(start, stop time.Time) (*schedule.Schedule, error)
 		return nil, fmt.Errorf("start cannot be before stop")
 	}
 
-	sched := &schedule.Schedule{}
+	sched := &schedule.Schedule{
+		HandoffTime: s.handoffTime,
+		TimeZone:    s.timeZone,
+		Regions:     s.regions,
+	}
+	if f := s.followTheSun(); f != nil {
+		// Start with the region on duty at midnight UTC on the start date, from when it took over.
+		start = f.Previous(start)
+	}
 	if err := s.extendSchedule(sched, start, stop); err != nil {
 		return nil, fmt.Errorf("error extending schedule: %v", err)
 	}
+	s.saveRotation(sched)
 
 	return sched, nil
 }
 
 // ExtendSchedule takes a previously generated schedule and extends it. The user rotation continues as normal from the
-// last shift in the schedule.
+// last shift in the schedule, exactly where it left off if the schedule's Rotation was saved after that shift, and
+// otherwise worked out from the shifts.
 //
 // If prune is true:
 // * all shifts completed in the past are purged, and all current and future shifts are checked for owners in the
 // current rotation.
 // * If a shift is assigned to a user that is no longer in the rotation (including if the missing user is in the
 // userOverride field), that shift and all future shifts are rescheduled.
-// * Rescheduled shifts do not carry over previous userOverride values.
+// * Rescheduled shifts carry over previous userOverride values where they're still valid: both users are still in
+// the rotation, and a shift still starts and stops on the same dates. The rest are reported as LostOverrides to any
+// report the Scheduler was created WithReport.
 // * Shifts originally assigned to a missing rotation member, but have a userOverride owner that is in the
 // current rotation, are not be rescheduled.
+// * If the Scheduler was created WithReassign, only the shifts of missing users are reassigned instead.
+// * Locked shifts, and shifts frozen WithFreezeDays, are never changed, even if their users are missing. Shifts
+// rescheduled around them stop short of them, and the rotation continues after them as if they weren't there, except
+// that their users aren't on duty in the shifts right before and after them.
+// * The current shift is the one on duty today, in the schedule's time zone and counting from its handoff time, unless
+// the Scheduler was created WithAsOf a date.
+//
+// Any handoff time or time zone the Scheduler was created with replaces the schedule's own, and so do its regions.
+// Schedules that follow the sun can only be extended by a Scheduler created WithRegion.
 func (s *Scheduler) ExtendSchedule(sched *schedule.Schedule, stopInclusive time.Time, prune bool) error {
+	if s.handoffTime != "" {
+		sched.HandoffTime = s.handoffTime
+	}
+	if s.timeZone != "" {
+		sched.TimeZone = s.timeZone
+	}
+	if len(s.regions) != 0 {
+		sched.Regions = s.regions
+	} else if len(sched.Regions) != 0 {
+		return fmt.Errorf("cannot extend a schedule that follows the sun without its regions")
+	}
+
 	if err := sched.Validate(); err != nil {
 		return fmt.Errorf("cannot extend invalid schedule: %v", err)
 	}
 
+	s.removed, s.locked, s.putBack = nil, nil, nil
 	if prune {
-		s.prune(today(), sched)
+		today, err := s.today(sched)
+		if err != nil {
+			return err
+		}
+		if err := s.prune(today, sched); err != nil {
+			return err
+		}
 	}
 
 	lastShift := sched.LastShift()
-	if lastShift.StopDateExclusive().After(stopInclusive) {
+	stopsBy := stopInclusive
+	if f := s.followTheSun(); f != nil {
+		stopsBy = f.endOfDay(stopInclusive, lastShift.StartDate)
+	}
+	if lastShift.StopDateExclusive().After(stopsBy) {
 		return fmt.Errorf("cannot stop before the last shift of the previous schedule is complete")
 	}
 
-	s.userSource.StartAfter(lastShift.User)
+	s.resume(sched, sched.Shifts, lastShift.User)
 	firstNewShiftStart := sched.LastShift().StopDateExclusive()
 	sched.LastShift().ClearStopDate()
 
-	return s.extendSchedule(sched, firstNewShiftStart, stopInclusive)
+	if err := s.extendSchedule(sched, firstNewShiftStart, stopInclusive); err != nil {
+		return err
+	}
+	s.restoreOverrides(sched)
+	s.saveRotation(sched)
+	return nil
 }
 
-func (s *Scheduler) prune(start time.Time, sched *schedule.Schedule) {
-	s.pruneOldShifts(start, sched)
-	s.pruneNotFoundUsers(sched)
+// today returns the date of the shift day it is now, for sched, or for schedules that follow the sun, the time the
+// region on duty now took over.
+func (s *Scheduler) today(sched *schedule.Schedule) (time.Time, error) {
+	if f := s.followTheSun(); f != nil {
+		if !s.asOf.IsZero() {
+			return f.Previous(s.asOf), nil
+		}
+		return f.Previous(s.clock()), nil
+	}
+
+	if !s.asOf.IsZero() {
+		return s.asOf, nil
+	}
+
+	handoff, err := sched.Handoff()
+	if err != nil {
+		return time.Time{}, err
+	}
+	return handoff.Date(s.clock()), nil
 }
 
-func (s *Scheduler) pruneOldShifts(start time.Time, sched *schedule.Schedule) {
+func (s *Scheduler) prune(start time.Time, sched *schedule.Schedule) error {
+	s.frozenBefore = start.AddDate(0, 0, s.freezeDays)
+	if err := s.pruneOldShifts(start, sched); err != nil {
+		return err
+	}
+	if s.reassign {
+		return s.reassignNotFoundUsers(sched)
+	}
+	return s.pruneNotFoundUsers(sched)
+}
+
+func (s *Scheduler) pruneOldShifts(start time.Time, sched *schedule.Schedule) error {
 	for i, shift := range sched.Shifts {
 		if i != 0 && start.Before(shift.StartDate) {
 			// prune start time happened sometime in between the last shift and this shift.
 			sched.Shifts = sched.Shifts[(i - 1):]
 			break
-		} else if start == shift.StartDate || (shift == sched.LastShift() && start == shift.StopDate) {
+		} else if start.Equal(shift.StartDate) || (shift == sched.LastShift() && start.Equal(shift.StopDate)) {
 			// prune start time landed on a shift start or stop time.
 			sched.Shifts = sched.Shifts[i:]
 			break
 		} else if shift == sched.LastShift() && start.After(shift.StopDate) {
 			// entire schedule is in the past.
-			s.userSource.StartAfter(sched.LastShift().GetUser())
-			sched.Shifts = []*schedule.Shift{
-				{
-					StartDate: start,
-					User:      s.userSource.NextUser(),
-				},
+			s.resume(sched, sched.Shifts, sched.LastShift().GetUser())
+			newShift, err := s.newShift(sched, start)
+			if err != nil {
+				return err
 			}
+			sched.Shifts = []*schedule.Shift{newShift}
 			sched.LastShift().SetStopDateExclusive(s.nextShiftTime(sched.LastShift().StartDate))
 			break
 		}
 	}
+	return nil
 }
 
 // pruneNotFoundUsers truncates the schedule at the first shift where a user is no longer in the rotation group.
 // The intent is to not reschedule too aggressively, so if a removed user shift has been swapped with someone else, that
-// shift will not be removed.
-func (s *Scheduler) pruneNotFoundUsers(sched *schedule.Schedule) {
+// shift will not be removed. Locked and frozen shifts are never removed, so any locked ones after the truncation are set
+// aside to be put back.
+func (s *Scheduler) pruneNotFoundUsers(sched *schedule.Schedule) error {
 	for i, shift := range sched.Shifts {
-		if !s.userSource.Contains(shift.GetUser()) {
-			sched.Shifts = sched.Shifts[:i]
-
-			if sched.LastShift() == nil {
-				s.userSource.StartAfter(shift.GetUser())
-				sched.Shifts = append(sched.Shifts, &schedule.Shift{
-					StartDate: shift.StartDate,
-					User:      s.userSource.NextUser(),
-				})
-				sched.LastShift().SetStopDateExclusive(s.nextShiftTime(shift.StartDate))
-			} else {
-				sched.LastShift().SetStopDateExclusive(shift.StartDate)
+		if s.keep(sched, i) || s.hasCurrentUsers(sched, i) {
+			continue
+		}
+
+		for j := i; j < len(sched.Shifts); j++ {
+			_, stopExcl := sched.ShiftStopDates(j)
+			removed := &removedShift{shift: sched.Shifts[j], stopExcl: stopExcl}
+			if s.keep(sched, j) {
+				s.locked = append(s.locked, removed)
+				continue
 			}
-			return
+			s.countHolidays(sched, removed.shift, stopExcl, -1)
+			s.removed = append(s.removed, removed)
+		}
+		sched.Shifts = sched.Shifts[:i]
+
+		if sched.LastShift() == nil {
+			s.resume(sched, []*schedule.Shift{shift}, shift.GetUser())
+			newShift, err := s.newShift(sched, shift.StartDate)
+			if err != nil {
+				return err
+			}
+			sched.Shifts = append(sched.Shifts, newShift)
+			sched.LastShift().SetStopDateExclusive(s.shiftStop(shift.StartDate))
+		} else {
+			sched.LastShift().SetStopDateExclusive(shift.StartDate)
+		}
+		return nil
+	}
+	return nil
+}
+
+// hasCurrentUsers is true if everyone on duty in the shift at index i of sched is still in the rotation for their role,
+// for the whole shift. Roles the Scheduler doesn't fill aren't checked.
+func (s *Scheduler) hasCurrentUsers(sched *schedule.Schedule, i int) bool {
+	shift := sched.Shifts[i]
+	_, stopExcl := sched.ShiftStopDates(i)
+	for _, r := range s.rolesOf(shift) {
+		if user := roleUser(shift, r.name); user != "" && !s.inRotation(sched, r, user, shift.StartDate, stopExcl) {
+			return false
 		}
 	}
+	return true
 }
 
+// inRotation is true if user is in the rotation for role r every day from start (inclusive) to stopExcl (exclusive):
+// they're in its source, and have joined and not yet left according to the Scheduler's roster, and the Roster of sched.
+func (s *Scheduler) inRotation(sched *schedule.Schedule, r *role, user string, start, stopExcl time.Time) bool {
+	return r.source.Contains(strings.ToLower(user)) &&
+		s.roster.Active(user, start, stopExcl) &&
+		sched.Roster.Active(user, start, stopExcl)
+}
+
+// resume positions the source of every role to continue after shifts, which must not be empty, and forgets any
+// pending users. Sources continue from the Rotation cursors of sched, if there are any for its last shift. Otherwise,
+// they resume after the users of shifts, where primary sources only count shifts of their type, and continue after
+// last, in place of the user scheduled for the last shift.
+func (s *Scheduler) resume(sched *schedule.Schedule, shifts []*schedule.Shift, last string) {
+	cursors := sched.Cursors()
+
+	for _, r := range s.filled() {
+		if !users.Seek(r.source, cursors[r.key()]) {
+			history := []string{}
+			for i, shift := range shifts {
+				if r.name != schedule.PrimaryRole {
+					if a, ok := shift.Roles[r.name]; ok {
+						history = append(history, a.User)
+					}
+					continue
+				}
+
+				if shift.Type != r.shiftType {
+					continue
+				}
+				if i == len(shifts)-1 {
+					history = append(history, last)
+				} else {
+					history = append(history, shift.User)
+				}
+			}
+			users.Resume(r.source, history)
+		}
+		r.pending = nil
+	}
+}
+
+// saveRotation saves where the source of every role left off in the Rotation of sched, so extending it continues from
+// there.
+func (s *Scheduler) saveRotation(sched *schedule.Schedule) {
+	rotation := &schedule.Rotation{
+		GeneratedAt:    s.clock().UTC().Truncate(time.Second),
+		LastShiftStart: sched.LastShift().StartDate,
+		Cursors:        map[string]*users.Cursor{},
+	}
+	switch p := s.pattern.(type) {
+	case *DaysPattern:
+		rotation.ShiftDurationDays = p.Days
+	case *FollowTheSunPattern:
+		// The schedule's Regions are its pattern.
+	default:
+		rotation.ShiftPattern = p.String()
+	}
+	for _, r := range s.filled() {
+		if c := users.CursorOf(r.source); c != nil {
+			rotation.Cursors[r.key()] = c
+		}
+	}
+	sched.Rotation = rotation
+}
+
+// newShift creates a shift of sched starting on start, of the type for its weekday, filling every role with the next
+// user from its source who's in the rotation and available for the whole shift, not already on duty, not cooling down,
+// and not breaking any rules. Nobody is on duty as the primary in the shift before, if it's of a different type, or in a
+// locked shift right before or after.
+func (s *Scheduler) newShift(sched *schedule.Schedule, start time.Time) (*schedule.Shift, error) {
+	stopExcl := s.shiftStop(start)
+	onDuty := map[string]bool{}
+	available := func(user string) bool {
+		return !onDuty[strings.ToLower(user)] &&
+			s.roster.Available(user, start, stopExcl) &&
+			sched.Roster.Available(user, start, stopExcl) &&
+			!s.coolingDown(sched, user, len(sched.Shifts), start)
+	}
+
+	primary := s.primaryOf(start)
+	shift := &schedule.Shift{
+		StartDate: start,
+		Type:      primary.shiftType,
+	}
+	if f := s.followTheSun(); f != nil {
+		shift.TimeZone = f.Region(start).Location().String()
+	}
+	neighbours := map[string]bool{}
+	if last := sched.LastShift(); last != nil && (last.Type != shift.Type || last == s.putBack) {
+		neighbours[strings.ToLower(last.GetUser())] = true
+	}
+	if len(s.locked) > 0 && s.locked[0].shift.StartDate.Equal(stopExcl) {
+		neighbours[strings.ToLower(s.locked[0].shift.GetUser())] = true
+	}
+
+	var others []string
+	roles := append([]*role{primary}, s.roles...)
+	for i, r := range roles {
+		r := r
+		last := i == len(roles)-1
+		active := func(user string) bool {
+			return s.inRotation(sched, r, user, start, stopExcl)
+		}
+		var broken *schedule.Rule
+		eligible := func(user string) bool {
+			if !active(user) || !available(user) || (r == primary && neighbours[strings.ToLower(user)]) {
+				return false
+			}
+			if rule := s.broken(sched, len(sched.Shifts), start, stopExcl, user, others, last); rule != nil {
+				if broken == nil {
+					broken = rule
+				}
+				return false
+			}
+			return true
+		}
+		if r == primary && schedule.CountHolidays(s.holidays, start, stopExcl) > 0 {
+			eligible = s.fewestHolidays(sched, r, eligible)
+		}
+
+		user, err := r.next(active, eligible)
+		if err != nil {
+			if broken != nil {
+				err = fmt.Errorf("nobody in the %v role can be on duty without breaking rule %v", r.name, broken)
+			}
+			return nil, fmt.Errorf("error scheduling shift starting %v: %v", shift.FormatStart(), err)
+		}
+		onDuty[strings.ToLower(user)] = true
+		others = append(others, user)
+
+		if r == primary {
+			shift.User = user
+			s.countHolidays(sched, shift, stopExcl, 1)
+			continue
+		}
+
+		if shift.Roles == nil {
+			shift.Roles = map[string]*schedule.Assignment{}
+		}
+		shift.Roles[r.name] = &schedule.Assignment{User: user}
+	}
+	return shift, nil
+}
+
+// fewestHolidays narrows eligible to the users with the fewest holidays in sched's HolidayCounts, among all eligible
+// users of the primary role r.
+func (s *Scheduler) fewestHolidays(sched *schedule.Schedule, r *role, eligible func(user string) bool) func(user string) bool {
+	fewest := -1
+	for _, u := range r.source.Users() {
+		if count := sched.HolidayCounts[strings.ToLower(u)]; eligible(u) && (fewest == -1 || count < fewest) {
+			fewest = count
+		}
+	}
+
+	return func(user string) bool {
+		return eligible(user) && sched.HolidayCounts[strings.ToLower(user)] == fewest
+	}
+}
+
+// countHolidays adds delta to the holiday count of shift's user, for each holiday from the shift's start to stopExcl.
+func (s *Scheduler) countHolidays(sched *schedule.Schedule, shift *schedule.Shift, stopExcl time.Time, delta int) {
+	n := schedule.CountHolidays(s.holidays, shift.StartDate, stopExcl)
+	if n == 0 {
+		return
+	}
+
+	if sched.HolidayCounts == nil {
+		sched.HolidayCounts = map[string]int{}
+	}
+	user := strings.ToLower(shift.User)
+	sched.HolidayCounts[user] += n * delta
+	if sched.HolidayCounts[user] <= 0 {
+		delete(sched.HolidayCounts, user)
+	}
+}
+
+// next returns the first pending user, or the next user from the source, that's eligible. Active users skipped along
+// the way become pending, while users who aren't active, because they haven't joined the rotation yet or have left it,
+// are skipped for good.
+func (r *role) next(active, eligible func(user string) bool) (string, error) {
+	for i, u := range r.pending {
+		if eligible(u) {
+			r.pending = append(r.pending[:i], r.pending[i+1:]...)
+			return u, nil
+		}
+	}
+
+	seen := map[string]bool{}
+	for {
+		u := r.source.NextUser()
+		if eligible(u) {
+			return u, nil
+		}
+
+		if seen[u] {
+			return "", fmt.Errorf("everyone in the %v role is unavailable, already on duty, cooling down, or not in the rotation", r.name)
+		}
+		seen[u] = true
+		if active(u) && !r.isPending(u) {
+			r.pending = append(r.pending, u)
+		}
+	}
+}
+
+func (r *role) isPending(user string) bool {
+	for _, u := range r.pending {
+		if u == user {
+			return true
+		}
+	}
+	return false
+}
+
+// extendSchedule appends whole shifts to sched from start until stopInclusive, putting back any locked shifts set
+// aside by pruning in between, even if they go beyond stopInclusive.
 func (s *Scheduler) extendSchedule(sched *schedule.Schedule, start, stopInclusive time.Time) error {
-	for ; s.wholeShiftCanFit(start, stopInclusive); start = s.nextShiftTime(start) {
-		sched.Shifts = append(sched.Shifts, &schedule.Shift{
-			User:      s.userSource.NextUser(),
-			StartDate: start,
-		})
+	for s.wholeShiftCanFit(start, stopInclusive) || len(s.locked) > 0 {
+		if len(s.locked) > 0 && !start.Before(s.locked[0].shift.StartDate) {
+			locked := s.locked[0]
+			s.locked = s.locked[1:]
+			locked.shift.ClearStopDate()
+			sched.Shifts = append(sched.Shifts, locked.shift)
+			s.putBack = locked.shift
+			start = locked.stopExcl
+			continue
+		}
+
+		shift, err := s.newShift(sched, start)
+		if err != nil {
+			return err
+		}
+		sched.Shifts = append(sched.Shifts, shift)
+		start = s.shiftStop(start)
 	}
 
 	if sched.LastShift() == nil {
-		return fmt.Errorf("no whole shifts of duration %v days can fit between %v and %v",
-			s.shiftDurationDays,
+		return fmt.Errorf("no whole shifts of pattern %v can fit between %v and %v",
+			s.pattern,
 			start.Format(DateFormat),
 			stopInclusive.Format(DateFormat))
 	}
@@ -165,11 +684,27 @@ func (s *Scheduler) extendSchedule(sched *schedule.Schedule, start, stopInclusiv
 	return nil
 }
 
+// wholeShiftCanFit is true if a shift starting on start stops by the end of stopInclusive, which for timed shifts of a
+// schedule that follows the sun is the end of the day in the time zone of their region.
 func (s *Scheduler) wholeShiftCanFit(start, stopInclusive time.Time) bool {
+	if f := s.followTheSun(); f != nil {
+		return !s.nextShiftTime(start).After(f.endOfDay(stopInclusive, start))
+	}
+
 	shiftStopIncl := s.nextShiftTime(start).Add(-24 * time.Hour)
 	return shiftStopIncl.Before(stopInclusive) || shiftStopIncl == stopInclusive
 }
 
 func (s *Scheduler) nextShiftTime(previous time.Time) time.Time {
-	return previous.AddDate(0, 0, s.shiftDurationDays)
+	return s.pattern.Next(previous)
+}
+
+// shiftStop returns the exclusive stop date of a new shift starting on start, which is cut short by the next locked
+// shift waiting to be put back, if it starts sooner.
+func (s *Scheduler) shiftStop(start time.Time) time.Time {
+	stopExcl := s.nextShiftTime(start)
+	if len(s.locked) > 0 && s.locked[0].shift.StartDate.Before(stopExcl) {
+		return s.locked[0].shift.StartDate
+	}
+	return stopExcl
 }
@@ -2,6 +2,7 @@ package scheduler
 
 import (
 	"reflect"
+	"strings"
 	"testing"
 	"time"
 
@@ -17,6 +18,630 @@ func TestNewScheduler(t *testing.T) {
 	if _, err := NewScheduler(users.NewStaticSource("foo"), 0); err == nil {
 		t.Errorf("want error on invalid shift duration, and didn't get one.")
 	}
+
+	if _, err := NewScheduler(users.NewStaticSource("foo"), 1, WithHandoff("25:00", "")); err == nil {
+		t.Errorf("want error on invalid handoff time, and didn't get one.")
+	}
+
+	if _, err := NewScheduler(users.NewStaticSource("foo"), 1, WithHandoff("", "Nowhere/Special")); err == nil {
+		t.Errorf("want error on invalid time zone, and didn't get one.")
+	}
+
+	if _, err := NewScheduler(users.NewStaticSource("foo"), 1, WithClock(nil)); err == nil {
+		t.Errorf("want error on nil clock, and didn't get one.")
+	}
+
+	if _, err := NewScheduler(users.NewStaticSource("foo"), 1, WithAsOf(time.Time{})); err == nil {
+		t.Errorf("want error on zero as-of date, and didn't get one.")
+	}
+
+	if _, err := NewScheduler(users.NewStaticSource("foo"), 1, WithRole(schedule.PrimaryRole, users.NewStaticSource("bar"))); err == nil {
+		t.Errorf("want error on primary role name, and didn't get one.")
+	}
+
+	if _, err := NewScheduler(users.NewStaticSource("foo"), 1,
+		WithRoster(&schedule.Roster{Members: []*schedule.Member{{User: "foo"}, {User: "FOO"}}})); err == nil {
+		t.Errorf("want error on invalid roster, and didn't get one.")
+	}
+
+	if _, err := NewScheduler(users.NewStaticSource("foo"), 1, WithHolidays([]*schedule.Holiday{{Name: "undated"}})); err == nil {
+		t.Errorf("want error on holiday without a date, and didn't get one.")
+	}
+
+	if _, err := NewScheduler(users.NewStaticSource("foo"), 1, WithPattern(nil)); err == nil {
+		t.Errorf("want error on nil pattern, and didn't get one.")
+	}
+
+	if _, err := NewScheduler(users.NewStaticSource("foo"), 1, WithFreezeDays(-1)); err == nil {
+		t.Errorf("want error on negative freeze days, and didn't get one.")
+	}
+
+	if _, err := NewScheduler(users.NewStaticSource("foo"), 1, WithRole("secondary", nil)); err == nil {
+		t.Errorf("want error on nil role source, and didn't get one.")
+	}
+
+	if _, err := NewScheduler(users.NewStaticSource("foo"), 1,
+		WithRole("secondary", users.NewStaticSource("bar")),
+		WithRole("secondary", users.NewStaticSource("baz"))); err == nil {
+		t.Errorf("want error on duplicate role, and didn't get one.")
+	}
+}
+
+func TestScheduleRoles(t *testing.T) {
+	for _, tc := range []struct {
+		desc    string
+		users   []string
+		roles   map[string][]string
+		wantErr bool
+		want    []string
+	}{
+		{
+			desc:  "separate sources",
+			users: []string{"a", "b", "c"},
+			roles: map[string][]string{"secondary": {"x", "y"}},
+			want:  []string{"a x", "b y", "c x", "a y"},
+		},
+		{
+			desc:  "same source, skipped user goes next",
+			users: []string{"a", "b", "c"},
+			roles: map[string][]string{"secondary": {"a", "b", "c"}},
+			want:  []string{"a b", "b a", "c a", "a c"},
+		},
+		{
+			desc:  "shadow after secondary",
+			users: []string{"a", "b"},
+			roles: map[string][]string{"secondary": {"a", "b"}, "shadow": {"a", "b", "n"}},
+			want:  []string{"a b n", "b a n", "a b n", "b a n"},
+		},
+		{
+			desc:    "nobody left",
+			users:   []string{"a"},
+			roles:   map[string][]string{"secondary": {"a"}},
+			wantErr: true,
+		},
+	} {
+		t.Run(tc.desc, func(t *testing.T) {
+			var opts []Option
+			for _, name := range []string{"secondary", "shadow"} {
+				if roleUsers, ok := tc.roles[name]; ok {
+					opts = append(opts, WithRole(name, users.NewStaticSource(roleUsers...)))
+				}
+			}
+
+			s, err := NewScheduler(users.NewStaticSource(tc.users...), 1, opts...)
+			if err != nil {
+				t.Fatalf("error creating scheduler: %v", err)
+			}
+
+			sched, err := s.Schedule(time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC), time.Date(2020, 1, 4, 0, 0, 0, 0, time.UTC))
+			if tc.wantErr {
+				if err == nil {
+					t.Errorf("want error and didn't get one.")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			var got []string
+			for _, shift := range sched.Shifts {
+				got = append(got, strings.Join(shift.Users(), " "))
+			}
+			if !reflect.DeepEqual(tc.want, got) {
+				t.Errorf("want users %q, got %q", tc.want, got)
+			}
+		})
+	}
+}
+
+func TestExtendScheduleRoles(t *testing.T) {
+	sched := &schedule.Schedule{
+		Shifts: []*schedule.Shift{
+			{
+				StartDate: time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC),
+				User:      "a",
+				Roles:     map[string]*schedule.Assignment{"secondary": {User: "x"}},
+			},
+			{
+				StartDate: time.Date(2020, 1, 2, 0, 0, 0, 0, time.UTC),
+				StopDate:  time.Date(2020, 1, 2, 0, 0, 0, 0, time.UTC),
+				User:      "b",
+				Roles:     map[string]*schedule.Assignment{"secondary": {User: "gone"}},
+			},
+		},
+	}
+
+	s, err := NewScheduler(users.NewStaticSource("a", "b"), 1,
+		WithRole("secondary", users.NewStaticSource("x", "y", "z")),
+		WithClock(func() time.Time { return time.Date(2020, 1, 1, 12, 0, 0, 0, time.UTC) }))
+	if err != nil {
+		t.Fatalf("error creating scheduler: %v", err)
+	}
+
+	if err := s.ExtendSchedule(sched, time.Date(2020, 1, 4, 0, 0, 0, 0, time.UTC), true); err != nil {
+		t.Fatalf("got error from ExtendSchedule: %v", err)
+	}
+
+	// The shift with a departed secondary is rescheduled, continuing after "x".
+	want := []string{"a x", "b y", "a z", "b x"}
+	var got []string
+	for _, shift := range sched.Shifts {
+		got = append(got, strings.Join(shift.Users(), " "))
+	}
+	if !reflect.DeepEqual(want, got) {
+		t.Errorf("want users %q, got %q", want, got)
+	}
+}
+
+func TestExtendScheduleOrderStable(t *testing.T) {
+	start := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+	mid := time.Date(2020, 1, 7, 0, 0, 0, 0, time.UTC)
+	stop := time.Date(2020, 1, 15, 0, 0, 0, 0, time.UTC)
+
+	for _, order := range users.Orders {
+		t.Run(string(order), func(t *testing.T) {
+			newScheduler := func() *Scheduler {
+				src, err := users.NewSource(order, 42, "d", "b", "a", "c")
+				if err != nil {
+					t.Fatalf("error creating source: %v", err)
+				}
+				secondary, err := users.NewSource(order, 43, "x", "y", "z")
+				if err != nil {
+					t.Fatalf("error creating source: %v", err)
+				}
+				s, err := NewScheduler(src, 1, WithRole("secondary", secondary))
+				if err != nil {
+					t.Fatalf("error creating scheduler: %v", err)
+				}
+				return s
+			}
+
+			want, err := newScheduler().Schedule(start, stop)
+			if err != nil {
+				t.Fatalf("got error from Schedule: %v", err)
+			}
+
+			got, err := newScheduler().Schedule(start, mid)
+			if err != nil {
+				t.Fatalf("got error from Schedule: %v", err)
+			}
+			if err := newScheduler().ExtendSchedule(got, stop, false); err != nil {
+				t.Fatalf("got error from ExtendSchedule: %v", err)
+			}
+
+			if !reflect.DeepEqual(want, got) {
+				t.Errorf("extended schedule differs from one generated at once.\nWant:\n%v\n\nGot:\n%v\n", want, got)
+			}
+		})
+	}
+}
+
+func TestScheduleRotation(t *testing.T) {
+	now := time.Date(2020, 1, 1, 12, 30, 15, 999, time.UTC)
+	s, err := NewScheduler(users.NewStaticSource("abc", "lmn", "xyz"), 2,
+		WithRole("secondary", users.NewStaticSource("x", "y")),
+		WithClock(func() time.Time { return now }))
+	if err != nil {
+		t.Fatalf("error creating scheduler: %v", err)
+	}
+
+	sched, err := s.Schedule(time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC), time.Date(2020, 1, 4, 0, 0, 0, 0, time.UTC))
+	if err != nil {
+		t.Fatalf("got error from Schedule: %v", err)
+	}
+
+	want := &schedule.Rotation{
+		GeneratedAt:       time.Date(2020, 1, 1, 12, 30, 15, 0, time.UTC),
+		ShiftDurationDays: 2,
+		LastShiftStart:    time.Date(2020, 1, 3, 0, 0, 0, 0, time.UTC),
+		Cursors: map[string]*users.Cursor{
+			schedule.PrimaryRole: {Users: []string{"abc", "lmn", "xyz"}, Next: 2},
+			"secondary":          {Users: []string{"x", "y"}, Cycles: 1},
+		},
+	}
+	if !reflect.DeepEqual(want, sched.Rotation) {
+		t.Errorf("want rotation:\n%+v\n\ngot:\n%+v", want, sched.Rotation)
+	}
+
+	// A new user sorting after the last one on duty waits for the next cycle, rather than going next.
+	s, err = NewScheduler(users.NewStaticSource("abc", "lmn", "mno", "xyz"), 2,
+		WithRole("secondary", users.NewStaticSource("x", "y")))
+	if err != nil {
+		t.Fatalf("error creating scheduler: %v", err)
+	}
+	if err := s.ExtendSchedule(sched, time.Date(2020, 1, 12, 0, 0, 0, 0, time.UTC), false); err != nil {
+		t.Fatalf("got error from ExtendSchedule: %v", err)
+	}
+
+	wantUsers := []string{"abc x", "lmn y", "xyz x", "abc y", "lmn x", "mno y"}
+	var got []string
+	for _, shift := range sched.Shifts {
+		got = append(got, strings.Join(shift.Users(), " "))
+	}
+	if !reflect.DeepEqual(wantUsers, got) {
+		t.Errorf("want users %q, got %q", wantUsers, got)
+	}
+}
+
+func TestExtendScheduleRotationPrune(t *testing.T) {
+	start := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+	newScheduler := func(opts ...Option) *Scheduler {
+		src, err := users.NewWeightedSource(users.NewShuffledSource(42, "a", "b", "c", "d"), map[string]float64{"b": 0.5})
+		if err != nil {
+			t.Fatalf("error creating source: %v", err)
+		}
+		s, err := NewScheduler(src, 1, opts...)
+		if err != nil {
+			t.Fatalf("error creating scheduler: %v", err)
+		}
+		return s
+	}
+
+	want, err := newScheduler().Schedule(start, time.Date(2020, 1, 20, 0, 0, 0, 0, time.UTC))
+	if err != nil {
+		t.Fatalf("got error from Schedule: %v", err)
+	}
+
+	got, err := newScheduler().Schedule(start, time.Date(2020, 1, 9, 0, 0, 0, 0, time.UTC))
+	if err != nil {
+		t.Fatalf("got error from Schedule: %v", err)
+	}
+	asOf := WithAsOf(time.Date(2020, 1, 7, 0, 0, 0, 0, time.UTC))
+	if err := newScheduler(asOf).ExtendSchedule(got, time.Date(2020, 1, 20, 0, 0, 0, 0, time.UTC), true); err != nil {
+		t.Fatalf("got error from ExtendSchedule: %v", err)
+	}
+
+	// Pruning drops the shifts the order was worked out from, but the shuffle and credits continue from the cursor.
+	if !reflect.DeepEqual(want.Shifts[6:], got.Shifts) {
+		t.Errorf("want shifts:\n%v\n\ngot:\n%v", want.Shifts[6:], got.Shifts)
+	}
+}
+
+func TestExtendScheduleLocked(t *testing.T) {
+	day := func(d int) time.Time {
+		return time.Date(2020, 1, d, 0, 0, 0, 0, time.UTC)
+	}
+
+	for _, tc := range []struct {
+		desc     string
+		duration int
+		opts     []Option
+		stop     time.Time
+		want     []string
+	}{
+		{
+			desc:     "rescheduled around",
+			duration: 1,
+			stop:     day(8),
+			want:     []string{"01:a", "02:b", "03:c", "04:b!", "05:gone!", "06:a", "07:b", "08:c"},
+		},
+		{
+			desc:     "shifts cut short",
+			duration: 2,
+			stop:     day(9),
+			// b isn't on duty right before their locked shift, so c takes it and b the one after.
+			want: []string{"01:a", "02:c", "04:b!", "05:gone!", "06:b", "08:a"},
+		},
+		{
+			desc:     "reassigned around",
+			duration: 1,
+			opts:     []Option{WithReassign()},
+			stop:     day(7),
+			want:     []string{"01:a", "02:b", "03:c", "04:b!", "05:gone!", "06:c", "07:a"},
+		},
+	} {
+		t.Run(tc.desc, func(t *testing.T) {
+			sched := &schedule.Schedule{
+				Shifts: []*schedule.Shift{
+					{StartDate: day(1), User: "a"},
+					{StartDate: day(2), User: "gone"},
+					{StartDate: day(3), User: "c"},
+					{StartDate: day(4), User: "b", Locked: true},
+					{StartDate: day(5), User: "gone", Locked: true},
+					{StartDate: day(6), StopDate: day(6), User: "c"},
+				},
+			}
+
+			s, err := NewScheduler(users.NewStaticSource("a", "b", "c"), tc.duration, append(tc.opts, WithAsOf(day(1)))...)
+			if err != nil {
+				t.Fatalf("error creating scheduler: %v", err)
+			}
+
+			if err := s.ExtendSchedule(sched, tc.stop, true); err != nil {
+				t.Fatalf("got error from ExtendSchedule: %v", err)
+			}
+
+			var got []string
+			for _, shift := range sched.Shifts {
+				u := shift.StartDate.Format("02") + ":" + shift.GetUser()
+				if shift.Locked {
+					u += "!"
+				}
+				got = append(got, u)
+			}
+			if !reflect.DeepEqual(tc.want, got) {
+				t.Errorf("want shifts %q, got %q", tc.want, got)
+			}
+		})
+	}
+}
+
+func TestExtendScheduleLockedNotBackToBack(t *testing.T) {
+	week := func(w int) time.Time {
+		return time.Date(2020, 6, 1+7*w, 0, 0, 0, 0, time.UTC)
+	}
+
+	// a is locked in for Mon 29 Jun, and d for Mon 20 Jul.
+	generate := func(t *testing.T) *schedule.Schedule {
+		s, err := NewScheduler(users.NewStaticSource("a", "b", "c", "d"), 7)
+		if err != nil {
+			t.Fatalf("error creating scheduler: %v", err)
+		}
+		sched, err := s.Schedule(week(0), week(8).AddDate(0, 0, -1))
+		if err != nil {
+			t.Fatalf("got error from Schedule: %v", err)
+		}
+		sched.Lock(week(4), week(4), true)
+		sched.Lock(week(7), week(7), true)
+		return sched
+	}
+
+	for _, user := range []string{"b", "c", "d"} {
+		t.Run("without "+user, func(t *testing.T) {
+			sched := generate(t)
+			var remaining []string
+			for _, u := range []string{"a", "b", "c", "d"} {
+				if u != user {
+					remaining = append(remaining, u)
+				}
+			}
+			s, err := NewScheduler(users.NewStaticSource(remaining...), 7, WithAsOf(week(0)))
+			if err != nil {
+				t.Fatalf("error creating scheduler: %v", err)
+			}
+
+			if err := s.ExtendSchedule(sched, week(10).AddDate(0, 0, -1), true); err != nil {
+				t.Fatalf("got error from ExtendSchedule: %v", err)
+			}
+
+			for i, shift := range sched.Shifts {
+				if i > 0 && strings.EqualFold(shift.GetUser(), sched.Shifts[i-1].GetUser()) {
+					t.Errorf("%v is on duty back to back, starting %v and %v", shift.GetUser(),
+						sched.Shifts[i-1].StartDate.Format(DateFormat), shift.StartDate.Format(DateFormat))
+				}
+			}
+			if !sched.Shifts[4].Locked || sched.Shifts[4].User != "a" {
+				t.Errorf("want locked shift kept, got %v", sched.Shifts[4])
+			}
+		})
+	}
+}
+
+func TestScheduleUnavailable(t *testing.T) {
+	day := func(d int) time.Time { return time.Date(2020, 1, d, 0, 0, 0, 0, time.UTC) }
+
+	for _, tc := range []struct {
+		desc        string
+		roster      *schedule.Roster
+		schedRoster *schedule.Roster
+		roles       []string
+		wantErr     bool
+		want        []string
+	}{
+		{
+			desc: "no roster",
+			want: []string{"a", "b", "c", "a", "b", "c"},
+		},
+		{
+			desc: "skipped user takes the next shift",
+			roster: &schedule.Roster{Members: []*schedule.Member{
+				{User: "B", Unavailable: []*schedule.Period{{Start: day(2)}}},
+			}},
+			want: []string{"a", "c", "b", "a", "b", "c"},
+		},
+		{
+			desc: "unavailable for several shifts",
+			roster: &schedule.Roster{Members: []*schedule.Member{
+				{User: "a", Unavailable: []*schedule.Period{{Start: day(1), Stop: day(3)}}},
+			}},
+			want: []string{"b", "c", "b", "a", "c", "a"},
+		},
+		{
+			desc: "schedule roster",
+			schedRoster: &schedule.Roster{Members: []*schedule.Member{
+				{User: "c", Unavailable: []*schedule.Period{{Start: day(3)}}},
+			}},
+			want: []string{"a", "b", "a", "c", "b", "c"},
+		},
+		{
+			desc:  "roles",
+			roles: []string{"a", "b", "c"},
+			roster: &schedule.Roster{Members: []*schedule.Member{
+				{User: "b", Unavailable: []*schedule.Period{{Start: day(1), Stop: day(2)}}},
+			}},
+			want: []string{"a c", "c a", "b a", "a b", "b c", "c b"},
+		},
+		{
+			desc: "joins the rotation",
+			roster: &schedule.Roster{Members: []*schedule.Member{
+				{User: "c", JoinDate: day(4)},
+			}},
+			want: []string{"a", "b", "a", "b", "c", "a"},
+		},
+		{
+			desc: "leaves the rotation",
+			schedRoster: &schedule.Roster{Members: []*schedule.Member{
+				{User: "a", LeaveDate: day(3)},
+			}},
+			want: []string{"a", "b", "c", "b", "c", "b"},
+		},
+		{
+			desc: "everyone unavailable",
+			roster: &schedule.Roster{Members: []*schedule.Member{
+				{User: "a", Unavailable: []*schedule.Period{{Start: day(4)}}},
+				{User: "b", Unavailable: []*schedule.Period{{Start: day(4)}}},
+				{User: "c", Unavailable: []*schedule.Period{{Start: day(4)}}},
+			}},
+			wantErr: true,
+		},
+	} {
+		t.Run(tc.desc, func(t *testing.T) {
+			opts := []Option{WithRoster(tc.roster)}
+			if tc.roles != nil {
+				opts = append(opts, WithRole("secondary", users.NewStaticSource(tc.roles...)))
+			}
+
+			s, err := NewScheduler(users.NewStaticSource("a", "b", "c"), 1, opts...)
+			if err != nil {
+				t.Fatalf("error creating scheduler: %v", err)
+			}
+
+			sched := &schedule.Schedule{Roster: tc.schedRoster}
+			err = s.extendSchedule(sched, day(1), day(6))
+			if tc.wantErr {
+				if err == nil {
+					t.Errorf("want error and didn't get one.")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			var got []string
+			for _, shift := range sched.Shifts {
+				got = append(got, strings.Join(shift.Users(), " "))
+			}
+			if !reflect.DeepEqual(tc.want, got) {
+				t.Errorf("want users %q, got %q", tc.want, got)
+			}
+
+			if problems := sched.Lint(tc.roster, nil, nil, nil); len(problems) != 0 {
+				t.Errorf("want no lint problems, got %v", problems)
+			}
+		})
+	}
+}
+
+func TestExtendScheduleLeaveDate(t *testing.T) {
+	day := func(d int) time.Time { return time.Date(2020, 1, d, 0, 0, 0, 0, time.UTC) }
+
+	sched := &schedule.Schedule{
+		Roster: &schedule.Roster{Members: []*schedule.Member{{User: "a", LeaveDate: day(3)}}},
+	}
+	for i, u := range []string{"a", "b", "c", "a", "b", "c"} {
+		sched.Shifts = append(sched.Shifts, &schedule.Shift{StartDate: day(i + 1), User: u})
+	}
+	sched.LastShift().StopDate = day(6)
+
+	s, err := NewScheduler(users.NewStaticSource("a", "b", "c"), 1, WithAsOf(day(1)))
+	if err != nil {
+		t.Fatalf("error creating scheduler: %v", err)
+	}
+
+	if err := s.ExtendSchedule(sched, day(8), true); err != nil {
+		t.Fatalf("got error from ExtendSchedule: %v", err)
+	}
+
+	want := []string{"a", "b", "c", "b", "c", "b", "c", "b"}
+	var got []string
+	for _, shift := range sched.Shifts {
+		got = append(got, shift.GetUser())
+	}
+	if !reflect.DeepEqual(want, got) {
+		t.Errorf("want users %q, got %q", want, got)
+	}
+}
+
+func TestScheduleHolidays(t *testing.T) {
+	day := func(d int) time.Time { return time.Date(2020, 1, d, 0, 0, 0, 0, time.UTC) }
+	holidays := []*schedule.Holiday{
+		{Date: day(1), Name: "New Year's Day"},
+		{Date: day(4)},
+		{Date: day(4), Name: "Same day, another region"},
+		{Date: day(7)},
+	}
+
+	for _, tc := range []struct {
+		desc       string
+		roster     *schedule.Roster
+		counts     map[string]int
+		want       []string
+		wantCounts map[string]int
+	}{
+		{
+			desc:       "spread evenly",
+			want:       []string{"a", "b", "c", "b", "a", "c", "c", "a", "b"},
+			wantCounts: map[string]int{"a": 1, "b": 1, "c": 1},
+		},
+		{
+			desc:       "previous counts",
+			counts:     map[string]int{"a": 2, "b": 1},
+			want:       []string{"c", "a", "b", "b", "a", "c", "c", "a", "b"},
+			wantCounts: map[string]int{"a": 2, "b": 2, "c": 2},
+		},
+		{
+			desc: "fewest is unavailable",
+			roster: &schedule.Roster{Members: []*schedule.Member{
+				{User: "c", Unavailable: []*schedule.Period{{Start: day(1)}}},
+			}},
+			counts:     map[string]int{"a": 1, "b": 1},
+			want:       []string{"a", "b", "c", "c", "a", "b", "b", "a", "c"},
+			wantCounts: map[string]int{"a": 2, "b": 2, "c": 1},
+		},
+	} {
+		t.Run(tc.desc, func(t *testing.T) {
+			s, err := NewScheduler(users.NewStaticSource("a", "b", "c"), 1, WithHolidays(holidays), WithRoster(tc.roster))
+			if err != nil {
+				t.Fatalf("error creating scheduler: %v", err)
+			}
+
+			sched := &schedule.Schedule{HolidayCounts: tc.counts}
+			if err := s.extendSchedule(sched, day(1), day(9)); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			var got []string
+			for _, shift := range sched.Shifts {
+				got = append(got, shift.User)
+			}
+			if !reflect.DeepEqual(tc.want, got) {
+				t.Errorf("want users %q, got %q", tc.want, got)
+			}
+
+			if !reflect.DeepEqual(tc.wantCounts, sched.HolidayCounts) {
+				t.Errorf("want holiday counts %v, got %v", tc.wantCounts, sched.HolidayCounts)
+			}
+		})
+	}
+}
+
+func TestPruneNotFoundUsersHolidays(t *testing.T) {
+	day := func(d int) time.Time { return time.Date(2020, 1, d, 0, 0, 0, 0, time.UTC) }
+	sched := &schedule.Schedule{
+		HolidayCounts: map[string]int{"a": 3, "gone": 1},
+		Shifts: []*schedule.Shift{
+			{StartDate: day(1), User: "a"},
+			{StartDate: day(2), User: "gone"},
+			{StartDate: day(3), StopDate: day(3), User: "a"},
+		},
+	}
+
+	s, err := NewScheduler(users.NewStaticSource("a", "b"), 1,
+		WithHolidays([]*schedule.Holiday{{Date: day(1)}, {Date: day(2)}, {Date: day(3)}}))
+	if err != nil {
+		t.Fatalf("error creating scheduler: %v", err)
+	}
+
+	if err := s.pruneNotFoundUsers(sched); err != nil {
+		t.Fatalf("got error from pruneNotFoundUsers: %v", err)
+	}
+
+	want := map[string]int{"a": 2}
+	if !reflect.DeepEqual(want, sched.HolidayCounts) {
+		t.Errorf("want holiday counts %v, got %v", want, sched.HolidayCounts)
+	}
 }
 
 func TestSchedule(t *testing.T) {
@@ -116,6 +741,12 @@ func TestSchedule(t *testing.T) {
 				return
 			}
 
+			// Where the rotation left off is covered by TestScheduleRotation.
+			if got.Rotation == nil {
+				t.Errorf("want rotation saved, got none.")
+			}
+			got.Rotation = nil
+
 			if !reflect.DeepEqual(tc.want, got) {
 				t.Errorf("got schedule different from expected.\nWant:\n%v\n\nGot:\n%v\n", tc.want, got)
 			}
@@ -366,15 +997,12 @@ func TestExtendSchedule(t *testing.T) {
 		},
 	} {
 		t.Run(tc.desc, func(t *testing.T) {
-			s, err := NewScheduler(users.NewStaticSource(tc.users...), tc.durationDays)
+			s, err := NewScheduler(users.NewStaticSource(tc.users...), tc.durationDays,
+				WithClock(func() time.Time { return tc.today }))
 			if err != nil {
 				t.Fatalf("error creating scheduler: %v", err)
 			}
 
-			if tc.prune {
-				today = func() time.Time { return tc.today }
-			}
-
 			err = s.ExtendSchedule(tc.input, tc.stop, tc.prune)
 			if tc.wantErr && err == nil {
 				t.Errorf("err expected and not received.")
@@ -387,6 +1015,11 @@ func TestExtendSchedule(t *testing.T) {
 				return
 			}
 
+			if tc.input.Rotation == nil {
+				t.Errorf("want rotation saved, got none.")
+			}
+			tc.input.Rotation = nil
+
 			if !reflect.DeepEqual(tc.want, tc.input) {
 				t.Errorf("got schedule different from expected.\nWant:\n%v\n\nGot:\n%v\n", tc.want, tc.input)
 			}
@@ -394,6 +1027,159 @@ func TestExtendSchedule(t *testing.T) {
 	}
 }
 
+func TestScheduleHandoff(t *testing.T) {
+	s, err := NewScheduler(users.NewStaticSource("first", "second"), 7, WithHandoff("10:00", "America/Los_Angeles"))
+	if err != nil {
+		t.Fatalf("error creating scheduler: %v", err)
+	}
+
+	got, err := s.Schedule(time.Date(2020, 6, 1, 0, 0, 0, 0, time.UTC), time.Date(2020, 6, 14, 0, 0, 0, 0, time.UTC))
+	if err != nil {
+		t.Fatalf("got error from Schedule: %v", err)
+	}
+
+	if got.HandoffTime != "10:00" || got.TimeZone != "America/Los_Angeles" {
+		t.Errorf("want handoff at 10:00 America/Los_Angeles, got %v %v", got.HandoffTime, got.TimeZone)
+	}
+}
+
+func TestExtendScheduleHandoff(t *testing.T) {
+	la, err := time.LoadLocation("America/Los_Angeles")
+	if err != nil {
+		t.Fatalf("error loading location: %v", err)
+	}
+
+	for _, tc := range []struct {
+		desc      string
+		now       time.Time
+		wantFirst time.Time
+	}{
+		{
+			desc:      "before handoff, the previous shift is still on duty",
+			now:       time.Date(2020, 1, 3, 9, 0, 0, 0, la),
+			wantFirst: time.Date(2020, 1, 2, 0, 0, 0, 0, time.UTC),
+		},
+		{
+			desc:      "after handoff, already the next day in UTC",
+			now:       time.Date(2020, 1, 3, 20, 0, 0, 0, la),
+			wantFirst: time.Date(2020, 1, 3, 0, 0, 0, 0, time.UTC),
+		},
+	} {
+		t.Run(tc.desc, func(t *testing.T) {
+			sched := &schedule.Schedule{
+				Shifts: []*schedule.Shift{
+					{
+						StartDate: time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC),
+						User:      "first",
+					},
+					{
+						StartDate: time.Date(2020, 1, 2, 0, 0, 0, 0, time.UTC),
+						User:      "second",
+					},
+					{
+						StartDate: time.Date(2020, 1, 3, 0, 0, 0, 0, time.UTC),
+						StopDate:  time.Date(2020, 1, 3, 0, 0, 0, 0, time.UTC),
+						User:      "first",
+					},
+				},
+			}
+
+			s, err := NewScheduler(users.NewStaticSource("first", "second"), 1,
+				WithHandoff("10:00", "America/Los_Angeles"), WithClock(func() time.Time { return tc.now }))
+			if err != nil {
+				t.Fatalf("error creating scheduler: %v", err)
+			}
+
+			if err := s.ExtendSchedule(sched, time.Date(2020, 1, 5, 0, 0, 0, 0, time.UTC), true); err != nil {
+				t.Fatalf("got error from ExtendSchedule: %v", err)
+			}
+
+			if got := sched.Shifts[0].StartDate; tc.wantFirst != got {
+				t.Errorf("want first shift on %v, got %v", tc.wantFirst, got)
+			}
+			if sched.HandoffTime != "10:00" || sched.TimeZone != "America/Los_Angeles" {
+				t.Errorf("want handoff at 10:00 America/Los_Angeles, got %v %v", sched.HandoffTime, sched.TimeZone)
+			}
+		})
+	}
+}
+
+func TestExtendScheduleToday(t *testing.T) {
+	la, err := time.LoadLocation("America/Los_Angeles")
+	if err != nil {
+		t.Fatalf("error loading location: %v", err)
+	}
+	// A US evening cron job, when it's already the next day in UTC.
+	evening := time.Date(2020, 1, 2, 20, 0, 0, 0, la)
+
+	for _, tc := range []struct {
+		desc      string
+		timeZone  string
+		opts      []Option
+		wantFirst time.Time
+	}{
+		{
+			desc:      "UTC by default",
+			wantFirst: time.Date(2020, 1, 3, 0, 0, 0, 0, time.UTC),
+		},
+		{
+			desc:      "schedule time zone",
+			timeZone:  "America/Los_Angeles",
+			wantFirst: time.Date(2020, 1, 2, 0, 0, 0, 0, time.UTC),
+		},
+		{
+			desc:      "scheduler time zone",
+			opts:      []Option{WithHandoff("", "America/Los_Angeles")},
+			wantFirst: time.Date(2020, 1, 2, 0, 0, 0, 0, time.UTC),
+		},
+		{
+			desc:      "as of",
+			timeZone:  "America/Los_Angeles",
+			opts:      []Option{WithAsOf(time.Date(2020, 1, 4, 0, 0, 0, 0, time.UTC))},
+			wantFirst: time.Date(2020, 1, 4, 0, 0, 0, 0, time.UTC),
+		},
+	} {
+		t.Run(tc.desc, func(t *testing.T) {
+			sched := &schedule.Schedule{
+				TimeZone: tc.timeZone,
+				Shifts: []*schedule.Shift{
+					{
+						StartDate: time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC),
+						User:      "first",
+					},
+					{
+						StartDate: time.Date(2020, 1, 2, 0, 0, 0, 0, time.UTC),
+						User:      "second",
+					},
+					{
+						StartDate: time.Date(2020, 1, 3, 0, 0, 0, 0, time.UTC),
+						User:      "first",
+					},
+					{
+						StartDate: time.Date(2020, 1, 4, 0, 0, 0, 0, time.UTC),
+						StopDate:  time.Date(2020, 1, 4, 0, 0, 0, 0, time.UTC),
+						User:      "second",
+					},
+				},
+			}
+
+			opts := append([]Option{WithClock(func() time.Time { return evening })}, tc.opts...)
+			s, err := NewScheduler(users.NewStaticSource("first", "second"), 1, opts...)
+			if err != nil {
+				t.Fatalf("error creating scheduler: %v", err)
+			}
+
+			if err := s.ExtendSchedule(sched, time.Date(2020, 1, 6, 0, 0, 0, 0, time.UTC), true); err != nil {
+				t.Fatalf("got error from ExtendSchedule: %v", err)
+			}
+
+			if got := sched.Shifts[0].StartDate; tc.wantFirst != got {
+				t.Errorf("want first shift on %v, got %v", tc.wantFirst, got)
+			}
+		})
+	}
+}
+
 func TestPruneOldSchedules(t *testing.T) {
 	for _, tc := range []struct {
 		desc       string
@@ -678,7 +1464,9 @@ func TestPruneOldSchedules(t *testing.T) {
 			if err := tc.sched.Validate(); err != nil {
 				t.Fatalf("invalid schedule beforehand: %v", err)
 			}
-			s.pruneOldShifts(tc.pruneStart, tc.sched)
+			if err := s.pruneOldShifts(tc.pruneStart, tc.sched); err != nil {
+				t.Fatalf("got error from pruneOldShifts: %v", err)
+			}
 			if err := tc.sched.Validate(); err != nil {
 				t.Errorf("invalid pruned schedule: %v", err)
 			}
@@ -804,7 +1592,9 @@ func TestPruneNotFoundUsers(t *testing.T) {
 				t.Fatalf("invalid schedule beforehand: %v", err)
 			}
 
-			s.pruneNotFoundUsers(tc.sched)
+			if err := s.pruneNotFoundUsers(tc.sched); err != nil {
+				t.Fatalf("got error from pruneNotFoundUsers: %v", err)
+			}
 			if err := tc.sched.Validate(); err != nil {
 				t.Errorf("invalid pruned schedule: %v", err)
 			}
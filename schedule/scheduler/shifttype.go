@@ -0,0 +1,99 @@
+package scheduler
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/spinnaker/rotation-scheduler/schedule"
+	"github.com/spinnaker/rotation-scheduler/users"
+)
+
+// WithShiftType interleaves shifts of another type, like "weekend", with the Scheduler's own: shifts starting on any of
+// weekdays get the type, and their primary role is filled from source, in its own order, instead of the Scheduler's
+// userSource. Paired with the "workweek" Pattern, it makes a business hours rotation alternate with a weekend one.
+// Nobody is on duty as the primary in two shifts in a row of different types.
+func WithShiftType(name string, source users.Source, weekdays ...time.Weekday) Option {
+	return func(s *Scheduler) error {
+		if name == "" {
+			return fmt.Errorf("shift type name cannot be empty")
+		}
+
+		if source == nil {
+			return fmt.Errorf("no user source specified for shift type %v", name)
+		}
+
+		if len(weekdays) == 0 {
+			return fmt.Errorf("no weekdays specified for shift type %v", name)
+		}
+
+		for _, t := range s.types {
+			if t.shiftType == name {
+				return fmt.Errorf("shift type %v specified more than once", name)
+			}
+			for _, d := range weekdays {
+				if t.startsOn(d) {
+					return fmt.Errorf("shifts starting on %v cannot be both %v and %v", d, t.shiftType, name)
+				}
+			}
+		}
+
+		s.types = append(s.types, &role{
+			name:      schedule.PrimaryRole,
+			source:    source,
+			shiftType: name,
+			weekdays:  weekdays,
+		})
+		return nil
+	}
+}
+
+// startsOn is true if shifts of the role's type start on weekday.
+func (r *role) startsOn(weekday time.Weekday) bool {
+	for _, d := range r.weekdays {
+		if d == weekday {
+			return true
+		}
+	}
+	return false
+}
+
+// primaryOf returns the primary role of a shift starting on start: that of the region on duty, if the Scheduler follows
+// the sun, or of the shift type for its weekday, if there is one, or the default.
+func (s *Scheduler) primaryOf(start time.Time) *role {
+	if f := s.followTheSun(); f != nil {
+		region := f.Region(start)
+		for _, t := range s.types {
+			if t.shiftType == region.Name {
+				return t
+			}
+		}
+	}
+
+	for _, t := range s.types {
+		if t.startsOn(start.Weekday()) {
+			return t
+		}
+	}
+	return s.primary
+}
+
+// filled returns every role the Scheduler fills: the default primary role, unless the Scheduler follows the sun, the
+// primary roles of each shift type or region, and any others.
+func (s *Scheduler) filled() []*role {
+	roles := append([]*role{}, s.types...)
+	if len(s.regions) == 0 {
+		roles = append([]*role{s.primary}, roles...)
+	}
+	return append(roles, s.roles...)
+}
+
+// rolesOf returns the roles the Scheduler fills for shift: the primary role of its type, followed by any others.
+func (s *Scheduler) rolesOf(shift *schedule.Shift) []*role {
+	primary := s.primary
+	for _, t := range s.types {
+		if t.shiftType == shift.Type {
+			primary = t
+		}
+	}
+	return append([]*role{primary}, s.roles...)
+}
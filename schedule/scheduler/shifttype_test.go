@@ -0,0 +1,75 @@
+package scheduler
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/spinnaker/rotation-scheduler/users"
+)
+
+func TestWithShiftType(t *testing.T) {
+	weekend := WithShiftType("weekend", users.NewStaticSource("c", "d"), time.Saturday, time.Sunday)
+
+	for _, tc := range []struct {
+		desc string
+		opts []Option
+	}{
+		{desc: "empty name", opts: []Option{WithShiftType("", users.NewStaticSource("c"), time.Saturday)}},
+		{desc: "nil source", opts: []Option{WithShiftType("weekend", nil, time.Saturday)}},
+		{desc: "no weekdays", opts: []Option{WithShiftType("weekend", users.NewStaticSource("c"))}},
+		{desc: "duplicate type", opts: []Option{weekend, WithShiftType("weekend", users.NewStaticSource("e"), time.Friday)}},
+		{desc: "overlapping weekdays", opts: []Option{weekend, WithShiftType("sunday", users.NewStaticSource("e"), time.Sunday)}},
+	} {
+		t.Run(tc.desc, func(t *testing.T) {
+			if _, err := NewScheduler(users.NewStaticSource("a"), 1, tc.opts...); err == nil {
+				t.Errorf("want error and didn't get one.")
+			}
+		})
+	}
+}
+
+func TestScheduleShiftTypes(t *testing.T) {
+	day := func(d int) time.Time {
+		return time.Date(2020, 3, d, 0, 0, 0, 0, time.UTC)
+	}
+
+	s, err := NewScheduler(users.NewStaticSource("a", "b", "c"), 7,
+		WithPattern(WorkweekPattern{}),
+		WithShiftType("weekend", users.NewStaticSource("c", "d"), time.Saturday, time.Sunday))
+	if err != nil {
+		t.Fatalf("error creating scheduler: %v", err)
+	}
+
+	sched, err := s.Schedule(day(2), day(22))
+	if err != nil {
+		t.Fatalf("got error from Schedule: %v", err)
+	}
+
+	got := func() []string {
+		var got []string
+		for _, shift := range sched.Shifts {
+			got = append(got, shift.StartDate.Format("Mon 02")+" "+shift.Type+":"+shift.User)
+		}
+		return got
+	}
+
+	// c takes the weekend after their business hours shift, so d takes it again.
+	want := []string{"Mon 02 :a", "Sat 07 weekend:c", "Mon 09 :b", "Sat 14 weekend:d", "Mon 16 :c", "Sat 21 weekend:d"}
+	if !reflect.DeepEqual(want, got()) {
+		t.Errorf("want shifts %q, got %q", want, got())
+	}
+
+	if _, ok := sched.Rotation.Cursors["weekend/primary"]; !ok {
+		t.Errorf("want a cursor for the weekend rotation, got %v", sched.Rotation.Cursors)
+	}
+
+	if err := s.ExtendSchedule(sched, day(29), false); err != nil {
+		t.Fatalf("got error from ExtendSchedule: %v", err)
+	}
+
+	want = append(want, "Mon 23 :a", "Sat 28 weekend:c")
+	if !reflect.DeepEqual(want, got()) {
+		t.Errorf("want extended shifts %q, got %q", want, got())
+	}
+}
@@ -0,0 +1,96 @@
+package users
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Cursor is where a Source is in its rotation, so the rotation can be saved, and continued exactly later.
+type Cursor struct {
+	// Users are the users of the current cycle, in the order they take turns.
+	Users []string `json:"users"`
+
+	// Next is the index in Users of the user with the next turn.
+	Next int `json:"next"`
+
+	// Cycles is how many cycles were completed before the current one.
+	Cycles int `json:"cycles,omitempty"`
+
+	// Credits are the credits of weighted users, keyed by lower-cased user. See WeightedSource.
+	Credits map[string]float64 `json:"credits,omitempty"`
+}
+
+// Seeker is implemented by Sources that can save their position in the rotation, and continue from it later.
+type Seeker interface {
+	// Cursor returns the position of this Source, or nil if it can't be saved.
+	Cursor() *Cursor
+
+	// Seek continues the rotation from c. Users of the current cycle who are no longer in this Source are skipped, and
+	// users new to it join from the next cycle, or the current one if it hasn't begun.
+	Seek(c *Cursor)
+}
+
+// CursorOf returns the position of src, or nil if it isn't a Seeker.
+func CursorOf(src Source) *Cursor {
+	if s, ok := src.(Seeker); ok {
+		return s.Cursor()
+	}
+	return nil
+}
+
+// Seek continues src from c, and is true if it could. It can't if src isn't a Seeker, or c is nil.
+func Seek(src Source, c *Cursor) bool {
+	s, ok := src.(Seeker)
+	if !ok || c == nil {
+		return false
+	}
+	s.Seek(c)
+	return true
+}
+
+// Validate confirms Next is within Users, and the counts and credits aren't negative.
+func (c *Cursor) Validate() error {
+	if c == nil {
+		return nil
+	}
+
+	if c.Next < 0 || c.Next > len(c.Users) {
+		return fmt.Errorf("next %v is outside of the %v users", c.Next, len(c.Users))
+	}
+
+	if c.Cycles < 0 {
+		return fmt.Errorf("cycles cannot be negative")
+	}
+
+	for user, credit := range c.Credits {
+		if credit < 0 {
+			return fmt.Errorf("credit for %v cannot be negative", user)
+		}
+	}
+	return nil
+}
+
+// unfinished returns the rest of the cycle of c, with users lower-cased, and Next at the next one src contains. It's nil
+// if the cycle hasn't begun, so the next one can include new users, or if there's nobody left in it.
+func (c *Cursor) unfinished(src Source) *Cursor {
+	if c.Next == 0 {
+		return nil
+	}
+
+	u := &Cursor{Next: c.Next}
+	for _, user := range c.Users {
+		u.Users = append(u.Users, strings.ToLower(user))
+	}
+	if !u.skip(src) {
+		return nil
+	}
+	return u
+}
+
+// skip moves Next past users src doesn't contain, and is true if there are any users left in the cycle.
+func (c *Cursor) skip(src Source) bool {
+	for c.Next < len(c.Users) && !src.Contains(c.Users[c.Next]) {
+		c.Next++
+	}
+	return c.Next < len(c.Users)
+}
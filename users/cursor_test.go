@@ -0,0 +1,128 @@
+package users
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestSeekContinues(t *testing.T) {
+	for _, order := range Orders {
+		t.Run(string(order), func(t *testing.T) {
+			newSource := func() Source {
+				src, err := NewSource(order, 7, "d", "b", "a", "c")
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				ws, err := NewWeightedSource(src, map[string]float64{"b": 0.5, "c": 0.3})
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				return ws
+			}
+
+			want := next(newSource(), 30)
+
+			for _, turns := range []int{0, 1, 4, 11} {
+				src := newSource()
+				next(src, turns)
+				c := CursorOf(src)
+				if err := c.Validate(); err != nil {
+					t.Fatalf("invalid cursor after %v turns: %v", turns, err)
+				}
+
+				resumed := newSource()
+				if !Seek(resumed, c) {
+					t.Fatalf("want source to seek and it didn't.")
+				}
+				if got := next(resumed, len(want)-turns); !reflect.DeepEqual(want[turns:], got) {
+					t.Errorf("seeking after %v turns, want %v, got %v", turns, want[turns:], got)
+				}
+			}
+		})
+	}
+}
+
+func TestSeekMembershipChange(t *testing.T) {
+	for _, tc := range []struct {
+		desc   string
+		src    Source
+		cursor *Cursor
+		want   []string
+	}{
+		{
+			desc:   "alphabetical, new user joins next cycle",
+			src:    NewStaticSource("aaa", "abc", "lmn", "xyz"),
+			cursor: &Cursor{Users: []string{"abc", "lmn", "xyz"}, Next: 1},
+			want:   []string{"lmn", "xyz", "aaa", "abc", "lmn", "xyz"},
+		},
+		{
+			desc:   "alphabetical, departed user skipped",
+			src:    NewStaticSource("abc", "xyz"),
+			cursor: &Cursor{Users: []string{"abc", "lmn", "xyz"}, Next: 1},
+			want:   []string{"xyz", "abc", "xyz"},
+		},
+		{
+			desc:   "alphabetical, end of cycle",
+			src:    NewStaticSource("abc", "lmn"),
+			cursor: &Cursor{Users: []string{"abc", "lmn"}, Next: 2},
+			want:   []string{"abc", "lmn"},
+		},
+		{
+			desc:   "roster, continues cycle in its own order",
+			src:    NewOrderedSource("xyz", "abc", "new"),
+			cursor: &Cursor{Users: []string{"gone", "abc", "xyz"}, Next: 1},
+			want:   []string{"abc", "xyz", "xyz", "abc", "new"},
+		},
+		{
+			desc:   "roster, new user joins cycle that hasn't begun",
+			src:    NewOrderedSource("xyz", "abc", "new"),
+			cursor: &Cursor{Users: []string{"xyz", "abc"}, Cycles: 2},
+			want:   []string{"xyz", "abc", "new"},
+		},
+		{
+			desc:   "shuffle, departed user skipped",
+			src:    NewShuffledSource(0, "abc", "xyz"),
+			cursor: &Cursor{Users: []string{"xyz", "gone", "abc"}, Next: 1},
+			want:   []string{"abc"},
+		},
+		{
+			desc:   "least recent, new user first",
+			src:    NewLeastRecentSource("abc", "lmn", "new"),
+			cursor: &Cursor{Users: []string{"abc", "gone", "lmn"}, Next: 2},
+			want:   []string{"new", "lmn", "abc", "new"},
+		},
+	} {
+		t.Run(tc.desc, func(t *testing.T) {
+			if !Seek(tc.src, tc.cursor) {
+				t.Fatalf("want source to seek and it didn't.")
+			}
+			if got := next(tc.src, len(tc.want)); !reflect.DeepEqual(tc.want, got) {
+				t.Errorf("want %v, got %v", tc.want, got)
+			}
+		})
+	}
+}
+
+func TestCursorValidate(t *testing.T) {
+	for _, tc := range []struct {
+		desc    string
+		cursor  *Cursor
+		wantErr bool
+	}{
+		{desc: "nil"},
+		{desc: "valid", cursor: &Cursor{Users: []string{"a", "b"}, Next: 2, Cycles: 1, Credits: map[string]float64{"a": 0.5}}},
+		{desc: "next past users", cursor: &Cursor{Users: []string{"a"}, Next: 2}, wantErr: true},
+		{desc: "negative next", cursor: &Cursor{Next: -1}, wantErr: true},
+		{desc: "negative cycles", cursor: &Cursor{Cycles: -1}, wantErr: true},
+		{desc: "negative credit", cursor: &Cursor{Credits: map[string]float64{"a": -1}}, wantErr: true},
+	} {
+		t.Run(tc.desc, func(t *testing.T) {
+			err := tc.cursor.Validate()
+			if tc.wantErr && err == nil {
+				t.Errorf("err expected and not received.")
+			} else if !tc.wantErr && err != nil {
+				t.Errorf("got unexpected error: %v", err)
+			}
+		})
+	}
+}
@@ -0,0 +1,404 @@
+package users
+
+import (
+	"container/ring"
+	"fmt"
+	"math/rand"
+	"sort"
+	"strings"
+)
+
+// Order is a strategy for the order users take turns in.
+type Order string
+
+const (
+	// Alphabetical cycles through users sorted alphabetically. It's the order of a StaticSource.
+	Alphabetical Order = "alphabetical"
+
+	// RosterOrder cycles through users in the order they're given.
+	RosterOrder Order = "roster"
+
+	// Shuffled cycles through users in a different random order each time, decided by a seed, so the order is the
+	// same every time the schedule is generated.
+	Shuffled Order = "shuffle"
+
+	// LeastRecent gives each turn to whoever's last turn was longest ago, with users that have never had a turn first,
+	// in the order they're given.
+	LeastRecent Order = "least-recent"
+)
+
+// Orders are all the valid Orders.
+var Orders = []Order{Alphabetical, RosterOrder, Shuffled, LeastRecent}
+
+// NewSource creates a Source of users that take turns in order. The seed is only used by Shuffled.
+func NewSource(order Order, seed int64, users ...string) (Source, error) {
+	switch order {
+	case Alphabetical, "":
+		return NewStaticSource(users...), nil
+	case RosterOrder:
+		return NewOrderedSource(users...), nil
+	case Shuffled:
+		return NewShuffledSource(seed, users...), nil
+	case LeastRecent:
+		return NewLeastRecentSource(users...), nil
+	default:
+		return nil, fmt.Errorf("invalid order %q. Must be one of %v", order, Orders)
+	}
+}
+
+// Resumer is implemented by Sources whose position depends on more than the last user to have a turn.
+type Resumer interface {
+	// Resume positions this Source to continue after history, which is every user to have had a turn so far, oldest
+	// first.
+	Resume(history []string)
+}
+
+// Resume positions src to continue after history, which is every user to have had a turn so far, oldest first. Sources
+// that aren't Resumers start after the last user in history.
+func Resume(src Source, history []string) {
+	if r, ok := src.(Resumer); ok {
+		r.Resume(history)
+		return
+	}
+
+	last := ""
+	if len(history) != 0 {
+		last = history[len(history)-1]
+	}
+	src.StartAfter(last)
+}
+
+// OrderedSource cycles through users in the order they were given.
+type OrderedSource struct {
+	nextUser  *ring.Ring
+	usernames map[string]bool
+	ordered   []string
+
+	// unfinished is the rest of a cycle continued with Seek, which is finished before the ring.
+	unfinished *Cursor
+	cycles     int
+}
+
+var (
+	_ Source = &OrderedSource{}
+	_ Seeker = &OrderedSource{}
+)
+
+// NewOrderedSource creates an OrderedSource of users, lower-cased, in the order given.
+func NewOrderedSource(users ...string) *OrderedSource {
+	o := &OrderedSource{
+		nextUser:  ring.New(len(users)),
+		usernames: make(map[string]bool, len(users)),
+	}
+	for _, u := range users {
+		u = strings.ToLower(u)
+		o.ordered = append(o.ordered, u)
+		o.usernames[u] = true
+		o.nextUser.Value = u
+		o.nextUser = o.nextUser.Next()
+	}
+	return o
+}
+
+// StartAfter positions this Source to continue after user. If user isn't in the Source, it starts from the first user.
+func (o *OrderedSource) StartAfter(user string) {
+	if len(o.ordered) == 0 {
+		return
+	}
+
+	user = strings.ToLower(user)
+	o.unfinished = nil
+	for i := 0; i < o.nextUser.Len(); i++ {
+		if o.nextUser.Value.(string) == o.ordered[0] {
+			break
+		}
+		o.nextUser = o.nextUser.Next()
+	}
+
+	if !o.usernames[user] {
+		return
+	}
+	for o.nextUser.Prev().Value.(string) != user {
+		o.nextUser = o.nextUser.Next()
+	}
+}
+
+func (o *OrderedSource) NextUser() string {
+	if o.unfinished != nil {
+		u := o.unfinished.Users[o.unfinished.Next]
+		o.unfinished.Next++
+		if !o.unfinished.skip(o) {
+			o.unfinished = nil
+			o.cycles++
+		}
+		return u
+	}
+
+	u := o.nextUser.Value.(string)
+	o.nextUser = o.nextUser.Next()
+	if o.nextUser.Value.(string) == o.ordered[0] {
+		o.cycles++
+	}
+	return u
+}
+
+func (o *OrderedSource) Cursor() *Cursor {
+	if o.unfinished != nil {
+		return &Cursor{Users: append([]string{}, o.unfinished.Users...), Next: o.unfinished.Next, Cycles: o.cycles}
+	}
+
+	c := &Cursor{Users: o.Users(), Cycles: o.cycles}
+	for i, u := range o.ordered {
+		if u == o.nextUser.Value.(string) {
+			c.Next = i
+			break
+		}
+	}
+	return c
+}
+
+// Seek finishes the cycle of c, then continues in order from the first user.
+func (o *OrderedSource) Seek(c *Cursor) {
+	o.StartAfter("")
+	o.unfinished = c.unfinished(o)
+	o.cycles = c.Cycles
+	if c.Next != 0 && o.unfinished == nil {
+		o.cycles++
+	}
+}
+
+func (o *OrderedSource) Contains(user string) bool {
+	return o.usernames[user]
+}
+
+func (o *OrderedSource) Users() []string {
+	return append([]string{}, o.ordered...)
+}
+
+// ShuffledSource cycles through users in a new random order every cycle. The orders come from a seeded random number
+// generator, so they're the same every time for the same seed and users.
+type ShuffledSource struct {
+	seed  int64
+	users []string
+
+	rnd    *rand.Rand
+	cycle  []string
+	next   int
+	cycles int
+}
+
+var (
+	_ Source  = &ShuffledSource{}
+	_ Resumer = &ShuffledSource{}
+	_ Seeker  = &ShuffledSource{}
+)
+
+// NewShuffledSource creates a ShuffledSource of users, lower-cased.
+func NewShuffledSource(seed int64, users ...string) *ShuffledSource {
+	sh := &ShuffledSource{seed: seed}
+	for _, u := range users {
+		sh.users = append(sh.users, strings.ToLower(u))
+	}
+	sort.Strings(sh.users)
+	sh.Resume(nil)
+	return sh
+}
+
+// StartAfter positions this Source after user in the current cycle. If user isn't in it, it starts the next cycle.
+func (sh *ShuffledSource) StartAfter(user string) {
+	user = strings.ToLower(user)
+	for i, u := range sh.cycle {
+		if u == user {
+			sh.next = i + 1
+			return
+		}
+	}
+	sh.next = len(sh.cycle)
+}
+
+// Resume positions this Source after as many turns as there are users in history, counting from the first cycle.
+func (sh *ShuffledSource) Resume(history []string) {
+	cycles := 0
+	if len(sh.users) != 0 {
+		cycles = len(history) / len(sh.users)
+	}
+	sh.replay(cycles)
+	if len(sh.users) != 0 {
+		sh.next = len(history) % len(sh.users)
+	}
+}
+
+func (sh *ShuffledSource) Cursor() *Cursor {
+	return &Cursor{Users: append([]string{}, sh.cycle...), Next: sh.next, Cycles: sh.cycles}
+}
+
+// Seek finishes the cycle of c, then continues with the shuffles that follow it. A cycle that hasn't begun is shuffled
+// again, so it includes new users.
+func (sh *ShuffledSource) Seek(c *Cursor) {
+	sh.replay(c.Cycles)
+	if c.Next == 0 {
+		return
+	}
+
+	sh.cycle, sh.next = nil, c.Next
+	for _, u := range c.Users {
+		sh.cycle = append(sh.cycle, strings.ToLower(u))
+	}
+}
+
+// replay shuffles the first cycle, and the cycles after it, up to the one after cycles completed cycles.
+func (sh *ShuffledSource) replay(cycles int) {
+	sh.rnd = rand.New(rand.NewSource(sh.seed))
+	sh.cycle = nil
+	sh.shuffle()
+	for i := 0; i < cycles; i++ {
+		sh.shuffle()
+	}
+	sh.cycles = cycles
+}
+
+// shuffle starts a new cycle. Nobody ends one cycle and starts the next, so nobody takes two turns in a row.
+func (sh *ShuffledSource) shuffle() {
+	last := ""
+	if len(sh.cycle) != 0 {
+		last = sh.cycle[len(sh.cycle)-1]
+	}
+
+	sh.cycle = append([]string{}, sh.users...)
+	sh.rnd.Shuffle(len(sh.cycle), func(i, j int) {
+		sh.cycle[i], sh.cycle[j] = sh.cycle[j], sh.cycle[i]
+	})
+	if n := len(sh.cycle); n > 1 && sh.cycle[0] == last {
+		sh.cycle[0], sh.cycle[n-1] = sh.cycle[n-1], sh.cycle[0]
+	}
+	sh.next = 0
+}
+
+func (sh *ShuffledSource) NextUser() string {
+	// A cycle continued with Seek can have users who have since left.
+	for sh.next < len(sh.cycle) && !sh.Contains(sh.cycle[sh.next]) {
+		sh.next++
+	}
+	if sh.next >= len(sh.cycle) {
+		sh.shuffle()
+		sh.cycles++
+	}
+	u := sh.cycle[sh.next]
+	sh.next++
+	return u
+}
+
+func (sh *ShuffledSource) Contains(user string) bool {
+	for _, u := range sh.users {
+		if u == user {
+			return true
+		}
+	}
+	return false
+}
+
+func (sh *ShuffledSource) Users() []string {
+	return append([]string{}, sh.users...)
+}
+
+// LeastRecentSource gives each turn to the user whose last turn was longest ago. Users who have never had a turn go
+// first, in the order they were given.
+type LeastRecentSource struct {
+	users []string
+
+	// lastTurn is the turn number of each user's last turn. Users who have never had a turn aren't in it.
+	lastTurn map[string]int
+	turn     int
+}
+
+var (
+	_ Source  = &LeastRecentSource{}
+	_ Resumer = &LeastRecentSource{}
+	_ Seeker  = &LeastRecentSource{}
+)
+
+// NewLeastRecentSource creates a LeastRecentSource of users, lower-cased.
+func NewLeastRecentSource(users ...string) *LeastRecentSource {
+	ls := &LeastRecentSource{lastTurn: map[string]int{}}
+	for _, u := range users {
+		ls.users = append(ls.users, strings.ToLower(u))
+	}
+	return ls
+}
+
+// StartAfter counts user as having had the most recent turn.
+func (ls *LeastRecentSource) StartAfter(user string) {
+	user = strings.ToLower(user)
+	if ls.Contains(user) {
+		ls.turn++
+		ls.lastTurn[user] = ls.turn
+	}
+}
+
+// Resume counts each user in history as having had a turn, in order, after forgetting any previous turns.
+func (ls *LeastRecentSource) Resume(history []string) {
+	ls.lastTurn = map[string]int{}
+	ls.turn = 0
+	for _, u := range history {
+		ls.StartAfter(u)
+	}
+}
+
+// Cursor lists users in the order they'll take their next turns.
+func (ls *LeastRecentSource) Cursor() *Cursor {
+	c := &Cursor{}
+	for _, u := range ls.users {
+		if _, ok := ls.lastTurn[u]; !ok {
+			c.Users = append(c.Users, u)
+		}
+	}
+
+	var served []string
+	for _, u := range ls.users {
+		if _, ok := ls.lastTurn[u]; ok {
+			served = append(served, u)
+		}
+	}
+	sort.Slice(served, func(i, j int) bool {
+		return ls.lastTurn[served[i]] < ls.lastTurn[served[j]]
+	})
+	c.Users = append(c.Users, served...)
+	return c
+}
+
+// Seek counts users as having had turns in the reverse order of their next turns in c, so they take them in the same
+// order. Users new to this Source have never had a turn, so they go first.
+func (ls *LeastRecentSource) Seek(c *Cursor) {
+	ls.Resume(append(append([]string{}, c.Users[c.Next:]...), c.Users[:c.Next]...))
+}
+
+func (ls *LeastRecentSource) NextUser() string {
+	var next string
+	for _, u := range ls.users {
+		last, ok := ls.lastTurn[u]
+		if !ok {
+			next = u
+			break
+		}
+		if next == "" || last < ls.lastTurn[next] {
+			next = u
+		}
+	}
+
+	ls.turn++
+	ls.lastTurn[next] = ls.turn
+	return next
+}
+
+func (ls *LeastRecentSource) Contains(user string) bool {
+	for _, u := range ls.users {
+		if u == user {
+			return true
+		}
+	}
+	return false
+}
+
+func (ls *LeastRecentSource) Users() []string {
+	return append([]string{}, ls.users...)
+}
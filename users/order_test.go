@@ -0,0 +1,132 @@
+package users
+
+import (
+	"reflect"
+	"sort"
+	"testing"
+)
+
+func next(src Source, n int) []string {
+	var got []string
+	for i := 0; i < n; i++ {
+		got = append(got, src.NextUser())
+	}
+	return got
+}
+
+func TestNewSource(t *testing.T) {
+	for _, tc := range []struct {
+		order   Order
+		want    []string
+		wantErr bool
+	}{
+		{order: "", want: []string{"a", "b", "c", "a"}},
+		{order: Alphabetical, want: []string{"a", "b", "c", "a"}},
+		{order: RosterOrder, want: []string{"c", "a", "b", "c"}},
+		{order: LeastRecent, want: []string{"c", "a", "b", "c"}},
+		{order: "random", wantErr: true},
+	} {
+		t.Run(string(tc.order), func(t *testing.T) {
+			src, err := NewSource(tc.order, 0, "C", "a", "b")
+			if tc.wantErr {
+				if err == nil {
+					t.Errorf("want error and didn't get one.")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			if got := next(src, len(tc.want)); !reflect.DeepEqual(tc.want, got) {
+				t.Errorf("want %v, got %v", tc.want, got)
+			}
+		})
+	}
+}
+
+func TestOrderedSourceStartAfter(t *testing.T) {
+	for _, tc := range []struct {
+		desc       string
+		startAfter string
+		want       []string
+	}{
+		{desc: "start after first", startAfter: "c", want: []string{"a", "b", "c"}},
+		{desc: "start after last", startAfter: "B", want: []string{"c", "a", "b"}},
+		{desc: "start after missing", startAfter: "missing", want: []string{"c", "a", "b"}},
+	} {
+		t.Run(tc.desc, func(t *testing.T) {
+			src := NewOrderedSource("c", "a", "b")
+			src.NextUser()
+			src.StartAfter(tc.startAfter)
+			if got := next(src, len(tc.want)); !reflect.DeepEqual(tc.want, got) {
+				t.Errorf("want %v, got %v", tc.want, got)
+			}
+		})
+	}
+}
+
+func TestShuffledSource(t *testing.T) {
+	users := []string{"a", "b", "c", "d", "e"}
+	want := next(NewShuffledSource(7, users...), 4*len(users))
+
+	// Every cycle has everyone in it once.
+	for i := 0; i < len(want); i += len(users) {
+		cycle := append([]string{}, want[i:i+len(users)]...)
+		sort.Strings(cycle)
+		if !reflect.DeepEqual(users, cycle) {
+			t.Errorf("want cycle of %v, got %v", users, want[i:i+len(users)])
+		}
+	}
+
+	for i := 1; i < len(want); i++ {
+		if want[i] == want[i-1] {
+			t.Errorf("want no back to back turns, got %v twice at turn %v in %v", want[i], i, want)
+		}
+	}
+
+	if reflect.DeepEqual(want[:len(users)], want[len(users):2*len(users)]) {
+		t.Errorf("want a new order every cycle, got %v twice", want[:len(users)])
+	}
+
+	if got := next(NewShuffledSource(7, users...), len(want)); !reflect.DeepEqual(want, got) {
+		t.Errorf("want the same order for the same seed. want %v, got %v", want, got)
+	}
+
+	for _, turns := range []int{0, 3, 5, 12} {
+		src := NewShuffledSource(7, users...)
+		src.Resume(want[:turns])
+		if got := next(src, len(want)-turns); !reflect.DeepEqual(want[turns:], got) {
+			t.Errorf("resuming after %v turns, want %v, got %v", turns, want[turns:], got)
+		}
+	}
+}
+
+func TestLeastRecentSource(t *testing.T) {
+	src := NewLeastRecentSource("c", "a", "b")
+	src.Resume([]string{"a", "c", "missing", "a"})
+
+	want := []string{"b", "c", "a", "b"}
+	if got := next(src, len(want)); !reflect.DeepEqual(want, got) {
+		t.Errorf("want %v, got %v", want, got)
+	}
+
+	src.StartAfter("c")
+	want = []string{"a", "b", "c"}
+	if got := next(src, len(want)); !reflect.DeepEqual(want, got) {
+		t.Errorf("after StartAfter, want %v, got %v", want, got)
+	}
+}
+
+func TestWeightedSourceResume(t *testing.T) {
+	ws, err := NewWeightedSource(NewLeastRecentSource("a", "b", "c"), map[string]float64{"c": 0.5})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	ws.Resume([]string{"b", "a"})
+
+	want := []string{"c", "b", "a", "b"}
+	if got := next(ws, len(want)); !reflect.DeepEqual(want, got) {
+		t.Errorf("want %v, got %v", want, got)
+	}
+}
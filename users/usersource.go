@@ -8,19 +8,29 @@ import (
 
 // Source represents a source of usernames for rotation.
 type Source interface {
-	// StartAfter positions this Source to begin iterating alphabetically after this user. If this user sorts (case
-	// insensitively) outside of the first or last user, the first user is returned.
+	// StartAfter positions this Source to continue after this user had a turn, in whatever order the Source cycles
+	// through its users. Each implementation decides where to continue when the user isn't one of them.
 	StartAfter(user string)
 	NextUser() string
 	Contains(user string) bool
+
+	// Users returns every user, in the order they're cycled through, beginning with the first.
+	Users() []string
 }
 
 // StaticSource is a base implementation of static list of usernames.
 type StaticSource struct {
 	nextUser  *ring.Ring
 	usernames map[string]bool
+	sorted    []string
+
+	// unfinished is the rest of a cycle continued with Seek, which is finished before the ring.
+	unfinished *Cursor
+	cycles     int
 }
 
+var _ Seeker = &StaticSource{}
+
 // NewStaticSource creates a StaticSource of all lower-cased and sorted from the specified users.
 func NewStaticSource(users ...string) *StaticSource {
 	for i, u := range users {
@@ -31,6 +41,7 @@ func NewStaticSource(users ...string) *StaticSource {
 	ss := &StaticSource{
 		nextUser:  ring.New(len(users)),
 		usernames: make(map[string]bool, len(users)),
+		sorted:    append([]string{}, users...),
 	}
 	for _, u := range users {
 		ss.usernames[u] = true
@@ -40,8 +51,11 @@ func NewStaticSource(users ...string) *StaticSource {
 	return ss
 }
 
+// StartAfter positions this Source to begin iterating alphabetically after this user. If this user sorts (case
+// insensitively) outside of the first or last user, the first user is returned.
 func (ss *StaticSource) StartAfter(user string) {
 	user = strings.ToLower(user)
+	ss.unfinished = nil
 
 	var beginning *ring.Ring
 	for linksChecked := 0; linksChecked <= ss.nextUser.Len(); linksChecked++ {
@@ -61,12 +75,50 @@ func (ss *StaticSource) StartAfter(user string) {
 }
 
 func (ss *StaticSource) NextUser() string {
+	if ss.unfinished != nil {
+		u := ss.unfinished.Users[ss.unfinished.Next]
+		ss.unfinished.Next++
+		if !ss.unfinished.skip(ss) {
+			ss.unfinished = nil
+			ss.cycles++
+		}
+		return u
+	}
+
 	u := ss.nextUser.Value.(string)
 	ss.nextUser = ss.nextUser.Next()
+	if ss.nextUser.Value.(string) == ss.sorted[0] {
+		ss.cycles++
+	}
 	return u
 }
 
+func (ss *StaticSource) Cursor() *Cursor {
+	if ss.unfinished != nil {
+		return &Cursor{Users: append([]string{}, ss.unfinished.Users...), Next: ss.unfinished.Next, Cycles: ss.cycles}
+	}
+	return &Cursor{
+		Users:  ss.Users(),
+		Next:   sort.SearchStrings(ss.sorted, ss.nextUser.Value.(string)),
+		Cycles: ss.cycles,
+	}
+}
+
+// Seek finishes the cycle of c, then continues alphabetically from the first user.
+func (ss *StaticSource) Seek(c *Cursor) {
+	ss.StartAfter("")
+	ss.unfinished = c.unfinished(ss)
+	ss.cycles = c.Cycles
+	if c.Next != 0 && ss.unfinished == nil {
+		ss.cycles++
+	}
+}
+
 func (ss *StaticSource) Contains(user string) bool {
 	_, ok := ss.usernames[user]
 	return ok
 }
+
+func (ss *StaticSource) Users() []string {
+	return append([]string{}, ss.sorted...)
+}
@@ -1,6 +1,9 @@
 package users
 
-import "testing"
+import (
+	"reflect"
+	"testing"
+)
 
 func TestNextUser(t *testing.T) {
 	ss := NewStaticSource("a", "b")
@@ -118,3 +121,13 @@ func TestContains(t *testing.T) {
 		t.Errorf("should not contain b")
 	}
 }
+
+func TestUsers(t *testing.T) {
+	ss := NewStaticSource("c", "A", "b")
+	ss.StartAfter("b")
+
+	want := []string{"a", "b", "c"}
+	if got := ss.Users(); !reflect.DeepEqual(want, got) {
+		t.Errorf("want %v, got %v", want, got)
+	}
+}
@@ -0,0 +1,98 @@
+package users
+
+import (
+	"fmt"
+	"math"
+	"strings"
+)
+
+// weightEpsilon absorbs floating point error, so that, say, ten turns at a weight of 0.1 add up to a whole shift.
+const weightEpsilon = 1e-9
+
+// WeightedSource wraps another Source, where users can have a weight between 0 and 1, for the fraction of their turns
+// in the rotation they take, like 0.5 for someone allocated half time. Every time a user's turn comes up, their weight
+// is added to their credit, and they only take the turn once it adds up to a whole shift. Users otherwise take turns
+// in the order of the wrapped Source, so the result is deterministic.
+type WeightedSource struct {
+	Source
+
+	weights map[string]float64
+	credits map[string]float64
+}
+
+var (
+	_ Resumer = &WeightedSource{}
+	_ Seeker  = &WeightedSource{}
+)
+
+// NewWeightedSource weights the users of src. Users missing from weights have a weight of 1, and weights of users not
+// in src are ignored.
+func NewWeightedSource(src Source, weights map[string]float64) (*WeightedSource, error) {
+	ws := &WeightedSource{
+		Source:  src,
+		weights: map[string]float64{},
+		credits: map[string]float64{},
+	}
+
+	for user, w := range weights {
+		if w <= 0 || w > 1 {
+			return nil, fmt.Errorf("invalid weight %v for %v. Must be greater than 0, and no more than 1", w, user)
+		}
+		ws.weights[strings.ToLower(user)] = w
+	}
+
+	for _, u := range ws.Users() {
+		if _, ok := ws.weights[u]; !ok {
+			ws.weights[u] = 1
+		}
+		// Starting halfway rounds each user's share of turns to the nearest whole shift, rather than always down.
+		ws.credits[u] = 0.5
+	}
+	return ws, nil
+}
+
+// Weight returns the weight of user, or 0 if they're not in the source.
+func (ws *WeightedSource) Weight(user string) float64 {
+	return ws.weights[strings.ToLower(user)]
+}
+
+func (ws *WeightedSource) NextUser() string {
+	for {
+		u := ws.Source.NextUser()
+		ws.credits[u] += ws.weights[u]
+		if ws.credits[u] >= 1-weightEpsilon {
+			ws.credits[u]--
+			return u
+		}
+	}
+}
+
+// Resume resumes the wrapped Source. Credits are unchanged.
+func (ws *WeightedSource) Resume(history []string) {
+	Resume(ws.Source, history)
+}
+
+// Cursor returns the position of the wrapped Source, with the credits of every user. Credits are rounded to a
+// millionth, so they're readable once saved.
+func (ws *WeightedSource) Cursor() *Cursor {
+	c := CursorOf(ws.Source)
+	if c == nil {
+		return nil
+	}
+
+	c.Credits = make(map[string]float64, len(ws.credits))
+	for u, credit := range ws.credits {
+		c.Credits[u] = math.Round(credit*1e6) / 1e6
+	}
+	return c
+}
+
+// Seek continues the wrapped Source from c, and restores the credits of users still in it.
+func (ws *WeightedSource) Seek(c *Cursor) {
+	Seek(ws.Source, c)
+	for u, credit := range c.Credits {
+		if u = strings.ToLower(u); ws.Contains(u) {
+			ws.credits[u] = credit
+		}
+	}
+}
@@ -0,0 +1,79 @@
+package users
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestWeightedSourceNextUser(t *testing.T) {
+	for _, tc := range []struct {
+		desc    string
+		users   []string
+		weights map[string]float64
+		want    []string
+	}{
+		{
+			desc:  "no weights",
+			users: []string{"a", "b", "c"},
+			want:  []string{"a", "b", "c", "a", "b", "c"},
+		},
+		{
+			desc:    "half time",
+			users:   []string{"a", "B", "c"},
+			weights: map[string]float64{"b": 0.5},
+			want:    []string{"a", "b", "c", "a", "c", "a", "b", "c", "a", "c"},
+		},
+		{
+			desc:    "tenths add up",
+			users:   []string{"a", "b"},
+			weights: map[string]float64{"A": 0.1},
+			want:    []string{"b", "b", "b", "b", "a", "b", "b", "b", "b", "b", "b", "b", "b", "b", "b", "a"},
+		},
+		{
+			desc:    "unknown user ignored",
+			users:   []string{"a", "b"},
+			weights: map[string]float64{"z": 0.5},
+			want:    []string{"a", "b", "a", "b"},
+		},
+	} {
+		t.Run(tc.desc, func(t *testing.T) {
+			ws, err := NewWeightedSource(NewStaticSource(tc.users...), tc.weights)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			var got []string
+			for range tc.want {
+				got = append(got, ws.NextUser())
+			}
+			if !reflect.DeepEqual(tc.want, got) {
+				t.Errorf("want %v, got %v", tc.want, got)
+			}
+		})
+	}
+}
+
+func TestWeightedSourceProportional(t *testing.T) {
+	ws, err := NewWeightedSource(NewStaticSource("a", "b", "c", "d"), map[string]float64{"b": 0.5, "c": 0.25})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	counts := map[string]int{}
+	for i := 0; i < 1100; i++ {
+		counts[ws.NextUser()]++
+	}
+
+	want := map[string]int{"a": 400, "b": 200, "c": 100, "d": 400}
+	if !reflect.DeepEqual(want, counts) {
+		t.Errorf("want %v, got %v", want, counts)
+	}
+}
+
+func TestNewWeightedSourceInvalid(t *testing.T) {
+	for _, w := range []float64{0, -1, 1.5} {
+		if _, err := NewWeightedSource(NewStaticSource("a"), map[string]float64{"a": w}); err == nil {
+			t.Errorf("want error on weight %v and didn't get one.", w)
+		}
+	}
+}